@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crazyfarmbackend/config/di"
+	"crazyfarmbackend/src/constant"
+	"flag"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strings"
+)
+
+// runCommand executes a maintenance command given on the command line instead of serving http
+func runCommand(init *di.Initialization, args []string) {
+	switch args[0] {
+	case "ledger-check":
+		ledgerCheck(init, args[1:])
+	case "leaderboard-refresh":
+		leaderboardRefresh(init)
+	case "grant-role":
+		grantRole(init, args[1:])
+	default:
+		log.Fatalf("Unknown command %s", args[0])
+	}
+}
+
+func ledgerCheck(init *di.Initialization, args []string) {
+	flags := flag.NewFlagSet("ledger-check", flag.ExitOnError)
+	fix := flags.Bool("fix", false, "overwrite inventory quantities with balances rebuilt from the ledger")
+	_ = flags.Parse(args)
+
+	mismatches, err := init.InventoryRepository.CheckLedger(*fix)
+	if err != nil {
+		log.Fatal("Ledger check failed: ", err)
+	}
+	for _, mismatch := range mismatches {
+		log.Warnf("User %s plant %s: ledger %d, inventory %d",
+			mismatch.UserID, mismatch.Plant, mismatch.Ledger, mismatch.Inventory)
+	}
+	log.Infof("Ledger check finished, %d mismatches, fixed: %t", len(mismatches), *fix)
+	if len(mismatches) > 0 && !*fix {
+		os.Exit(1)
+	}
+}
+
+func leaderboardRefresh(init *di.Initialization) {
+	refreshed, err := init.LeaderboardRepository.RefreshInventoryWorth(0)
+	if err != nil {
+		log.Fatal("Leaderboard refresh failed: ", err)
+	}
+	if !refreshed {
+		log.Fatal("Leaderboard refresh is already running on another instance")
+	}
+	log.Info("Inventory worth leaderboard refreshed")
+}
+
+// grantRole sets the role of a user given by id or Telegram id, ADMIN unless named. It bootstraps the
+// first admin, later ones are granted through the admin api
+func grantRole(init *di.Initialization, args []string) {
+	if len(args) == 0 || len(args) > 2 {
+		log.Fatal("Usage: grant-role <user id | telegram id> [role]")
+	}
+	role := constant.ROLE_ADMIN
+	if len(args) == 2 {
+		role = constant.Role(strings.ToUpper(args[1]))
+	}
+	if !role.Valid() {
+		log.Fatalf("Unknown role %s", role)
+	}
+
+	userId, err := uuid.Parse(args[0])
+	if err != nil {
+		user, err := init.UserRepository.GetByAuth(args[0], constant.Telegram)
+		if err != nil {
+			log.Fatalf("User %s not found", args[0])
+		}
+		userId = user.ID
+	}
+	user, err := init.UserRepository.SetRole(userId, role)
+	if err != nil {
+		log.Fatal("Granting role failed: ", err)
+	}
+	log.Infof("User %s is now %s", user.ID, user.Role)
+}
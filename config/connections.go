@@ -15,6 +15,7 @@ func ConnectToDB() *gorm.DB {
 
 	db, err := gorm.Open(postgres.Open(dbDsn), &gorm.Config{
 		SkipDefaultTransaction: true,
+		TranslateError:         true,
 	})
 	if err != nil {
 		log.Fatal("Error connecting to database. Error: ", err)
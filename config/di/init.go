@@ -9,9 +9,10 @@ import (
 )
 
 type Initialization struct {
-	UserRepository repository.UserRepository
-	UserService    service.UserService
-	UserController controller.UserController
+	UserRepository    repository.UserRepository
+	UpgradeRepository repository.UpgradeRepository
+	UserService       service.UserService
+	UserController    controller.UserController
 
 	InventoryRepository repository.InventoryRepository
 	InventoryService    service.InventoryService
@@ -21,12 +22,52 @@ type Initialization struct {
 	TaskService    service.TaskService
 	TaskController controller.TaskController
 
+	WalletRepository repository.WalletRepository
+	WalletService    service.WalletService
+	WalletController controller.WalletController
+
+	ShopRepository repository.ShopRepository
+	ShopService    service.ShopService
+	ShopController controller.ShopController
+
+	TradeRepository repository.TradeRepository
+	TradeService    service.TradeService
+	TradeController controller.TradeController
+
+	PlantRepository repository.PlantRepository
+	PlantService    service.PlantService
+	PlantController controller.PlantController
+
+	FarmRepository repository.FarmRepository
+	FarmService    service.FarmService
+	FarmController controller.FarmController
+
+	LeaderboardRepository repository.LeaderboardRepository
+	LeaderboardService    service.LeaderboardService
+	LeaderboardController controller.LeaderboardController
+
+	ReferralRepository repository.ReferralRepository
+	ReferralService    service.ReferralService
+	ReferralController controller.ReferralController
+
+	SessionRepository repository.SessionRepository
+	SessionService    service.SessionService
+	SessionController controller.SessionController
+
+	AuthRepository repository.AuthRepository
+	AuthService    service.AuthService
+	AuthController controller.AuthController
+
+	AdminService    service.AdminService
+	AdminController controller.AdminController
+
 	MiddlewareService middlewares.MiddlewareService
 	Nats              config.NatsBroker
 }
 
 func NewInitialization(
 	userRepository repository.UserRepository,
+	upgradeRepository repository.UpgradeRepository,
 	userService service.UserService,
 	userController controller.UserController,
 
@@ -38,19 +79,88 @@ func NewInitialization(
 	taskService service.TaskService,
 	taskController controller.TaskController,
 
+	walletRepository repository.WalletRepository,
+	walletService service.WalletService,
+	walletController controller.WalletController,
+
+	shopRepository repository.ShopRepository,
+	shopService service.ShopService,
+	shopController controller.ShopController,
+
+	tradeRepository repository.TradeRepository,
+	tradeService service.TradeService,
+	tradeController controller.TradeController,
+
+	plantRepository repository.PlantRepository,
+	plantService service.PlantService,
+	plantController controller.PlantController,
+
+	farmRepository repository.FarmRepository,
+	farmService service.FarmService,
+	farmController controller.FarmController,
+
+	leaderboardRepository repository.LeaderboardRepository,
+	leaderboardService service.LeaderboardService,
+	leaderboardController controller.LeaderboardController,
+
+	referralRepository repository.ReferralRepository,
+	referralService service.ReferralService,
+	referralController controller.ReferralController,
+
+	sessionRepository repository.SessionRepository,
+	sessionService service.SessionService,
+	sessionController controller.SessionController,
+
+	authRepository repository.AuthRepository,
+	authService service.AuthService,
+	authController controller.AuthController,
+
+	adminService service.AdminService,
+	adminController controller.AdminController,
+
 	middlewareService middlewares.MiddlewareService,
 	nats config.NatsBroker) *Initialization {
 	return &Initialization{
-		UserRepository:      userRepository,
-		UserService:         userService,
-		UserController:      userController,
-		InventoryRepository: inventoryRepository,
-		InventoryService:    inventoryService,
-		InventoryController: inventoryController,
-		TaskRepository:      taskRepository,
-		TaskService:         taskService,
-		TaskController:      taskController,
-		MiddlewareService:   middlewareService,
-		Nats:                nats,
+		UserRepository:        userRepository,
+		UpgradeRepository:     upgradeRepository,
+		UserService:           userService,
+		UserController:        userController,
+		InventoryRepository:   inventoryRepository,
+		InventoryService:      inventoryService,
+		InventoryController:   inventoryController,
+		TaskRepository:        taskRepository,
+		TaskService:           taskService,
+		TaskController:        taskController,
+		WalletRepository:      walletRepository,
+		WalletService:         walletService,
+		WalletController:      walletController,
+		ShopRepository:        shopRepository,
+		ShopService:           shopService,
+		ShopController:        shopController,
+		TradeRepository:       tradeRepository,
+		TradeService:          tradeService,
+		TradeController:       tradeController,
+		PlantRepository:       plantRepository,
+		PlantService:          plantService,
+		PlantController:       plantController,
+		FarmRepository:        farmRepository,
+		FarmService:           farmService,
+		FarmController:        farmController,
+		LeaderboardRepository: leaderboardRepository,
+		LeaderboardService:    leaderboardService,
+		LeaderboardController: leaderboardController,
+		ReferralRepository:    referralRepository,
+		ReferralService:       referralService,
+		ReferralController:    referralController,
+		SessionRepository:     sessionRepository,
+		SessionService:        sessionService,
+		SessionController:     sessionController,
+		AuthRepository:        authRepository,
+		AuthService:           authService,
+		AuthController:        authController,
+		AdminService:          adminService,
+		AdminController:       adminController,
+		MiddlewareService:     middlewareService,
+		Nats:                  nats,
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"crazyfarmbackend/config"
 	"crazyfarmbackend/src/api/middlewares"
 	"crazyfarmbackend/src/controller"
+	"crazyfarmbackend/src/pkg"
 	"crazyfarmbackend/src/repository"
 	"crazyfarmbackend/src/service"
 	"github.com/google/wire"
@@ -18,17 +19,27 @@ var connectionsSet = wire.NewSet(
 	config.ConnectToNatsBroker,
 )
 
-var middlewareServiceSet = wire.NewSet(middlewares.MiddlewareServiceInit,
+var transactionSet = wire.NewSet(
+	repository.TransactionRepositoryInit,
+	wire.Bind(new(repository.TransactionRepository), new(*repository.TransactionRepositoryImpl)),
+)
+
+var middlewareServiceSet = wire.NewSet(pkg.RateLimiterInit, middlewares.MiddlewareServiceInit,
 	wire.Bind(new(middlewares.MiddlewareService), new(*middlewares.MiddlewareServiceImpl)))
 
 var natsBrokerSet = wire.NewSet(
 	config.NatsBrokerInit,
 	wire.Bind(new(config.NatsBroker), new(*config.NatsBrokerImpl)),
+	wire.Bind(new(config.CoinReceiver), new(*service.WalletServiceImpl)),
 )
 
 var userSet = wire.NewSet(
 	repository.UserRepositoryInit,
 	wire.Bind(new(repository.UserRepository), new(*repository.UserRepositoryImpl)),
+	repository.UpgradeRepositoryInit,
+	wire.Bind(new(repository.UpgradeRepository), new(*repository.UpgradeRepositoryImpl)),
+	repository.CounterRepositoryInit,
+	wire.Bind(new(repository.CounterRepository), new(*repository.CounterRepositoryImpl)),
 	service.UserServiceInit,
 	wire.Bind(new(service.UserService), new(*service.UserServiceImpl)),
 	controller.UserControllerInit,
@@ -47,19 +58,125 @@ var inventorySet = wire.NewSet(
 var taskSet = wire.NewSet(
 	repository.TaskRepositoryInit,
 	wire.Bind(new(repository.TaskRepository), new(*repository.TaskRepositoryImpl)),
+	repository.RewardRepositoryInit,
+	wire.Bind(new(repository.RewardRepository), new(*repository.RewardRepositoryImpl)),
+	service.TaskCheckerRegistryInit,
+	wire.Bind(new(service.TaskCheckerRegistry), new(*service.TaskCheckerRegistryImpl)),
 	service.TaskServiceInit,
 	wire.Bind(new(service.TaskService), new(*service.TaskServiceImpl)),
 	controller.TaskControllerInit,
 	wire.Bind(new(controller.TaskController), new(*controller.TaskControllerImpl)),
 )
 
+var walletSet = wire.NewSet(
+	repository.WalletRepositoryInit,
+	wire.Bind(new(repository.WalletRepository), new(*repository.WalletRepositoryImpl)),
+	service.WalletServiceInit,
+	wire.Bind(new(service.WalletService), new(*service.WalletServiceImpl)),
+	controller.WalletControllerInit,
+	wire.Bind(new(controller.WalletController), new(*controller.WalletControllerImpl)),
+)
+
+var shopSet = wire.NewSet(
+	repository.ShopRepositoryInit,
+	wire.Bind(new(repository.ShopRepository), new(*repository.ShopRepositoryImpl)),
+	service.ShopServiceInit,
+	wire.Bind(new(service.ShopService), new(*service.ShopServiceImpl)),
+	controller.ShopControllerInit,
+	wire.Bind(new(controller.ShopController), new(*controller.ShopControllerImpl)),
+)
+
+var tradeSet = wire.NewSet(
+	repository.TradeRepositoryInit,
+	wire.Bind(new(repository.TradeRepository), new(*repository.TradeRepositoryImpl)),
+	service.TradeServiceInit,
+	wire.Bind(new(service.TradeService), new(*service.TradeServiceImpl)),
+	controller.TradeControllerInit,
+	wire.Bind(new(controller.TradeController), new(*controller.TradeControllerImpl)),
+)
+
+var plantSet = wire.NewSet(
+	repository.PlantRepositoryInit,
+	wire.Bind(new(repository.PlantRepository), new(*repository.PlantRepositoryImpl)),
+	service.PlantServiceInit,
+	wire.Bind(new(service.PlantService), new(*service.PlantServiceImpl)),
+	controller.PlantControllerInit,
+	wire.Bind(new(controller.PlantController), new(*controller.PlantControllerImpl)),
+)
+
+var farmSet = wire.NewSet(
+	repository.FarmRepositoryInit,
+	wire.Bind(new(repository.FarmRepository), new(*repository.FarmRepositoryImpl)),
+	service.FarmServiceInit,
+	wire.Bind(new(service.FarmService), new(*service.FarmServiceImpl)),
+	controller.FarmControllerInit,
+	wire.Bind(new(controller.FarmController), new(*controller.FarmControllerImpl)),
+)
+
+var leaderboardSet = wire.NewSet(
+	repository.LeaderboardRepositoryInit,
+	wire.Bind(new(repository.LeaderboardRepository), new(*repository.LeaderboardRepositoryImpl)),
+	service.LeaderboardServiceInit,
+	wire.Bind(new(service.LeaderboardService), new(*service.LeaderboardServiceImpl)),
+	controller.LeaderboardControllerInit,
+	wire.Bind(new(controller.LeaderboardController), new(*controller.LeaderboardControllerImpl)),
+)
+
+var referralSet = wire.NewSet(
+	repository.ReferralRepositoryInit,
+	wire.Bind(new(repository.ReferralRepository), new(*repository.ReferralRepositoryImpl)),
+	service.ReferralServiceInit,
+	wire.Bind(new(service.ReferralService), new(*service.ReferralServiceImpl)),
+	controller.ReferralControllerInit,
+	wire.Bind(new(controller.ReferralController), new(*controller.ReferralControllerImpl)),
+)
+
+var sessionSet = wire.NewSet(
+	repository.SessionRepositoryInit,
+	wire.Bind(new(repository.SessionRepository), new(*repository.SessionRepositoryImpl)),
+	service.SessionServiceInit,
+	wire.Bind(new(service.SessionService), new(*service.SessionServiceImpl)),
+	controller.SessionControllerInit,
+	wire.Bind(new(controller.SessionController), new(*controller.SessionControllerImpl)),
+)
+
+var authSet = wire.NewSet(
+	repository.AuthRepositoryInit,
+	wire.Bind(new(repository.AuthRepository), new(*repository.AuthRepositoryImpl)),
+	pkg.MailSenderInit,
+	service.AuthenticatorRegistryInit,
+	wire.Bind(new(service.AuthenticatorRegistry), new(*service.AuthenticatorRegistryImpl)),
+	service.AuthServiceInit,
+	wire.Bind(new(service.AuthService), new(*service.AuthServiceImpl)),
+	controller.AuthControllerInit,
+	wire.Bind(new(controller.AuthController), new(*controller.AuthControllerImpl)),
+)
+
+var adminSet = wire.NewSet(
+	service.AdminServiceInit,
+	wire.Bind(new(service.AdminService), new(*service.AdminServiceImpl)),
+	controller.AdminControllerInit,
+	wire.Bind(new(controller.AdminController), new(*controller.AdminControllerImpl)),
+)
+
 func Init() *Initialization {
 	wire.Build(NewInitialization,
 		natsBrokerSet,
 		connectionsSet,
+		transactionSet,
 		userSet,
 		inventorySet,
 		taskSet,
+		walletSet,
+		shopSet,
+		tradeSet,
+		plantSet,
+		farmSet,
+		leaderboardSet,
+		referralSet,
+		sessionSet,
+		authSet,
+		adminSet,
 		middlewareServiceSet)
 	return nil
 }
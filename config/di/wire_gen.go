@@ -10,6 +10,7 @@ import (
 	"crazyfarmbackend/config"
 	"crazyfarmbackend/src/api/middlewares"
 	"crazyfarmbackend/src/controller"
+	"crazyfarmbackend/src/pkg"
 	"crazyfarmbackend/src/repository"
 	"crazyfarmbackend/src/service"
 	"github.com/google/wire"
@@ -20,18 +21,55 @@ import (
 func Init() *Initialization {
 	db := config.ConnectToDB()
 	userRepositoryImpl := repository.UserRepositoryInit(db)
-	userServiceImpl := service.UserServiceInit(userRepositoryImpl)
-	userControllerImpl := controller.UserControllerInit(userServiceImpl)
+	upgradeRepositoryImpl := repository.UpgradeRepositoryInit(db)
+	counterRepositoryImpl := repository.CounterRepositoryInit(db)
+	referralRepositoryImpl := repository.ReferralRepositoryInit(db)
 	inventoryRepositoryImpl := repository.InventoryRepositoryInit(db)
-	inventoryServiceImpl := service.InventoryServiceInit(inventoryRepositoryImpl)
-	inventoryControllerImpl := controller.InventoryControllerInit(inventoryServiceImpl)
+	plantRepositoryImpl := repository.PlantRepositoryInit(db)
+	referralServiceImpl := service.ReferralServiceInit(referralRepositoryImpl, userRepositoryImpl, counterRepositoryImpl, plantRepositoryImpl, upgradeRepositoryImpl)
 	conn := config.ConnectToNatsBroker()
+	sessionRepositoryImpl := repository.SessionRepositoryInit(db, conn)
+	sessionServiceImpl := service.SessionServiceInit(sessionRepositoryImpl)
+	authRepositoryImpl := repository.AuthRepositoryInit(db)
+	mailSender := pkg.MailSenderInit()
+	authenticatorRegistryImpl := service.AuthenticatorRegistryInit(authRepositoryImpl, mailSender)
+	userServiceImpl := service.UserServiceInit(userRepositoryImpl, upgradeRepositoryImpl, counterRepositoryImpl, referralServiceImpl, sessionServiceImpl, authenticatorRegistryImpl)
+	userControllerImpl := controller.UserControllerInit(userServiceImpl)
+	inventoryServiceImpl := service.InventoryServiceInit(inventoryRepositoryImpl, userRepositoryImpl, upgradeRepositoryImpl, plantRepositoryImpl, referralServiceImpl)
+	inventoryControllerImpl := controller.InventoryControllerInit(inventoryServiceImpl)
 	taskRepositoryImpl := repository.TaskRepositoryInit(db, conn)
-	taskServiceImpl := service.TaskServiceInit(taskRepositoryImpl, inventoryRepositoryImpl, userRepositoryImpl, conn)
+	transactionRepositoryImpl := repository.TransactionRepositoryInit(db)
+	taskCheckerRegistryImpl := service.TaskCheckerRegistryInit(conn, userRepositoryImpl, inventoryRepositoryImpl, plantRepositoryImpl, counterRepositoryImpl)
+	rewardRepositoryImpl := repository.RewardRepositoryInit(db)
+	taskServiceImpl := service.TaskServiceInit(transactionRepositoryImpl, taskRepositoryImpl, rewardRepositoryImpl, upgradeRepositoryImpl, userRepositoryImpl, plantRepositoryImpl, taskCheckerRegistryImpl, referralServiceImpl)
 	taskControllerImpl := controller.TaskControllerInit(taskServiceImpl)
-	middlewareServiceImpl := middlewares.MiddlewareServiceInit(userRepositoryImpl)
-	natsBrokerImpl := config.NatsBrokerInit(conn)
-	initialization := NewInitialization(userRepositoryImpl, userServiceImpl, userControllerImpl, inventoryRepositoryImpl, inventoryServiceImpl, inventoryControllerImpl, taskRepositoryImpl, taskServiceImpl, taskControllerImpl, middlewareServiceImpl, natsBrokerImpl)
+	rateLimiter := pkg.RateLimiterInit(conn)
+	middlewareServiceImpl := middlewares.MiddlewareServiceInit(userRepositoryImpl, sessionRepositoryImpl, rateLimiter)
+	walletRepositoryImpl := repository.WalletRepositoryInit(db)
+	walletServiceImpl := service.WalletServiceInit(walletRepositoryImpl)
+	walletControllerImpl := controller.WalletControllerInit(walletServiceImpl)
+	shopRepositoryImpl := repository.ShopRepositoryInit(db)
+	shopServiceImpl := service.ShopServiceInit(shopRepositoryImpl, plantRepositoryImpl, upgradeRepositoryImpl)
+	shopControllerImpl := controller.ShopControllerInit(shopServiceImpl)
+	tradeRepositoryImpl := repository.TradeRepositoryInit(db)
+	tradeServiceImpl := service.TradeServiceInit(tradeRepositoryImpl, userRepositoryImpl, plantRepositoryImpl)
+	tradeControllerImpl := controller.TradeControllerInit(tradeServiceImpl)
+	plantServiceImpl := service.PlantServiceInit(plantRepositoryImpl)
+	plantControllerImpl := controller.PlantControllerInit(plantServiceImpl)
+	farmRepositoryImpl := repository.FarmRepositoryInit(db)
+	farmServiceImpl := service.FarmServiceInit(farmRepositoryImpl, inventoryRepositoryImpl, userRepositoryImpl, plantRepositoryImpl)
+	farmControllerImpl := controller.FarmControllerInit(farmServiceImpl)
+	leaderboardRepositoryImpl := repository.LeaderboardRepositoryInit(db)
+	leaderboardServiceImpl := service.LeaderboardServiceInit(leaderboardRepositoryImpl, userRepositoryImpl)
+	leaderboardControllerImpl := controller.LeaderboardControllerInit(leaderboardServiceImpl)
+	referralControllerImpl := controller.ReferralControllerInit(referralServiceImpl)
+	sessionControllerImpl := controller.SessionControllerInit(sessionServiceImpl)
+	authServiceImpl := service.AuthServiceInit(authRepositoryImpl, userRepositoryImpl, sessionRepositoryImpl, authenticatorRegistryImpl, mailSender, rateLimiter)
+	authControllerImpl := controller.AuthControllerInit(authServiceImpl)
+	adminServiceImpl := service.AdminServiceInit(userRepositoryImpl, inventoryRepositoryImpl, plantRepositoryImpl)
+	adminControllerImpl := controller.AdminControllerInit(adminServiceImpl)
+	natsBrokerImpl := config.NatsBrokerInit(conn, walletServiceImpl)
+	initialization := NewInitialization(userRepositoryImpl, upgradeRepositoryImpl, userServiceImpl, userControllerImpl, inventoryRepositoryImpl, inventoryServiceImpl, inventoryControllerImpl, taskRepositoryImpl, taskServiceImpl, taskControllerImpl, walletRepositoryImpl, walletServiceImpl, walletControllerImpl, shopRepositoryImpl, shopServiceImpl, shopControllerImpl, tradeRepositoryImpl, tradeServiceImpl, tradeControllerImpl, plantRepositoryImpl, plantServiceImpl, plantControllerImpl, farmRepositoryImpl, farmServiceImpl, farmControllerImpl, leaderboardRepositoryImpl, leaderboardServiceImpl, leaderboardControllerImpl, referralRepositoryImpl, referralServiceImpl, referralControllerImpl, sessionRepositoryImpl, sessionServiceImpl, sessionControllerImpl, authRepositoryImpl, authServiceImpl, authControllerImpl, adminServiceImpl, adminControllerImpl, middlewareServiceImpl, natsBrokerImpl)
 	return initialization
 }
 
@@ -39,12 +77,34 @@ func Init() *Initialization {
 
 var connectionsSet = wire.NewSet(config.ConnectToDB, config.ConnectToNatsBroker)
 
-var middlewareServiceSet = wire.NewSet(middlewares.MiddlewareServiceInit, wire.Bind(new(middlewares.MiddlewareService), new(*middlewares.MiddlewareServiceImpl)))
+var transactionSet = wire.NewSet(repository.TransactionRepositoryInit, wire.Bind(new(repository.TransactionRepository), new(*repository.TransactionRepositoryImpl)))
 
-var natsBrokerSet = wire.NewSet(config.NatsBrokerInit, wire.Bind(new(config.NatsBroker), new(*config.NatsBrokerImpl)))
+var middlewareServiceSet = wire.NewSet(pkg.RateLimiterInit, middlewares.MiddlewareServiceInit, wire.Bind(new(middlewares.MiddlewareService), new(*middlewares.MiddlewareServiceImpl)))
 
-var userSet = wire.NewSet(repository.UserRepositoryInit, wire.Bind(new(repository.UserRepository), new(*repository.UserRepositoryImpl)), service.UserServiceInit, wire.Bind(new(service.UserService), new(*service.UserServiceImpl)), controller.UserControllerInit, wire.Bind(new(controller.UserController), new(*controller.UserControllerImpl)))
+var natsBrokerSet = wire.NewSet(config.NatsBrokerInit, wire.Bind(new(config.NatsBroker), new(*config.NatsBrokerImpl)), wire.Bind(new(config.CoinReceiver), new(*service.WalletServiceImpl)))
+
+var userSet = wire.NewSet(repository.UserRepositoryInit, wire.Bind(new(repository.UserRepository), new(*repository.UserRepositoryImpl)), repository.UpgradeRepositoryInit, wire.Bind(new(repository.UpgradeRepository), new(*repository.UpgradeRepositoryImpl)), repository.CounterRepositoryInit, wire.Bind(new(repository.CounterRepository), new(*repository.CounterRepositoryImpl)), service.UserServiceInit, wire.Bind(new(service.UserService), new(*service.UserServiceImpl)), controller.UserControllerInit, wire.Bind(new(controller.UserController), new(*controller.UserControllerImpl)))
 
 var inventorySet = wire.NewSet(repository.InventoryRepositoryInit, wire.Bind(new(repository.InventoryRepository), new(*repository.InventoryRepositoryImpl)), service.InventoryServiceInit, wire.Bind(new(service.InventoryService), new(*service.InventoryServiceImpl)), controller.InventoryControllerInit, wire.Bind(new(controller.InventoryController), new(*controller.InventoryControllerImpl)))
 
-var taskSet = wire.NewSet(repository.TaskRepositoryInit, wire.Bind(new(repository.TaskRepository), new(*repository.TaskRepositoryImpl)), service.TaskServiceInit, wire.Bind(new(service.TaskService), new(*service.TaskServiceImpl)), controller.TaskControllerInit, wire.Bind(new(controller.TaskController), new(*controller.TaskControllerImpl)))
+var taskSet = wire.NewSet(repository.TaskRepositoryInit, wire.Bind(new(repository.TaskRepository), new(*repository.TaskRepositoryImpl)), repository.RewardRepositoryInit, wire.Bind(new(repository.RewardRepository), new(*repository.RewardRepositoryImpl)), service.TaskCheckerRegistryInit, wire.Bind(new(service.TaskCheckerRegistry), new(*service.TaskCheckerRegistryImpl)), service.TaskServiceInit, wire.Bind(new(service.TaskService), new(*service.TaskServiceImpl)), controller.TaskControllerInit, wire.Bind(new(controller.TaskController), new(*controller.TaskControllerImpl)))
+
+var walletSet = wire.NewSet(repository.WalletRepositoryInit, wire.Bind(new(repository.WalletRepository), new(*repository.WalletRepositoryImpl)), service.WalletServiceInit, wire.Bind(new(service.WalletService), new(*service.WalletServiceImpl)), controller.WalletControllerInit, wire.Bind(new(controller.WalletController), new(*controller.WalletControllerImpl)))
+
+var shopSet = wire.NewSet(repository.ShopRepositoryInit, wire.Bind(new(repository.ShopRepository), new(*repository.ShopRepositoryImpl)), service.ShopServiceInit, wire.Bind(new(service.ShopService), new(*service.ShopServiceImpl)), controller.ShopControllerInit, wire.Bind(new(controller.ShopController), new(*controller.ShopControllerImpl)))
+
+var tradeSet = wire.NewSet(repository.TradeRepositoryInit, wire.Bind(new(repository.TradeRepository), new(*repository.TradeRepositoryImpl)), service.TradeServiceInit, wire.Bind(new(service.TradeService), new(*service.TradeServiceImpl)), controller.TradeControllerInit, wire.Bind(new(controller.TradeController), new(*controller.TradeControllerImpl)))
+
+var plantSet = wire.NewSet(repository.PlantRepositoryInit, wire.Bind(new(repository.PlantRepository), new(*repository.PlantRepositoryImpl)), service.PlantServiceInit, wire.Bind(new(service.PlantService), new(*service.PlantServiceImpl)), controller.PlantControllerInit, wire.Bind(new(controller.PlantController), new(*controller.PlantControllerImpl)))
+
+var farmSet = wire.NewSet(repository.FarmRepositoryInit, wire.Bind(new(repository.FarmRepository), new(*repository.FarmRepositoryImpl)), service.FarmServiceInit, wire.Bind(new(service.FarmService), new(*service.FarmServiceImpl)), controller.FarmControllerInit, wire.Bind(new(controller.FarmController), new(*controller.FarmControllerImpl)))
+
+var leaderboardSet = wire.NewSet(repository.LeaderboardRepositoryInit, wire.Bind(new(repository.LeaderboardRepository), new(*repository.LeaderboardRepositoryImpl)), service.LeaderboardServiceInit, wire.Bind(new(service.LeaderboardService), new(*service.LeaderboardServiceImpl)), controller.LeaderboardControllerInit, wire.Bind(new(controller.LeaderboardController), new(*controller.LeaderboardControllerImpl)))
+
+var referralSet = wire.NewSet(repository.ReferralRepositoryInit, wire.Bind(new(repository.ReferralRepository), new(*repository.ReferralRepositoryImpl)), service.ReferralServiceInit, wire.Bind(new(service.ReferralService), new(*service.ReferralServiceImpl)), controller.ReferralControllerInit, wire.Bind(new(controller.ReferralController), new(*controller.ReferralControllerImpl)))
+
+var sessionSet = wire.NewSet(repository.SessionRepositoryInit, wire.Bind(new(repository.SessionRepository), new(*repository.SessionRepositoryImpl)), service.SessionServiceInit, wire.Bind(new(service.SessionService), new(*service.SessionServiceImpl)), controller.SessionControllerInit, wire.Bind(new(controller.SessionController), new(*controller.SessionControllerImpl)))
+
+var authSet = wire.NewSet(repository.AuthRepositoryInit, wire.Bind(new(repository.AuthRepository), new(*repository.AuthRepositoryImpl)), pkg.MailSenderInit, service.AuthenticatorRegistryInit, wire.Bind(new(service.AuthenticatorRegistry), new(*service.AuthenticatorRegistryImpl)), service.AuthServiceInit, wire.Bind(new(service.AuthService), new(*service.AuthServiceImpl)), controller.AuthControllerInit, wire.Bind(new(controller.AuthController), new(*controller.AuthControllerImpl)))
+
+var adminSet = wire.NewSet(service.AdminServiceInit, wire.Bind(new(service.AdminService), new(*service.AdminServiceImpl)), controller.AdminControllerInit, wire.Bind(new(controller.AdminController), new(*controller.AdminControllerImpl)))
@@ -1,24 +1,47 @@
 package config
 
 import (
-	"fmt"
 	"github.com/nats-io/nats.go"
 	log "github.com/sirupsen/logrus"
+	"os"
+)
+
+const (
+	defaultCoinReceiveSubject = "wallet.coins.receive"
+	coinReceiveQueue          = "wallet"
 )
 
 type NatsBroker interface {
 	CoinReceive()
 }
 
+// CoinReceiver processes a coin credit message and returns the reply payload
+type CoinReceiver interface {
+	ReceiveCoins(data []byte) []byte
+}
+
 type NatsBrokerImpl struct {
-	nc *nats.Conn
+	nc           *nats.Conn
+	coinReceiver CoinReceiver
 }
 
 func (u NatsBrokerImpl) CoinReceive() {
-	_, err := u.nc.Subscribe("foo", func(m *nats.Msg) {
-		fmt.Printf("Checking: %s\n", string(m.Data))
+	subject := os.Getenv("NATS_COIN_SUBJECT")
+	if subject == "" {
+		subject = defaultCoinReceiveSubject
+	}
+	// Queue group makes each credit land on a single instance
+	_, err := u.nc.QueueSubscribe(subject, coinReceiveQueue, func(m *nats.Msg) {
+		reply := u.coinReceiver.ReceiveCoins(m.Data)
+		if m.Reply == "" {
+			return
+		}
+		if err := m.Respond(reply); err != nil {
+			log.Error("Coin receive reply: ", err)
+		}
 	})
 	if err != nil {
+		log.Error("Coin receive subscribe: ", err)
 		return
 	}
 }
@@ -29,9 +52,10 @@ func (u NatsBrokerImpl) Init() {
 	log.Infoln("Initialized nats success")
 }
 
-func NatsBrokerInit(nc *nats.Conn) *NatsBrokerImpl {
+func NatsBrokerInit(nc *nats.Conn, coinReceiver CoinReceiver) *NatsBrokerImpl {
 	broker := &NatsBrokerImpl{
-		nc: nc,
+		nc:           nc,
+		coinReceiver: coinReceiver,
 	}
 	broker.Init()
 	return broker
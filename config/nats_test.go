@@ -0,0 +1,215 @@
+package config_test
+
+import (
+	"crazyfarmbackend/config"
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+	"crazyfarmbackend/src/repository"
+	"crazyfarmbackend/src/service"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"gorm.io/gorm"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryWallet is a WalletRepository keeping transactions in memory, keyed by tx id like the real one
+type memoryWallet struct {
+	mu           sync.Mutex
+	balances     map[uuid.UUID]int64
+	transactions map[string]dao.WalletTransaction
+}
+
+func newMemoryWallet(users ...uuid.UUID) *memoryWallet {
+	wallet := &memoryWallet{balances: map[uuid.UUID]int64{}, transactions: map[string]dao.WalletTransaction{}}
+	for _, user := range users {
+		wallet.balances[user] = 0
+	}
+	return wallet
+}
+
+func (m *memoryWallet) GetBalance(userId uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.balances[userId], nil
+}
+
+func (m *memoryWallet) AdjustCoins(userId uuid.UUID, amount int64, reason constant.WalletReason, txID string) (dao.WalletTransaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.transactions[txID]; ok {
+		return dao.WalletTransaction{}, repository.ErrDuplicateTransaction
+	}
+	balance, ok := m.balances[userId]
+	if !ok || balance+amount < 0 {
+		return dao.WalletTransaction{}, repository.ErrNotEnoughCoins
+	}
+	m.balances[userId] = balance + amount
+	transaction := dao.WalletTransaction{UserID: userId, Amount: amount, BalanceAfter: balance + amount, Reason: reason, TxID: txID}
+	m.transactions[txID] = transaction
+	return transaction, nil
+}
+
+func (m *memoryWallet) GetTransaction(txID string) (dao.WalletTransaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	transaction, ok := m.transactions[txID]
+	if !ok {
+		return dao.WalletTransaction{}, gorm.ErrRecordNotFound
+	}
+	return transaction, nil
+}
+
+func (m *memoryWallet) WithTx(*gorm.DB) repository.WalletRepository {
+	return m
+}
+
+func runNats(t *testing.T) *server.Server {
+	t.Helper()
+	s := natstest.RunRandClientPortServer()
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func connect(t *testing.T, s *server.Server) *nats.Conn {
+	t.Helper()
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+func request(t *testing.T, nc *nats.Conn, subject string, credit interface{}) dto.CoinCreditReply {
+	t.Helper()
+	data, err := json.Marshal(credit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := nc.Request(subject, data, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reply dto.CoinCreditReply
+	if err := json.Unmarshal(msg.Data, &reply); err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+func TestCoinReceiveRequestReply(t *testing.T) {
+	const subject = "test.coins.receive"
+	t.Setenv("NATS_COIN_SUBJECT", subject)
+	s := runNats(t)
+	user := uuid.New()
+	wallet := newMemoryWallet(user)
+	config.NatsBrokerInit(connect(t, s), service.WalletServiceInit(wallet))
+	client := connect(t, s)
+
+	tests := []struct {
+		name   string
+		credit interface{}
+		want   dto.CoinCreditReply
+	}{
+		{"credit", dto.CoinCredit{UserID: user, Amount: 50, TxID: "tx-1"}, dto.CoinCreditReply{OK: true, Balance: 50}},
+		{"second credit", dto.CoinCredit{UserID: user, Amount: 25, TxID: "tx-2"}, dto.CoinCreditReply{OK: true, Balance: 75}},
+		{"redelivery", dto.CoinCredit{UserID: user, Amount: 50, TxID: "tx-1"}, dto.CoinCreditReply{OK: true, Duplicate: true, Balance: 50}},
+		{"tx id reused", dto.CoinCredit{UserID: user, Amount: 10, TxID: "tx-1"}, dto.CoinCreditReply{Error: "tx_id reused with different payload"}},
+		{"unknown user", dto.CoinCredit{UserID: uuid.New(), Amount: 10, TxID: "tx-3"}, dto.CoinCreditReply{Error: "user not found"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reply := request(t, client, subject, tt.credit); reply != tt.want {
+				t.Fatalf("reply %+v, want %+v", reply, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid payload", func(t *testing.T) {
+		reply := request(t, client, subject, map[string]interface{}{"user_id": user, "amount": -5})
+		if reply.OK || reply.Error == "" {
+			t.Fatalf("invalid credit accepted: %+v", reply)
+		}
+	})
+
+	if balance, _ := wallet.GetBalance(user); balance != 75 {
+		t.Fatalf("balance %d, want 75", balance)
+	}
+}
+
+// countingReceiver records the credits it got and replies with a fixed payload
+type countingReceiver struct {
+	mu       sync.Mutex
+	received [][]byte
+}
+
+func (r *countingReceiver) ReceiveCoins(data []byte) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.received = append(r.received, data)
+	return []byte(`{"ok":true}`)
+}
+
+func (r *countingReceiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.received)
+}
+
+// Plain publishes carry no reply subject, every one is processed once across the queue group
+func TestCoinReceivePublishQueueGroup(t *testing.T) {
+	const subject = "test.coins.publish"
+	t.Setenv("NATS_COIN_SUBJECT", subject)
+	s := runNats(t)
+	first, second := &countingReceiver{}, &countingReceiver{}
+	firstConn, secondConn := connect(t, s), connect(t, s)
+	config.NatsBrokerInit(firstConn, first)
+	config.NatsBrokerInit(secondConn, second)
+	for _, nc := range []*nats.Conn{firstConn, secondConn} {
+		if err := nc.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := connect(t, s)
+	const messages = 100
+	for i := 0; i < messages; i++ {
+		if err := client.Publish(subject, []byte(`{}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for first.count()+second.count() < messages && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Give a duplicate delivery the chance to show up
+	time.Sleep(50 * time.Millisecond)
+	if total := first.count() + second.count(); total != messages {
+		t.Fatalf("%d deliveries for %d messages", total, messages)
+	}
+}
+
+func TestCoinReceiveDefaultSubject(t *testing.T) {
+	t.Setenv("NATS_COIN_SUBJECT", "")
+	s := runNats(t)
+	receiver := &countingReceiver{}
+	config.NatsBrokerInit(connect(t, s), receiver)
+
+	msg, err := connect(t, s).Request("wallet.coins.receive", []byte(`{}`), 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Data) != `{"ok":true}` || receiver.count() != 1 {
+		t.Fatalf("reply %q after %d deliveries", msg.Data, receiver.count())
+	}
+}
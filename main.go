@@ -5,6 +5,7 @@ import (
 	"crazyfarmbackend/config/di"
 	"crazyfarmbackend/src/api"
 	"github.com/joho/godotenv"
+	"os"
 )
 
 func init() {
@@ -16,6 +17,12 @@ func main() {
 	port := "8000"
 
 	init := di.Init()
+	if len(os.Args) > 1 {
+		runCommand(init, os.Args[1:])
+		return
+	}
+	init.LeaderboardService.StartRefresh()
+	init.SessionService.StartPruning()
 	app := api.Init(init)
 	app.Run(":" + port)
 }
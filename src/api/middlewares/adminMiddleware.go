@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/pkg"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware lets through staff whose role has every one of the permissions, AuthMiddleware has to
+// run first. Without permissions any staff role passes
+func (m MiddlewareServiceImpl) AdminMiddleware(permissions ...constant.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer pkg.PanicHandler(c)
+		user, ok := c.MustGet("user").(dao.User)
+		if !ok {
+			pkg.PanicException(constant.Unauthorized, "")
+		}
+		if !user.Role.IsStaff() {
+			pkg.PanicException(constant.Forbidden, "Admin access required")
+		}
+		for _, permission := range permissions {
+			if !user.Role.Can(permission) {
+				pkg.PanicException(constant.Forbidden, "Missing permission "+string(permission))
+			}
+		}
+		c.Next()
+	}
+}
@@ -12,12 +12,17 @@ import (
 
 type MiddlewareService interface {
 	AuthMiddleware() gin.HandlerFunc
+	AdminMiddleware(permissions ...constant.Permission) gin.HandlerFunc
 	RequestIdMiddleware() gin.HandlerFunc
 	CorsMiddleware() gin.HandlerFunc
+	RateLimitMiddleware(policyName string) gin.HandlerFunc
 }
 
 type MiddlewareServiceImpl struct {
-	userRepository repository.UserRepository
+	userRepository    repository.UserRepository
+	sessionRepository repository.SessionRepository
+	rateLimiter       pkg.RateLimiter
+	rateLimitPolicies map[string]rateLimitPolicy
 }
 
 func (m MiddlewareServiceImpl) AuthMiddleware() gin.HandlerFunc {
@@ -53,6 +58,19 @@ func (m MiddlewareServiceImpl) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Checked on every request, so a revoked session stops working before its access tokens expire
+		sessionIDStr, _ := claims["sid"].(string)
+		sessionID, err := uuid.Parse(sessionIDStr)
+		if err != nil {
+			pkg.PanicException(constant.Unauthorized, "Invalid session in token")
+			return
+		}
+		active, err := m.sessionRepository.IsSessionActive(sessionID)
+		if err != nil || !active {
+			pkg.PanicException(constant.Unauthorized, "Session expired")
+			return
+		}
+
 		userAuth, err := m.userRepository.GetByAuthId(userAuthID)
 		if err != nil {
 			pkg.PanicException(constant.Unauthorized, "User not found")
@@ -61,12 +79,19 @@ func (m MiddlewareServiceImpl) AuthMiddleware() gin.HandlerFunc {
 
 		c.Set("user", userAuth.User)
 		c.Set("user_auth", userAuth)
+		c.Set("session_id", sessionID)
 		c.Next()
 	}
 }
 
-func MiddlewareServiceInit(userRepository repository.UserRepository) *MiddlewareServiceImpl {
+func MiddlewareServiceInit(
+	userRepository repository.UserRepository,
+	sessionRepository repository.SessionRepository,
+	rateLimiter pkg.RateLimiter) *MiddlewareServiceImpl {
 	return &MiddlewareServiceImpl{
-		userRepository: userRepository,
+		userRepository:    userRepository,
+		sessionRepository: sessionRepository,
+		rateLimiter:       rateLimiter,
+		rateLimitPolicies: loadRateLimitPolicies(),
 	}
 }
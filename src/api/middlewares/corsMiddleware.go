@@ -9,5 +9,6 @@ func (u MiddlewareServiceImpl) CorsMiddleware() gin.HandlerFunc {
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"http://localhost:5173", "https://front.gentelmanclub.pro", "https://devfront.gentelmanclub.pro"}
 	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+	config.ExposeHeaders = []string{"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"}
 	return cors.New(config)
 }
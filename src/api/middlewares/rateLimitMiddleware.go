@@ -0,0 +1,94 @@
+package middlewares
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/pkg"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate limit policies, each route group names the one it is throttled by
+const (
+	RateLimitAuth      = "auth"
+	RateLimitEmailCode = "email_code"
+	RateLimitUser      = "user"
+	RateLimitTaskCheck = "task_check"
+)
+
+type rateLimitPolicy struct {
+	limit pkg.RateLimit
+	// Buckets per signed in user, AuthMiddleware has to run first. Otherwise per client IP
+	byUser bool
+}
+
+// Defaults, RATE_LIMIT_<POLICY> overrides the limit as "<burst>/<period>"
+var defaultRateLimitPolicies = map[string]rateLimitPolicy{
+	RateLimitAuth:      {limit: pkg.RateLimit{Burst: 20, Period: time.Minute}},
+	RateLimitEmailCode: {limit: pkg.RateLimit{Burst: 5, Period: 10 * time.Minute}},
+	RateLimitUser:      {limit: pkg.RateLimit{Burst: 120, Period: time.Minute}, byUser: true},
+	// Every check waits on a NATS request to the subscription checker
+	RateLimitTaskCheck: {limit: pkg.RateLimit{Burst: 6, Period: time.Minute}, byUser: true},
+}
+
+func loadRateLimitPolicies() map[string]rateLimitPolicy {
+	policies := make(map[string]rateLimitPolicy, len(defaultRateLimitPolicies))
+	for name, policy := range defaultRateLimitPolicies {
+		key := "RATE_LIMIT_" + strings.ToUpper(name)
+		if value := os.Getenv(key); value != "" {
+			limit, err := pkg.ParseRateLimit(value)
+			if err != nil {
+				log.Errorf("%s: %v, keeping %s", key, err, policy.limit)
+			} else {
+				policy.limit = limit
+			}
+		}
+		policies[name] = policy
+	}
+	return policies
+}
+
+// RateLimitMiddleware throttles the route with a token bucket of the named policy. Responses carry the
+// X-RateLimit-* headers of the bucket, a request over the limit gets 429 with Retry-After
+func (m MiddlewareServiceImpl) RateLimitMiddleware(policyName string) gin.HandlerFunc {
+	policy, ok := m.rateLimitPolicies[policyName]
+	if !ok {
+		log.Fatal("Unknown rate limit policy: ", policyName)
+	}
+	return func(c *gin.Context) {
+		key := policyName + ":ip:" + c.ClientIP()
+		if policy.byUser {
+			if user, ok := c.Get("user"); ok {
+				key = policyName + ":user:" + user.(dao.User).ID.String()
+			}
+		}
+
+		result, err := m.rateLimiter.Take(key, policy.limit)
+		if err != nil {
+			// Better to let players through than to lock everyone out while the backend is down
+			log.Error("Rate limit: ", err)
+			c.Next()
+			return
+		}
+		header := c.Writer.Header()
+		header.Set("X-RateLimit-Limit", strconv.Itoa(policy.limit.Burst))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+		if !result.Allowed {
+			header.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, pkg.BuildResponse(constant.TooManyRequests, pkg.Null()))
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -5,10 +5,28 @@ import (
 	"crazyfarmbackend/src/api/routers"
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strings"
 )
 
+// trustedProxies reads TRUSTED_PROXIES, a comma separated list of proxy IPs or CIDRs whose
+// X-Forwarded-For is believed. None by default, so ClientIP is the peer address
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
 func Init(init *di.Initialization) *gin.Engine {
 	router := gin.New()
+	if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatal("TRUSTED_PROXIES: ", err)
+	}
 	router.Use(init.MiddlewareService.RequestIdMiddleware())
 	router.Use(init.MiddlewareService.CorsMiddleware())
 	router.Use(gin.Logger())
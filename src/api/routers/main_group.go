@@ -2,6 +2,8 @@ package routers
 
 import (
 	"crazyfarmbackend/config/di"
+	"crazyfarmbackend/src/api/middlewares"
+	"crazyfarmbackend/src/constant"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,15 +15,112 @@ func SetupMainGroup(router *gin.Engine, init *di.Initialization) {
 	api := router.Group("/api/v1")
 	{
 		api.GET("/ping", pong)
-		api.GET("/user/auth", init.UserController.AuthUser)
-		api.GET("/user/me", init.MiddlewareService.AuthMiddleware(), init.UserController.GetMe)
-		api.GET("/user/upgrade", init.MiddlewareService.AuthMiddleware(), init.UserController.GetMyUpgrades)
-		api.GET("/inventory/fields", init.MiddlewareService.AuthMiddleware(), init.InventoryController.GetMyFields)
-		api.GET("/inventory/plant", init.MiddlewareService.AuthMiddleware(), init.InventoryController.PlantField)
-		api.GET("/user/referrals", init.MiddlewareService.AuthMiddleware(), init.UserController.GetMyReferrals)
-		api.GET("/inventory/all", init.MiddlewareService.AuthMiddleware(), init.InventoryController.GetInventoryItems)
-		api.GET("/tasks/all", init.MiddlewareService.AuthMiddleware(), init.TaskController.GetAllTasks)
-		api.GET("/tasks/check", init.MiddlewareService.AuthMiddleware(), init.TaskController.Check)
-		api.GET("/tasks/claim", init.MiddlewareService.AuthMiddleware(), init.TaskController.Claim)
+	}
+
+	// Sign in is throttled per IP, there is no user yet
+	public := api.Group("", init.MiddlewareService.RateLimitMiddleware(middlewares.RateLimitAuth))
+	{
+		public.GET("/user/auth", init.UserController.AuthUser)
+		public.POST("/user/auth", init.UserController.AuthUser)
+		public.GET("/user/auth/ton/payload", init.AuthController.GetTonProofPayload)
+		public.POST("/user/auth/email/code", init.MiddlewareService.RateLimitMiddleware(middlewares.RateLimitEmailCode), init.AuthController.SendEmailCode)
+		public.POST("/user/refresh", init.SessionController.Refresh)
+	}
+
+	user := api.Group("", init.MiddlewareService.AuthMiddleware(), init.MiddlewareService.RateLimitMiddleware(middlewares.RateLimitUser))
+	{
+		user.POST("/user/logout", init.SessionController.Logout)
+		user.POST("/user/logout/all", init.SessionController.LogoutEverywhere)
+		user.GET("/user/auth-methods", init.AuthController.GetAuthMethods)
+		user.POST("/user/auth-methods/link", init.AuthController.LinkAuthMethod)
+		user.POST("/user/auth-methods/unlink", init.AuthController.UnlinkAuthMethod)
+		user.POST("/user/auth-methods/merge", init.AuthController.MergeAccount)
+		user.GET("/user/me", init.UserController.GetMe)
+		user.GET("/user/upgrade", init.UserController.GetMyUpgrades)
+		user.GET("/user/upgrade/levels", init.UserController.GetFarmLevels)
+		user.POST("/user/upgrade/buy", init.UserController.BuyFarmUpgrade)
+		user.GET("/inventory/fields", init.InventoryController.GetMyFields)
+		user.GET("/inventory/plant", init.InventoryController.PlantField)
+		user.POST("/inventory/harvest", init.InventoryController.HarvestField)
+		user.POST("/inventory/harvest/all", init.InventoryController.HarvestAll)
+		user.GET("/user/referrals", init.UserController.GetMyReferrals)
+		user.GET("/user/referrals/rewards", init.ReferralController.GetMyReferralProgram)
+		user.GET("/inventory/all", init.InventoryController.GetInventoryItems)
+		user.GET("/inventory/history", init.InventoryController.GetHistory)
+		user.GET("/wallet", init.WalletController.GetWallet)
+		user.GET("/shop/items", init.ShopController.GetItems)
+		user.POST("/shop/buy", init.ShopController.Buy)
+		user.GET("/shop/sell/prices", init.ShopController.GetSellPrices)
+		user.POST("/shop/sell", init.ShopController.Sell)
+		user.POST("/trade/gift", init.TradeController.SendGift)
+		user.GET("/trade/offers", init.TradeController.GetOffers)
+		user.POST("/trade/offer", init.TradeController.CreateOffer)
+		user.POST("/trade/offer/accept", init.TradeController.AcceptOffer)
+		user.POST("/trade/offer/reject", init.TradeController.RejectOffer)
+		user.POST("/trade/offer/cancel", init.TradeController.CancelOffer)
+		user.GET("/farm/visit", init.FarmController.Visit)
+		user.POST("/farm/steal", init.FarmController.Steal)
+		user.GET("/notifications", init.FarmController.GetNotifications)
+		user.POST("/notifications/read", init.FarmController.ReadNotifications)
+		user.GET("/leaderboard", init.LeaderboardController.GetLeaderboard)
+		user.GET("/tasks/all", init.TaskController.GetAllTasks)
+		user.GET("/tasks/check", init.MiddlewareService.RateLimitMiddleware(middlewares.RateLimitTaskCheck), init.TaskController.Check)
+		user.GET("/tasks/claim", init.TaskController.Claim)
+		user.GET("/plants", init.PlantController.GetPlants)
+	}
+
+	admin := router.Group("/api/admin",
+		init.MiddlewareService.AuthMiddleware(),
+		init.MiddlewareService.RateLimitMiddleware(middlewares.RateLimitUser),
+		init.MiddlewareService.AdminMiddleware())
+	{
+		admin.GET("/me", init.AdminController.GetMyRole)
+		admin.GET("/roles", init.AdminController.GetRoles)
+	}
+
+	plants := admin.Group("/plants", init.MiddlewareService.AdminMiddleware(constant.PERMISSION_PLANTS))
+	{
+		plants.GET("", init.PlantController.GetAllPlants)
+		plants.POST("", init.PlantController.CreatePlant)
+		plants.PUT("", init.PlantController.UpdatePlant)
+		plants.DELETE("", init.PlantController.DeletePlant)
+	}
+
+	tasks := admin.Group("/tasks", init.MiddlewareService.AdminMiddleware(constant.PERMISSION_TASKS))
+	{
+		tasks.GET("", init.TaskController.GetTaskDefinitions)
+		tasks.GET("/types", init.TaskController.GetTaskTypes)
+		tasks.GET("/stats", init.TaskController.GetTaskStats)
+		tasks.POST("", init.TaskController.CreateTask)
+		tasks.PUT("", init.TaskController.UpdateTask)
+		tasks.POST("/enable", init.TaskController.EnableTask)
+		tasks.POST("/disable", init.TaskController.DisableTask)
+		tasks.POST("/reorder", init.TaskController.ReorderTasks)
+	}
+
+	referrals := admin.Group("/referral-rewards", init.MiddlewareService.AdminMiddleware(constant.PERMISSION_REFERRALS))
+	{
+		referrals.GET("", init.ReferralController.GetReferralRewards)
+		referrals.PUT("", init.ReferralController.SaveReferralReward)
+	}
+
+	shop := admin.Group("/shop", init.MiddlewareService.AdminMiddleware(constant.PERMISSION_SHOP))
+	{
+		shop.GET("/items", init.ShopController.GetAllItems)
+		shop.POST("/items", init.ShopController.CreateItem)
+		shop.PUT("/items", init.ShopController.UpdateItem)
+		shop.GET("/farm-levels", init.UserController.GetFarmLevels)
+		shop.PUT("/farm-levels", init.ShopController.SaveFarmLevel)
+	}
+
+	roles := admin.Group("/users", init.MiddlewareService.AdminMiddleware(constant.PERMISSION_ROLES))
+	{
+		roles.GET("/staff", init.AdminController.GetStaff)
+		roles.PUT("/role", init.AdminController.SetUserRole)
+	}
+
+	grants := admin.Group("/inventory", init.MiddlewareService.AdminMiddleware(constant.PERMISSION_GRANTS))
+	{
+		grants.POST("/grant", init.AdminController.GrantItems)
 	}
 }
@@ -7,6 +7,8 @@ type General int
 // Auth methods
 const (
 	Telegram string = "telegram"
+	Ton      string = "ton"
+	Email    string = "email"
 )
 
 // Constant Api
@@ -19,14 +21,11 @@ const (
 	WrongBody
 	WrongMethod
 	WrongDataBody
+	Conflict
+	TooManyRequests
+	Forbidden
 )
 
 func (r ResponseStatus) GetResponseStatus() string {
-	return [...]string{"SUCCESS", "DATA_NOT_FOUND", "UNKNOWN_ERROR", "INVALID_REQUEST", "UNAUTHORIZED", "WRONG_BODY", "WRONG_METHOD", "WRONG_DATA_BODY"}[r-1]
-}
-
-type UpgradeLvl int
-
-func GetLvlMaxFields(r int) int {
-	return [...]int{4, 8, 16}[r-1]
+	return [...]string{"SUCCESS", "DATA_NOT_FOUND", "UNKNOWN_ERROR", "INVALID_REQUEST", "UNAUTHORIZED", "WRONG_BODY", "WRONG_METHOD", "WRONG_DATA_BODY", "CONFLICT", "TOO_MANY_REQUESTS", "FORBIDDEN"}[r-1]
 }
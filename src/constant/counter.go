@@ -0,0 +1,14 @@
+package constant
+
+type Counter string
+
+const (
+	COUNTER_PLANTED      Counter = "PLANTED"
+	COUNTER_HARVESTED    Counter = "HARVESTED"
+	COUNTER_LOGIN_STREAK Counter = "LOGIN_STREAK"
+	// Only hourly buckets, 1 for every hour with a sign in, recurring login tasks count the days among them
+	COUNTER_LOGIN Counter = "LOGIN"
+)
+
+// Counter key summing all plants, per plant counters use the plant name as key
+const COUNTER_KEY_ANY = ""
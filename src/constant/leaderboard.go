@@ -0,0 +1,20 @@
+package constant
+
+type LeaderboardBoard string
+
+const (
+	LEADERBOARD_HARVESTED       LeaderboardBoard = "HARVESTED"
+	LEADERBOARD_INVENTORY_WORTH LeaderboardBoard = "INVENTORY_WORTH"
+	LEADERBOARD_REFERRALS       LeaderboardBoard = "REFERRALS"
+)
+
+// Period key of all time standings, weekly standings use the ISO week key from pkg.WeekKey
+const LEADERBOARD_ALL_TIME = "all"
+
+func IsValidLeaderboard(board LeaderboardBoard) bool {
+	switch board {
+	case LEADERBOARD_HARVESTED, LEADERBOARD_INVENTORY_WORTH, LEADERBOARD_REFERRALS:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+package constant
+
+type LedgerReason string
+
+const (
+	LEDGER_OPENING_BALANCE LedgerReason = "OPENING_BALANCE"
+	LEDGER_PLANT           LedgerReason = "PLANT"
+	LEDGER_HARVEST         LedgerReason = "HARVEST"
+	LEDGER_TASK_CLAIM      LedgerReason = "TASK_CLAIM"
+	LEDGER_FARM_UPGRADE    LedgerReason = "FARM_UPGRADE"
+	LEDGER_SHOP_PURCHASE   LedgerReason = "SHOP_PURCHASE"
+	LEDGER_SELL            LedgerReason = "SELL"
+	LEDGER_GIFT            LedgerReason = "GIFT"
+	LEDGER_TRADE           LedgerReason = "TRADE"
+	LEDGER_STEAL           LedgerReason = "STEAL"
+	LEDGER_ADMIN_GRANT     LedgerReason = "ADMIN_GRANT"
+	LEDGER_REFERRAL_REWARD LedgerReason = "REFERRAL_REWARD"
+	LEDGER_ACCOUNT_MERGE   LedgerReason = "ACCOUNT_MERGE"
+)
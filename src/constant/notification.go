@@ -0,0 +1,8 @@
+package constant
+
+type NotificationType string
+
+const (
+	NOTIFICATION_STEAL           NotificationType = "STEAL"
+	NOTIFICATION_REFERRAL_REWARD NotificationType = "REFERRAL_REWARD" // Actor is the referral whose milestone paid out
+)
@@ -12,33 +12,18 @@ const (
 	CHRISTMAS_TREE Plant = "CHRISTMAS_TREE"
 )
 
-var Plants = []Plant{
-	MONEY,
-	STRAWBERRY,
-	ROSE,
-	SUNFLOWER,
-	CHRISTMAS_TREE,
+type DefaultPlant struct {
+	Name      Plant
+	GrowTime  time.Duration
+	Reward    int   // Reward for harvesting
+	SellPrice int64 // Coins paid per unit sold, 0 means not sellable
 }
 
-type PlantInfo struct {
-	Name     Plant
-	GrowTime time.Duration // Grow time in days
-	Reward   int           // Reward for harvesting
-}
-
-var plantData = []PlantInfo{
-	{Name: MONEY, GrowTime: time.Hour * 5, Reward: 1},
-	{Name: STRAWBERRY, GrowTime: time.Hour * 5, Reward: 1},
-	{Name: ROSE, GrowTime: time.Hour * 5, Reward: 1},
-	{Name: SUNFLOWER, GrowTime: time.Hour * 5, Reward: 2},
-	{Name: CHRISTMAS_TREE, GrowTime: time.Hour * 5, Reward: 1},
-}
-
-func IsValidPlant(plant Plant) bool {
-	for _, validPlant := range Plants {
-		if plant == validPlant {
-			return true
-		}
-	}
-	return false
+// DefaultPlants seed an empty plant catalog, afterwards the plant_infos table is the source of truth
+var DefaultPlants = []DefaultPlant{
+	{Name: MONEY, GrowTime: time.Hour * 5, Reward: 1, SellPrice: 0},
+	{Name: STRAWBERRY, GrowTime: time.Hour * 5, Reward: 1, SellPrice: 6},
+	{Name: ROSE, GrowTime: time.Hour * 5, Reward: 1, SellPrice: 9},
+	{Name: SUNFLOWER, GrowTime: time.Hour * 5, Reward: 2, SellPrice: 6},
+	{Name: CHRISTMAS_TREE, GrowTime: time.Hour * 5, Reward: 1, SellPrice: 30},
 }
@@ -0,0 +1,9 @@
+package constant
+
+type ReferralMilestone string
+
+const (
+	REFERRAL_SIGNUP     ReferralMilestone = "SIGNUP"     // Paid as soon as the referral signs up
+	REFERRAL_HARVEST    ReferralMilestone = "HARVEST"    // Referral harvested Threshold crops
+	REFERRAL_FARM_LEVEL ReferralMilestone = "FARM_LEVEL" // Referral reached farm level Threshold
+)
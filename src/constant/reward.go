@@ -0,0 +1,11 @@
+package constant
+
+type RewardKind string
+
+const (
+	REWARD_PLANT   RewardKind = "PLANT"   // Amount of Plant added to the inventory
+	REWARD_COINS   RewardKind = "COINS"   // Amount of coins credited to the wallet
+	REWARD_FARM_XP RewardKind = "FARM_XP" // Amount of farm experience
+	// REWARD_FARM_LEVEL unlocks farm level Amount for free, users already at or above it get nothing
+	REWARD_FARM_LEVEL RewardKind = "FARM_LEVEL"
+)
@@ -0,0 +1,48 @@
+package constant
+
+type Role string
+type Permission string
+
+const (
+	ROLE_PLAYER    Role = "PLAYER"
+	ROLE_MODERATOR Role = "MODERATOR" // Runs the game content: plants, tasks, referral rewards, the shop and farm levels
+	ROLE_ADMIN     Role = "ADMIN"     // Everything a moderator does, grants roles and hands out items
+)
+
+const (
+	PERMISSION_PLANTS    Permission = "PLANTS"    // Plant catalogue
+	PERMISSION_TASKS     Permission = "TASKS"     // Task definitions and their stats
+	PERMISSION_REFERRALS Permission = "REFERRALS" // Referral reward tiers
+	PERMISSION_SHOP      Permission = "SHOP"      // Shop items, their prices and stock, and farm levels
+	PERMISSION_ROLES     Permission = "ROLES"     // Roles of other users
+	PERMISSION_GRANTS    Permission = "GRANTS"    // Items put into or taken out of a user's inventory
+)
+
+// Roles in ascending order of power
+var Roles = []Role{ROLE_PLAYER, ROLE_MODERATOR, ROLE_ADMIN}
+
+// RolePermissions lists what every role may do, a role missing here is unknown
+var RolePermissions = map[Role][]Permission{
+	ROLE_PLAYER:    {},
+	ROLE_MODERATOR: {PERMISSION_PLANTS, PERMISSION_TASKS, PERMISSION_REFERRALS, PERMISSION_SHOP},
+	ROLE_ADMIN:     {PERMISSION_PLANTS, PERMISSION_TASKS, PERMISSION_REFERRALS, PERMISSION_SHOP, PERMISSION_ROLES, PERMISSION_GRANTS},
+}
+
+func (r Role) Valid() bool {
+	_, ok := RolePermissions[r]
+	return ok
+}
+
+// IsStaff reports whether the role has any permission, only staff reaches the admin api
+func (r Role) IsStaff() bool {
+	return len(RolePermissions[r]) > 0
+}
+
+func (r Role) Can(permission Permission) bool {
+	for _, p := range RolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
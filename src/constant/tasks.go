@@ -6,6 +6,12 @@ const (
 	FRIENDS   Task = "FRIENDS"
 	SUBSCRIBE Task = "SUBSCRIBE"
 	INVENTORY Task = "INVENTORY"
+
+	// Progress tasks fed by gameplay counters
+	PLANT        Task = "PLANT"
+	HARVEST      Task = "HARVEST"
+	LOGIN_STREAK Task = "LOGIN_STREAK"
+	FARM_LEVEL   Task = "FARM_LEVEL"
 )
 
 type TaskCompleteStatus string
@@ -15,3 +21,12 @@ const (
 	TASK_COMPLETE_DONE     TaskCompleteStatus = "TASK_COMPLETE_DONE"
 	TASK_COMPLETE_FINISHED TaskCompleteStatus = "TASK_COMPLETE_FINISHED"
 )
+
+type TaskRecurrence string
+
+const (
+	TASK_RECURRENCE_NONE   TaskRecurrence = ""
+	TASK_RECURRENCE_DAILY  TaskRecurrence = "DAILY"
+	TASK_RECURRENCE_WEEKLY TaskRecurrence = "WEEKLY"
+	TASK_RECURRENCE_HOURS  TaskRecurrence = "HOURS"
+)
@@ -0,0 +1,10 @@
+package constant
+
+type TradeStatus string
+
+const (
+	TRADE_PENDING   TradeStatus = "PENDING"
+	TRADE_ACCEPTED  TradeStatus = "ACCEPTED"
+	TRADE_REJECTED  TradeStatus = "REJECTED"
+	TRADE_CANCELLED TradeStatus = "CANCELLED"
+)
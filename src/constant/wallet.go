@@ -0,0 +1,13 @@
+package constant
+
+type WalletReason string
+
+const (
+	WALLET_EXTERNAL_CREDIT WalletReason = "EXTERNAL_CREDIT"
+	WALLET_FARM_UPGRADE    WalletReason = "FARM_UPGRADE"
+	WALLET_SHOP_PURCHASE   WalletReason = "SHOP_PURCHASE"
+	WALLET_SELL            WalletReason = "SELL"
+	WALLET_TASK_CLAIM      WalletReason = "TASK_CLAIM"
+	WALLET_REFERRAL_REWARD WalletReason = "REFERRAL_REWARD"
+	WALLET_ACCOUNT_MERGE   WalletReason = "ACCOUNT_MERGE"
+)
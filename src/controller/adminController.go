@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type AdminController interface {
+	GetMyRole(c *gin.Context)
+	GetRoles(c *gin.Context)
+	GetStaff(c *gin.Context)
+	SetUserRole(c *gin.Context)
+	GrantItems(c *gin.Context)
+}
+
+type AdminControllerImpl struct {
+	adminService service.AdminService
+}
+
+func (a AdminControllerImpl) GetMyRole(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	role := a.adminService.GetMyRole(c)
+	c.JSON(http.StatusOK, role)
+	return
+}
+
+func (a AdminControllerImpl) GetRoles(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	roles := a.adminService.GetRoles(c)
+	c.JSON(http.StatusOK, roles)
+	return
+}
+
+func (a AdminControllerImpl) GetStaff(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	staff := a.adminService.GetStaff(c)
+	c.JSON(http.StatusOK, staff)
+	return
+}
+
+func (a AdminControllerImpl) SetUserRole(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	user := a.adminService.SetUserRole(c)
+	c.JSON(http.StatusOK, user)
+	return
+}
+
+func (a AdminControllerImpl) GrantItems(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	stack := a.adminService.GrantItems(c)
+	c.JSON(http.StatusOK, stack)
+	return
+}
+
+func AdminControllerInit(adminService service.AdminService) *AdminControllerImpl {
+	return &AdminControllerImpl{
+		adminService: adminService,
+	}
+}
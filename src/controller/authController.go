@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type AuthController interface {
+	GetTonProofPayload(c *gin.Context)
+	SendEmailCode(c *gin.Context)
+	GetAuthMethods(c *gin.Context)
+	LinkAuthMethod(c *gin.Context)
+	UnlinkAuthMethod(c *gin.Context)
+	MergeAccount(c *gin.Context)
+}
+
+type AuthControllerImpl struct {
+	authService service.AuthService
+}
+
+func (a AuthControllerImpl) GetTonProofPayload(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	payload := a.authService.GetTonProofPayload(c)
+	c.JSON(http.StatusOK, payload)
+	return
+}
+
+func (a AuthControllerImpl) SendEmailCode(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	a.authService.SendEmailCode(c)
+	c.JSON(http.StatusOK, pkg.BuildResponse(constant.Success, pkg.Null()))
+	return
+}
+
+func (a AuthControllerImpl) GetAuthMethods(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	methods := a.authService.GetAuthMethods(c)
+	c.JSON(http.StatusOK, methods)
+	return
+}
+
+func (a AuthControllerImpl) LinkAuthMethod(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	methods, conflict := a.authService.LinkAuthMethod(c)
+	if conflict != nil {
+		c.JSON(http.StatusConflict, pkg.BuildResponse(constant.Conflict, conflict))
+		return
+	}
+	c.JSON(http.StatusOK, methods)
+	return
+}
+
+func (a AuthControllerImpl) UnlinkAuthMethod(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	methods := a.authService.UnlinkAuthMethod(c)
+	c.JSON(http.StatusOK, methods)
+	return
+}
+
+func (a AuthControllerImpl) MergeAccount(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	methods := a.authService.MergeAccount(c)
+	c.JSON(http.StatusOK, methods)
+	return
+}
+
+func AuthControllerInit(authService service.AuthService) *AuthControllerImpl {
+	return &AuthControllerImpl{
+		authService: authService,
+	}
+}
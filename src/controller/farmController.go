@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type FarmController interface {
+	Visit(c *gin.Context)
+	Steal(c *gin.Context)
+	GetNotifications(c *gin.Context)
+	ReadNotifications(c *gin.Context)
+}
+
+type FarmControllerImpl struct {
+	farmService service.FarmService
+}
+
+func (f *FarmControllerImpl) Visit(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	visit := f.farmService.Visit(c)
+	c.JSON(http.StatusOK, visit)
+	return
+}
+
+func (f *FarmControllerImpl) Steal(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	theft := f.farmService.Steal(c)
+	c.JSON(http.StatusOK, theft)
+	return
+}
+
+func (f *FarmControllerImpl) GetNotifications(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	notifications := f.farmService.GetNotifications(c)
+	c.JSON(http.StatusOK, notifications)
+	return
+}
+
+func (f *FarmControllerImpl) ReadNotifications(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	f.farmService.ReadNotifications(c)
+	c.JSON(http.StatusOK, pkg.BuildResponse(constant.Success, pkg.Null()))
+	return
+}
+
+func FarmControllerInit(farmService service.FarmService) *FarmControllerImpl {
+	return &FarmControllerImpl{
+		farmService: farmService,
+	}
+}
@@ -11,6 +11,9 @@ type InventoryController interface {
 	GetInventoryItems(c *gin.Context)
 	GetMyFields(c *gin.Context)
 	PlantField(c *gin.Context)
+	HarvestField(c *gin.Context)
+	HarvestAll(c *gin.Context)
+	GetHistory(c *gin.Context)
 }
 
 type InventoryControllerImpl struct {
@@ -42,6 +45,27 @@ func (u *InventoryControllerImpl) PlantField(c *gin.Context) {
 	return
 }
 
+func (u *InventoryControllerImpl) HarvestField(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	harvestResult := u.inventoryService.HarvestField(c)
+	c.JSON(http.StatusOK, harvestResult)
+	return
+}
+
+func (u *InventoryControllerImpl) HarvestAll(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	harvestResults := u.inventoryService.HarvestAll(c)
+	c.JSON(http.StatusOK, harvestResults)
+	return
+}
+
+func (u *InventoryControllerImpl) GetHistory(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	history := u.inventoryService.GetHistory(c)
+	c.JSON(http.StatusOK, history)
+	return
+}
+
 func InventoryControllerInit(inventoryService service.InventoryService) *InventoryControllerImpl {
 	return &InventoryControllerImpl{
 		inventoryService: inventoryService,
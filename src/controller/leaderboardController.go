@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type LeaderboardController interface {
+	GetLeaderboard(c *gin.Context)
+}
+
+type LeaderboardControllerImpl struct {
+	leaderboardService service.LeaderboardService
+}
+
+func (l *LeaderboardControllerImpl) GetLeaderboard(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	leaderboard := l.leaderboardService.GetLeaderboard(c)
+	c.JSON(http.StatusOK, leaderboard)
+	return
+}
+
+func LeaderboardControllerInit(leaderboardService service.LeaderboardService) *LeaderboardControllerImpl {
+	return &LeaderboardControllerImpl{
+		leaderboardService: leaderboardService,
+	}
+}
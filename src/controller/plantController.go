@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type PlantController interface {
+	GetPlants(c *gin.Context)
+	GetAllPlants(c *gin.Context)
+	CreatePlant(c *gin.Context)
+	UpdatePlant(c *gin.Context)
+	DeletePlant(c *gin.Context)
+}
+
+type PlantControllerImpl struct {
+	plantService service.PlantService
+}
+
+func (p *PlantControllerImpl) GetPlants(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	plants := p.plantService.GetPlants(c)
+	c.JSON(http.StatusOK, plants)
+	return
+}
+
+func (p *PlantControllerImpl) GetAllPlants(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	plants := p.plantService.GetAllPlants(c)
+	c.JSON(http.StatusOK, plants)
+	return
+}
+
+func (p *PlantControllerImpl) CreatePlant(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	plant := p.plantService.CreatePlant(c)
+	c.JSON(http.StatusOK, plant)
+	return
+}
+
+func (p *PlantControllerImpl) UpdatePlant(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	plant := p.plantService.UpdatePlant(c)
+	c.JSON(http.StatusOK, plant)
+	return
+}
+
+func (p *PlantControllerImpl) DeletePlant(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	p.plantService.DeletePlant(c)
+	c.JSON(http.StatusOK, pkg.BuildResponse(constant.Success, pkg.Null()))
+	return
+}
+
+func PlantControllerInit(plantService service.PlantService) *PlantControllerImpl {
+	return &PlantControllerImpl{
+		plantService: plantService,
+	}
+}
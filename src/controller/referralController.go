@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ReferralController interface {
+	GetMyReferralProgram(c *gin.Context)
+	GetReferralRewards(c *gin.Context)
+	SaveReferralReward(c *gin.Context)
+}
+
+type ReferralControllerImpl struct {
+	referralService service.ReferralService
+}
+
+func (r ReferralControllerImpl) GetMyReferralProgram(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	program := r.referralService.GetMyReferralProgram(c)
+	c.JSON(http.StatusOK, program)
+	return
+}
+
+func (r ReferralControllerImpl) GetReferralRewards(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	tiers := r.referralService.GetReferralRewards(c)
+	c.JSON(http.StatusOK, tiers)
+	return
+}
+
+func (r ReferralControllerImpl) SaveReferralReward(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	tier := r.referralService.SaveReferralReward(c)
+	c.JSON(http.StatusOK, tier)
+	return
+}
+
+func ReferralControllerInit(referralService service.ReferralService) *ReferralControllerImpl {
+	return &ReferralControllerImpl{
+		referralService: referralService,
+	}
+}
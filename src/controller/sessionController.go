@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type SessionController interface {
+	Refresh(c *gin.Context)
+	Logout(c *gin.Context)
+	LogoutEverywhere(c *gin.Context)
+}
+
+type SessionControllerImpl struct {
+	sessionService service.SessionService
+}
+
+func (s SessionControllerImpl) Refresh(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	tokens := s.sessionService.Refresh(c)
+	c.JSON(http.StatusOK, tokens)
+	return
+}
+
+func (s SessionControllerImpl) Logout(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	s.sessionService.Logout(c)
+	c.JSON(http.StatusOK, pkg.BuildResponse(constant.Success, pkg.Null()))
+	return
+}
+
+func (s SessionControllerImpl) LogoutEverywhere(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	s.sessionService.LogoutEverywhere(c)
+	c.JSON(http.StatusOK, pkg.BuildResponse(constant.Success, pkg.Null()))
+	return
+}
+
+func SessionControllerInit(sessionService service.SessionService) *SessionControllerImpl {
+	return &SessionControllerImpl{
+		sessionService: sessionService,
+	}
+}
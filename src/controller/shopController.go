@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ShopController interface {
+	GetItems(c *gin.Context)
+	Buy(c *gin.Context)
+	GetSellPrices(c *gin.Context)
+	Sell(c *gin.Context)
+	GetAllItems(c *gin.Context)
+	CreateItem(c *gin.Context)
+	UpdateItem(c *gin.Context)
+	SaveFarmLevel(c *gin.Context)
+}
+
+type ShopControllerImpl struct {
+	shopService service.ShopService
+}
+
+func (s *ShopControllerImpl) GetItems(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	items := s.shopService.GetItems(c)
+	c.JSON(http.StatusOK, items)
+	return
+}
+
+func (s *ShopControllerImpl) Buy(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	purchase := s.shopService.Buy(c)
+	c.JSON(http.StatusOK, purchase)
+	return
+}
+
+func (s *ShopControllerImpl) GetSellPrices(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	prices := s.shopService.GetSellPrices(c)
+	c.JSON(http.StatusOK, prices)
+	return
+}
+
+func (s *ShopControllerImpl) Sell(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	sale := s.shopService.Sell(c)
+	c.JSON(http.StatusOK, sale)
+	return
+}
+
+func (s *ShopControllerImpl) GetAllItems(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	items := s.shopService.GetAllItems(c)
+	c.JSON(http.StatusOK, items)
+	return
+}
+
+func (s *ShopControllerImpl) CreateItem(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	item := s.shopService.CreateItem(c)
+	c.JSON(http.StatusOK, item)
+	return
+}
+
+func (s *ShopControllerImpl) UpdateItem(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	item := s.shopService.UpdateItem(c)
+	c.JSON(http.StatusOK, item)
+	return
+}
+
+func (s *ShopControllerImpl) SaveFarmLevel(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	level := s.shopService.SaveFarmLevel(c)
+	c.JSON(http.StatusOK, level)
+	return
+}
+
+func ShopControllerInit(shopService service.ShopService) *ShopControllerImpl {
+	return &ShopControllerImpl{
+		shopService: shopService,
+	}
+}
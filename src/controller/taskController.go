@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"crazyfarmbackend/src/constant"
 	"crazyfarmbackend/src/pkg"
 	"crazyfarmbackend/src/service"
 	"github.com/gin-gonic/gin"
@@ -11,6 +12,14 @@ type TaskController interface {
 	GetAllTasks(c *gin.Context)
 	Check(c *gin.Context)
 	Claim(c *gin.Context)
+	GetTaskDefinitions(c *gin.Context)
+	GetTaskTypes(c *gin.Context)
+	CreateTask(c *gin.Context)
+	UpdateTask(c *gin.Context)
+	EnableTask(c *gin.Context)
+	DisableTask(c *gin.Context)
+	ReorderTasks(c *gin.Context)
+	GetTaskStats(c *gin.Context)
 }
 
 type TaskControllerImpl struct {
@@ -48,6 +57,62 @@ func (u TaskControllerImpl) GetAllTasks(c *gin.Context) {
 	return
 }
 
+func (u TaskControllerImpl) GetTaskDefinitions(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	tasks := u.taskService.GetTaskDefinitions(c)
+	c.JSON(http.StatusOK, tasks)
+	return
+}
+
+func (u TaskControllerImpl) GetTaskTypes(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	taskTypes := u.taskService.GetTaskTypes(c)
+	c.JSON(http.StatusOK, taskTypes)
+	return
+}
+
+func (u TaskControllerImpl) CreateTask(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	task := u.taskService.CreateTask(c)
+	c.JSON(http.StatusOK, task)
+	return
+}
+
+func (u TaskControllerImpl) UpdateTask(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	task := u.taskService.UpdateTask(c)
+	c.JSON(http.StatusOK, task)
+	return
+}
+
+func (u TaskControllerImpl) EnableTask(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	task := u.taskService.SetTaskEnabled(c, true)
+	c.JSON(http.StatusOK, task)
+	return
+}
+
+func (u TaskControllerImpl) DisableTask(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	task := u.taskService.SetTaskEnabled(c, false)
+	c.JSON(http.StatusOK, task)
+	return
+}
+
+func (u TaskControllerImpl) ReorderTasks(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	u.taskService.ReorderTasks(c)
+	c.JSON(http.StatusOK, pkg.BuildResponse(constant.Success, pkg.Null()))
+	return
+}
+
+func (u TaskControllerImpl) GetTaskStats(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	stats := u.taskService.GetTaskStats(c)
+	c.JSON(http.StatusOK, stats)
+	return
+}
+
 func TaskControllerInit(taskService service.TaskService) *TaskControllerImpl {
 	return &TaskControllerImpl{
 		taskService: taskService,
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type TradeController interface {
+	SendGift(c *gin.Context)
+	CreateOffer(c *gin.Context)
+	GetOffers(c *gin.Context)
+	AcceptOffer(c *gin.Context)
+	RejectOffer(c *gin.Context)
+	CancelOffer(c *gin.Context)
+}
+
+type TradeControllerImpl struct {
+	tradeService service.TradeService
+}
+
+func (t *TradeControllerImpl) SendGift(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	gift := t.tradeService.SendGift(c)
+	c.JSON(http.StatusOK, gift)
+	return
+}
+
+func (t *TradeControllerImpl) CreateOffer(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	offer := t.tradeService.CreateOffer(c)
+	c.JSON(http.StatusOK, offer)
+	return
+}
+
+func (t *TradeControllerImpl) GetOffers(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	offers := t.tradeService.GetOffers(c)
+	c.JSON(http.StatusOK, offers)
+	return
+}
+
+func (t *TradeControllerImpl) AcceptOffer(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	offer := t.tradeService.AcceptOffer(c)
+	c.JSON(http.StatusOK, offer)
+	return
+}
+
+func (t *TradeControllerImpl) RejectOffer(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	offer := t.tradeService.RejectOffer(c)
+	c.JSON(http.StatusOK, offer)
+	return
+}
+
+func (t *TradeControllerImpl) CancelOffer(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	offer := t.tradeService.CancelOffer(c)
+	c.JSON(http.StatusOK, offer)
+	return
+}
+
+func TradeControllerInit(tradeService service.TradeService) *TradeControllerImpl {
+	return &TradeControllerImpl{
+		tradeService: tradeService,
+	}
+}
@@ -12,6 +12,8 @@ type UserController interface {
 	GetMe(ctx *gin.Context)
 	GetMyUpgrades(c *gin.Context)
 	GetMyReferrals(c *gin.Context)
+	GetFarmLevels(c *gin.Context)
+	BuyFarmUpgrade(c *gin.Context)
 }
 
 type UserControllerImpl struct {
@@ -48,6 +50,19 @@ func (u UserControllerImpl) GetMyReferrals(c *gin.Context) {
 	return
 }
 
+func (u UserControllerImpl) GetFarmLevels(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	levels := u.userService.GetFarmLevels(c)
+	c.JSON(http.StatusOK, levels)
+	return
+}
+func (u UserControllerImpl) BuyFarmUpgrade(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	userResponse := u.userService.BuyFarmUpgrade(c)
+	c.JSON(http.StatusOK, userResponse)
+	return
+}
+
 func UserControllerInit(userService service.UserService) *UserControllerImpl {
 	return &UserControllerImpl{
 		userService: userService,
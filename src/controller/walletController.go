@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type WalletController interface {
+	GetWallet(c *gin.Context)
+}
+
+type WalletControllerImpl struct {
+	walletService service.WalletService
+}
+
+func (w *WalletControllerImpl) GetWallet(c *gin.Context) {
+	defer pkg.PanicHandler(c)
+	wallet := w.walletService.GetWallet(c)
+	c.JSON(http.StatusOK, wallet)
+	return
+}
+
+func WalletControllerInit(walletService service.WalletService) *WalletControllerImpl {
+	return &WalletControllerImpl{
+		walletService: walletService,
+	}
+}
@@ -0,0 +1,28 @@
+package constructor
+
+import (
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+)
+
+func ConstructTheftByModel(theft dao.Theft) dto.Theft {
+	return dto.Theft{
+		ID:       theft.ID,
+		VictimID: theft.VictimID,
+		FieldID:  theft.FieldID,
+		Plant:    theft.Plant,
+		Amount:   theft.Amount,
+	}
+}
+
+func ConstructNotificationByModel(notification dao.Notification) dto.Notification {
+	return dto.Notification{
+		ID:        notification.ID,
+		Type:      notification.Type,
+		Actor:     ConstructUserReferralFromModel(notification.Actor),
+		Plant:     notification.Plant,
+		Amount:    notification.Amount,
+		Read:      notification.Read,
+		CreatedAt: notification.CreatedAt.Unix(),
+	}
+}
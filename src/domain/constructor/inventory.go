@@ -10,3 +10,26 @@ func ConstructInventoryItemByModel(item dao.InventoryItem) dto.InventoryItem {
 		Plant:    item.Plant,
 		Quantity: item.Quantity}
 }
+
+func ConstructItemStacksByModel(stacks []dao.ItemStack) []dto.ItemStack {
+	items := make([]dto.ItemStack, len(stacks))
+	for i, stack := range stacks {
+		items[i] = dto.ItemStack{
+			Plant:  stack.Plant,
+			Amount: stack.Amount,
+		}
+	}
+	return items
+}
+
+func ConstructLedgerEntryByModel(entry dao.InventoryLedger) dto.LedgerEntry {
+	return dto.LedgerEntry{
+		ID:           entry.ID,
+		Plant:        entry.Plant,
+		Amount:       entry.Amount,
+		BalanceAfter: entry.BalanceAfter,
+		Reason:       entry.Reason,
+		RefID:        entry.RefID,
+		CreatedAt:    entry.CreatedAt.Unix(),
+	}
+}
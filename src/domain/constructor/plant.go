@@ -0,0 +1,100 @@
+package constructor
+
+import (
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+	"time"
+)
+
+func ConstructPlantByModel(plant dao.PlantInfo) dto.Plant {
+	return dto.Plant{
+		Name:          plant.Name,
+		GrowTime:      plant.GrowTime,
+		Reward:        plant.Reward,
+		SellPrice:     plant.SellPrice,
+		Icon:          plant.Icon,
+		Enabled:       plant.Enabled,
+		AvailableFrom: unixOrNil(plant.AvailableFrom),
+		AvailableTo:   unixOrNil(plant.AvailableTo),
+		SortOrder:     plant.SortOrder,
+	}
+}
+
+func ConstructPlantFromRequest(request dto.PlantRequest) dao.PlantInfo {
+	plant := dao.PlantInfo{
+		Name:          request.Name,
+		Enabled:       request.Enabled == nil || *request.Enabled,
+		AvailableFrom: availabilityOrNil(request.AvailableFrom),
+		AvailableTo:   availabilityOrNil(request.AvailableTo),
+	}
+	if request.GrowTime != nil {
+		plant.GrowTime = *request.GrowTime
+	}
+	if request.Reward != nil {
+		plant.Reward = *request.Reward
+	}
+	if request.SellPrice != nil {
+		plant.SellPrice = *request.SellPrice
+	}
+	if request.Icon != nil {
+		plant.Icon = stringOrNil(*request.Icon)
+	}
+	if request.SortOrder != nil {
+		plant.SortOrder = *request.SortOrder
+	}
+	return plant
+}
+
+// ConstructPlantUpdatesFromRequest maps the fields the request sets to the columns they update
+func ConstructPlantUpdatesFromRequest(request dto.PlantRequest) map[string]interface{} {
+	updates := map[string]interface{}{}
+	if request.GrowTime != nil {
+		updates["grow_time"] = *request.GrowTime
+	}
+	if request.Reward != nil {
+		updates["reward"] = *request.Reward
+	}
+	if request.SellPrice != nil {
+		updates["sell_price"] = *request.SellPrice
+	}
+	if request.Icon != nil {
+		updates["icon"] = stringOrNil(*request.Icon)
+	}
+	if request.Enabled != nil {
+		updates["enabled"] = *request.Enabled
+	}
+	if request.AvailableFrom != nil {
+		updates["available_from"] = availabilityOrNil(request.AvailableFrom)
+	}
+	if request.AvailableTo != nil {
+		updates["available_to"] = availabilityOrNil(request.AvailableTo)
+	}
+	if request.SortOrder != nil {
+		updates["sort_order"] = *request.SortOrder
+	}
+	return updates
+}
+
+// availabilityOrNil reads a bound of the availability window, 0 leaves that side open
+func availabilityOrNil(unix *int64) *time.Time {
+	if unix == nil || *unix == 0 {
+		return nil
+	}
+	return timeOrNil(unix)
+}
+
+func unixOrNil(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+	unix := t.Unix()
+	return &unix
+}
+
+func timeOrNil(unix *int64) *time.Time {
+	if unix == nil {
+		return nil
+	}
+	t := time.Unix(*unix, 0).UTC()
+	return &t
+}
@@ -0,0 +1,40 @@
+package constructor
+
+import (
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+)
+
+func ConstructReferralRewardByModel(reward dao.ReferralReward) dto.ReferralReward {
+	return dto.ReferralReward{
+		ID:             reward.ID,
+		Milestone:      reward.Milestone,
+		Threshold:      reward.Threshold,
+		Rewards:        ConstructRewardLinesByModel(reward.Rewards),
+		PremiumRewards: ConstructRewardLinesByModel(reward.PremiumRewards),
+		Enabled:        reward.Enabled,
+		SortOrder:      reward.SortOrder,
+	}
+}
+
+func ConstructReferralRewardFromRequest(request dto.ReferralRewardRequest) dao.ReferralReward {
+	return dao.ReferralReward{
+		ID:             request.ID,
+		Milestone:      request.Milestone,
+		Threshold:      request.Threshold,
+		Rewards:        ConstructRewardLinesFromRequest(request.Rewards),
+		PremiumRewards: ConstructRewardLinesFromRequest(request.PremiumRewards),
+		Enabled:        request.Enabled,
+		SortOrder:      request.SortOrder,
+	}
+}
+
+func ConstructReferralPayoutByModel(payout dao.ReferralPayout) dto.ReferralPayout {
+	return dto.ReferralPayout{
+		Referral:  ConstructUserReferralFromModel(payout.Referral),
+		RewardID:  payout.RewardID,
+		Premium:   payout.Premium,
+		Rewards:   ConstructRewardLinesByModel(payout.Rewards),
+		CreatedAt: payout.CreatedAt.Unix(),
+	}
+}
@@ -0,0 +1,25 @@
+package constructor
+
+import (
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+)
+
+func ConstructRewardLinesByModel(rewards []dao.RewardLine) []dto.RewardLine {
+	lines := make([]dto.RewardLine, len(rewards))
+	for i, reward := range rewards {
+		lines[i] = dto.RewardLine{Kind: reward.Kind, Plant: reward.Plant, Amount: reward.Amount}
+	}
+	return lines
+}
+
+func ConstructRewardLinesFromRequest(rewards []dto.RewardLine) []dao.RewardLine {
+	if len(rewards) == 0 {
+		return nil
+	}
+	lines := make([]dao.RewardLine, len(rewards))
+	for i, reward := range rewards {
+		lines[i] = dao.RewardLine{Kind: reward.Kind, Plant: reward.Plant, Amount: reward.Amount}
+	}
+	return lines
+}
@@ -0,0 +1,66 @@
+package constructor
+
+import (
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+)
+
+func ConstructShopItemByModel(item dao.ShopItem, purchased int) dto.ShopItem {
+	shopItem := dto.ShopItem{
+		ID:            item.ID,
+		Name:          item.Name,
+		Icon:          item.Icon,
+		Contents:      ConstructItemStacksByModel(item.Contents),
+		PriceCoins:    item.PriceCoins,
+		PriceItems:    ConstructItemStacksByModel(item.PriceItems),
+		PurchaseLimit: item.PurchaseLimit,
+		Stock:         item.Stock,
+	}
+	if item.PurchaseLimit > 0 {
+		remaining := max(item.PurchaseLimit-purchased, 0)
+		shopItem.RemainingPurchases = &remaining
+	}
+	return shopItem
+}
+
+func ConstructShopItemDefinitionByModel(item dao.ShopItem) dto.ShopItemDefinition {
+	return dto.ShopItemDefinition{
+		ID:            item.ID,
+		Name:          item.Name,
+		Icon:          item.Icon,
+		Contents:      ConstructItemStacksByModel(item.Contents),
+		PriceCoins:    item.PriceCoins,
+		PriceItems:    ConstructItemStacksByModel(item.PriceItems),
+		PurchaseLimit: item.PurchaseLimit,
+		Stock:         item.Stock,
+		Enabled:       item.Enabled,
+		SortOrder:     item.SortOrder,
+	}
+}
+
+func ConstructShopPurchaseByModel(purchase dao.ShopPurchase, item dao.ShopItem) dto.ShopPurchase {
+	received := make([]dto.ItemStack, len(item.Contents))
+	for i, stack := range item.Contents {
+		received[i] = dto.ItemStack{
+			Plant:  stack.Plant,
+			Amount: stack.Amount * purchase.Quantity,
+		}
+	}
+	return dto.ShopPurchase{
+		ID:         purchase.ID,
+		ShopItemID: purchase.ShopItemID,
+		Quantity:   purchase.Quantity,
+		PriceCoins: purchase.PriceCoins,
+		Received:   received,
+	}
+}
+
+func ConstructSaleByModel(sale dao.Sale) dto.Sale {
+	return dto.Sale{
+		ID:        sale.ID,
+		Plant:     sale.Plant,
+		Quantity:  sale.Quantity,
+		UnitPrice: sale.UnitPrice,
+		Coins:     sale.Coins,
+	}
+}
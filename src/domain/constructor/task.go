@@ -4,18 +4,138 @@ import (
 	"crazyfarmbackend/src/constant"
 	"crazyfarmbackend/src/domain/dao"
 	"crazyfarmbackend/src/domain/dto"
+	"crazyfarmbackend/src/pkg"
+	"time"
 )
 
-func ConstructTaskByModel(item dao.Task, status constant.TaskCompleteStatus) dto.Task {
-	return dto.Task{
+// ConstructTaskByModel builds the player view of a task, text is the result of item.Localize
+func ConstructTaskByModel(item dao.Task, text dao.TaskText, status constant.TaskCompleteStatus, progress *int) dto.Task {
+	task := dto.Task{
 		ID:            item.ID,
-		Name:          item.Name,
+		Name:          text.Name,
+		Description:   stringOrNil(text.Description),
+		ButtonLabel:   stringOrNil(text.ButtonLabel),
 		Icon:          item.Icon,
 		Reward:        item.Reward,
 		RewardAmount:  item.RewardAmount,
+		Rewards:       ConstructRewardLinesByModel(item.RewardBundle()),
 		NeedDoneTimes: item.NeedDoneTimes,
 		Type:          item.Type,
+		Category:      item.Category,
 		Data:          item.Data,
 		Status:        status,
+		Progress:      progress,
+		Recurrence:    item.Recurrence,
+		EndsAt:        unixOrNil(item.EndsAt),
+	}
+	if cycle := item.Cycle(time.Now()); cycle.Key != "" {
+		resetsAt := cycle.End.Unix()
+		task.ResetsAt = &resetsAt
+	}
+	return task
+}
+
+func ConstructTaskDefinitionByModel(item dao.Task) dto.TaskDefinition {
+	return dto.TaskDefinition{
+		ID:              item.ID,
+		Name:            item.Name,
+		Description:     item.Description,
+		ButtonLabel:     item.ButtonLabel,
+		Translations:    constructTaskTextsByModel(item.Translations),
+		Icon:            item.Icon,
+		Reward:          item.Reward,
+		RewardAmount:    item.RewardAmount,
+		Rewards:         ConstructRewardLinesByModel(item.Rewards),
+		NeedDoneTimes:   item.NeedDoneTimes,
+		Type:            item.Type,
+		Data:            item.Data,
+		Recurrence:      item.Recurrence,
+		RecurrenceHours: item.RecurrenceHours,
+		StartsAt:        unixOrNil(item.StartsAt),
+		EndsAt:          unixOrNil(item.EndsAt),
+		Prerequisites:   item.Prerequisites,
+		Audience: dto.TaskAudience{
+			LanguageCodes: item.Audience.LanguageCodes,
+			MinFarmLvl:    item.Audience.MinFarmLvl,
+			MaxFarmLvl:    item.Audience.MaxFarmLvl,
+			Referred:      item.Audience.Referred,
+		},
+		Category:  item.Category,
+		SortOrder: item.SortOrder,
+		Enabled:   item.Enabled,
+	}
+}
+
+func ConstructTaskFromRequest(request dto.TaskRequest) dao.Task {
+	data := request.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return dao.Task{
+		Name:            request.Name,
+		Description:     request.Description,
+		ButtonLabel:     request.ButtonLabel,
+		Translations:    constructTaskTextsFromRequest(request.Translations),
+		Icon:            request.Icon,
+		Reward:          request.Reward,
+		RewardAmount:    request.RewardAmount,
+		Rewards:         ConstructRewardLinesFromRequest(request.Rewards),
+		NeedDoneTimes:   request.NeedDoneTimes,
+		Type:            request.Type,
+		Data:            data,
+		Recurrence:      request.Recurrence,
+		RecurrenceHours: request.RecurrenceHours,
+		StartsAt:        timeOrNil(request.StartsAt),
+		EndsAt:          timeOrNil(request.EndsAt),
+		Prerequisites:   request.Prerequisites,
+		Audience: dao.TaskAudience{
+			LanguageCodes: request.Audience.LanguageCodes,
+			MinFarmLvl:    request.Audience.MinFarmLvl,
+			MaxFarmLvl:    request.Audience.MaxFarmLvl,
+			Referred:      request.Audience.Referred,
+		},
+		Category:  request.Category,
+		SortOrder: request.SortOrder,
+		Enabled:   request.Enabled == nil || *request.Enabled,
+	}
+}
+
+func ConstructTaskStats(item dao.Task, stats dao.TaskStats) dto.TaskStats {
+	return dto.TaskStats{
+		TaskID:        item.ID,
+		Name:          item.Name,
+		Enabled:       item.Enabled,
+		DoneUsers:     stats.DoneUsers,
+		FinishedUsers: stats.FinishedUsers,
+		Claims:        stats.Claims,
+		RewardsPaid:   stats.RewardsPaid,
+		CoinsPaid:     stats.CoinsPaid,
+	}
+}
+
+func constructTaskTextsByModel(texts map[string]dao.TaskText) map[string]dto.TaskText {
+	translations := make(map[string]dto.TaskText, len(texts))
+	for language, text := range texts {
+		translations[language] = dto.TaskText{Name: text.Name, Description: text.Description, ButtonLabel: text.ButtonLabel}
+	}
+	return translations
+}
+
+// constructTaskTextsFromRequest normalizes the language keys, "en-US" is stored as "en"
+func constructTaskTextsFromRequest(texts map[string]dto.TaskText) map[string]dao.TaskText {
+	if len(texts) == 0 {
+		return nil
+	}
+	translations := make(map[string]dao.TaskText, len(texts))
+	for language, text := range texts {
+		translations[pkg.PrimaryLanguage(language)] = dao.TaskText{Name: text.Name, Description: text.Description, ButtonLabel: text.ButtonLabel}
+	}
+	return translations
+}
+
+func stringOrNil(value string) *string {
+	if value == "" {
+		return nil
 	}
+	return &value
 }
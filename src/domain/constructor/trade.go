@@ -0,0 +1,27 @@
+package constructor
+
+import (
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+)
+
+func ConstructGiftByModel(gift dao.Gift) dto.Gift {
+	return dto.Gift{
+		ID:       gift.ID,
+		ToUserID: gift.ToUserID,
+		Plant:    gift.Plant,
+		Quantity: gift.Quantity,
+	}
+}
+
+func ConstructTradeOfferByModel(offer dao.TradeOffer) dto.TradeOffer {
+	return dto.TradeOffer{
+		ID:         offer.ID,
+		FromUserID: offer.FromUserID,
+		ToUserID:   offer.ToUserID,
+		Give:       ConstructItemStacksByModel(offer.Give),
+		Want:       ConstructItemStacksByModel(offer.Want),
+		Status:     offer.Status,
+		CreatedAt:  offer.CreatedAt.Unix(),
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crazyfarmbackend/src/domain/dao"
 	"crazyfarmbackend/src/domain/dto"
 	"crazyfarmbackend/src/pkg"
+	"time"
 )
 
 func ConstructUserFromModel(user dao.User) dto.User {
@@ -18,18 +19,41 @@ func ConstructUserFromModel(user dao.User) dto.User {
 	}
 }
 
-func ConstructUserUpgradeFromModel(userUpgrade dao.UserUpgrade) dto.UserUpgrade {
-	return dto.UserUpgrade{
+func ConstructUserUpgradeFromModel(userUpgrade dao.UserUpgrade, level dao.FarmLevel, next *dao.FarmLevel) dto.UserUpgrade {
+	userUpgradeDTO := dto.UserUpgrade{
 		FarmLvl:   userUpgrade.FarmLvl,
-		MaxFields: constant.GetLvlMaxFields(userUpgrade.FarmLvl),
+		FarmXp:    userUpgrade.FarmXp,
+		MaxFields: level.MaxFields,
+	}
+	if next != nil {
+		nextDTO := ConstructFarmLevelFromModel(*next)
+		userUpgradeDTO.Next = &nextDTO
+	}
+	return userUpgradeDTO
+}
+
+func ConstructFarmLevelFromModel(level dao.FarmLevel) dto.FarmLevel {
+	return dto.FarmLevel{
+		Lvl:       level.Lvl,
+		MaxFields: level.MaxFields,
+		Cost:      ConstructItemStacksByModel(level.Cost),
+		CoinCost:  level.CoinCost,
 	}
 }
 
-func ConstructUserFieldFromModel(userField dao.UserField) dto.UserField {
+func ConstructUserFieldFromModel(userField dao.UserField, plantInfo dao.PlantInfo) dto.UserField {
+	readyAt := userField.CreatedAt.Add(plantInfo.GrowDuration())
+	remainingTime := int64(time.Until(readyAt).Seconds())
+	if remainingTime < 0 {
+		remainingTime = 0
+	}
 	return dto.UserField{
-		FieldID:   userField.FieldID,
-		Plant:     userField.Plant,
-		PlantTime: userField.CreatedAt.Unix(),
+		FieldID:       userField.FieldID,
+		Plant:         userField.Plant,
+		PlantTime:     userField.CreatedAt.Unix(),
+		ReadyAt:       readyAt.Unix(),
+		RemainingTime: remainingTime,
+		Stolen:        userField.Stolen,
 	}
 }
 
@@ -42,3 +66,28 @@ func ConstructUserReferralFromModel(user dao.User) dto.UserReferral {
 		Icon:      user.Icon,
 	}
 }
+
+func ConstructAuthMethodFromModel(userAuth dao.UserAuth, current bool) dto.AuthMethod {
+	return dto.AuthMethod{
+		ID:       userAuth.ID,
+		Method:   userAuth.AuthMethod,
+		Identity: userAuth.AuthData,
+		LinkedAt: userAuth.CreatedAt.Unix(),
+		Current:  current,
+	}
+}
+
+func ConstructRolePermissions(role constant.Role) dto.RolePermissions {
+	permissions := constant.RolePermissions[role]
+	if permissions == nil {
+		permissions = []constant.Permission{}
+	}
+	return dto.RolePermissions{Role: role, Permissions: permissions}
+}
+
+func ConstructStaffUserFromModel(user dao.User) dto.StaffUser {
+	return dto.StaffUser{
+		User: ConstructUserFromModel(user),
+		Role: user.Role,
+	}
+}
@@ -0,0 +1,27 @@
+package dao
+
+import "time"
+
+// EmailCode is the pending sign in code of an email address, only its hash is stored
+type EmailCode struct {
+	Email     string    `gorm:"primary_key;type:text"`
+	CodeHash  string    `gorm:"type:text;not null"`
+	Attempts  int       `gorm:"not null;default:0"` // Wrong guesses since the first unexpired code, resends keep them
+	ExpiresAt time.Time `gorm:"not null"`
+	SentAt    time.Time `gorm:"not null"`
+}
+
+// EmailAuthEvent records a code sent to or a wrong code tried for an address, the hourly and daily
+// caps count them
+type EmailAuthEvent struct {
+	ID        uint64    `gorm:"primaryKey"`
+	Email     string    `gorm:"type:text;not null;index:idx_email_auth_event,priority:1"`
+	Kind      string    `gorm:"type:text;not null;index:idx_email_auth_event,priority:2"`
+	CreatedAt time.Time `gorm:"not null;index:idx_email_auth_event,priority:3"`
+}
+
+// TonProofNonce is a ton_proof payload that was handed out and not signed in with yet
+type TonProofNonce struct {
+	Payload   string    `gorm:"primaryKey;type:text"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
@@ -0,0 +1,27 @@
+package dao
+
+import (
+	"crazyfarmbackend/src/constant"
+	"github.com/google/uuid"
+	"time"
+)
+
+// UserCounter accumulates gameplay events for progress tasks, Key narrows a counter to one plant
+type UserCounter struct {
+	UserID    uuid.UUID        `gorm:"primaryKey;type:uuid"`
+	User      User             `gorm:"foreignKey:UserID;column:user_id;not null;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Counter   constant.Counter `gorm:"primaryKey;type:text"`
+	Key       string           `gorm:"primaryKey;type:text"`
+	Value     int64            `gorm:"not null;default:0"`
+	UpdatedAt time.Time
+}
+
+// UserCounterBucket holds the events of one counter in one UTC hour, recurring tasks sum
+// the buckets of their current cycle
+type UserCounterBucket struct {
+	UserID  uuid.UUID        `gorm:"primaryKey;type:uuid"`
+	Counter constant.Counter `gorm:"primaryKey;type:text"`
+	Key     string           `gorm:"primaryKey;type:text"`
+	Hour    time.Time        `gorm:"primaryKey"`
+	Value   int64            `gorm:"not null;default:0"`
+}
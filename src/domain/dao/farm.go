@@ -0,0 +1,30 @@
+package dao
+
+import (
+	"crazyfarmbackend/src/constant"
+	"github.com/google/uuid"
+)
+
+// Theft is one crop share taken from a friend's field, it drives the steal cooldowns
+type Theft struct {
+	ID       uuid.UUID      `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	ThiefID  uuid.UUID      `gorm:"type:uuid;not null;index"`
+	VictimID uuid.UUID      `gorm:"type:uuid;not null;index"`
+	FieldID  int            `gorm:"not null"`
+	Plant    constant.Plant `gorm:"not null"`
+	Amount   int            `gorm:"not null"`
+	BaseModel
+}
+
+// Notification tells a user what another player did to them
+type Notification struct {
+	ID      uuid.UUID                 `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	UserID  uuid.UUID                 `gorm:"type:uuid;not null;index"`
+	Type    constant.NotificationType `gorm:"type:text;not null"`
+	ActorID uuid.UUID                 `gorm:"type:uuid;not null"`
+	Actor   User                      `gorm:"foreignKey:ActorID;column:actor_id;not null;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Plant   constant.Plant            `gorm:"type:text;default:null"`
+	Amount  int                       `gorm:"not null;default:0"`
+	Read    bool                      `gorm:"not null;default:false"`
+	BaseModel
+}
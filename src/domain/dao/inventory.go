@@ -3,13 +3,39 @@ package dao
 import (
 	"crazyfarmbackend/src/constant"
 	"github.com/google/uuid"
+	"time"
 )
 
 type InventoryItem struct {
 	ID       uuid.UUID      `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
-	UserID   uuid.UUID      `gorm:"not null"`
+	UserID   uuid.UUID      `gorm:"not null;uniqueIndex:idx_inventory_user_plant"`
 	User     User           `gorm:"foreignKey:UserID;column:user_id;not null;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
-	Plant    constant.Plant `gorm:"not null"`
+	Plant    constant.Plant `gorm:"not null;uniqueIndex:idx_inventory_user_plant"`
 	Quantity int            `gorm:"default:0"`
 	BaseModel
 }
+
+type ItemStack struct {
+	Plant  constant.Plant `json:"plant"`
+	Amount int            `json:"amount"`
+}
+
+// InventoryLedger is append-only, every quantity change of InventoryItem writes one row
+type InventoryLedger struct {
+	ID           uint64                `gorm:"primaryKey;autoIncrement;index:idx_ledger_user,priority:2"`
+	UserID       uuid.UUID             `gorm:"type:uuid;not null;index:idx_ledger_user,priority:1"`
+	Plant        constant.Plant        `gorm:"not null"`
+	Amount       int                   `gorm:"not null"`
+	BalanceAfter int                   `gorm:"not null"`
+	Reason       constant.LedgerReason `gorm:"type:text;not null"`
+	RefID        string                `gorm:"type:text;default:null"`
+	CreatedAt    time.Time             `gorm:"autoCreateTime"`
+}
+
+// LedgerMismatch is a balance whose ledger sum differs from the inventory quantity
+type LedgerMismatch struct {
+	UserID    uuid.UUID
+	Plant     constant.Plant
+	Ledger    int
+	Inventory int
+}
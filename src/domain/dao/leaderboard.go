@@ -0,0 +1,18 @@
+package dao
+
+import (
+	"crazyfarmbackend/src/constant"
+	"github.com/google/uuid"
+	"time"
+)
+
+// LeaderboardScore is a precomputed standing, harvests and referrals add to it as they happen
+// and inventory worth is rebuilt periodically, so ranking never scans the source tables
+type LeaderboardScore struct {
+	Board     constant.LeaderboardBoard `gorm:"primaryKey;type:text;index:idx_leaderboard_rank,priority:1"`
+	Period    string                    `gorm:"primaryKey;type:text;index:idx_leaderboard_rank,priority:2"`
+	UserID    uuid.UUID                 `gorm:"primaryKey;type:uuid"`
+	User      User                      `gorm:"foreignKey:UserID;column:user_id;not null;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Score     int64                     `gorm:"not null;default:0;index:idx_leaderboard_rank,priority:3,sort:desc"`
+	UpdatedAt time.Time
+}
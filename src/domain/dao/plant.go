@@ -0,0 +1,37 @@
+package dao
+
+import (
+	"crazyfarmbackend/src/constant"
+	"time"
+)
+
+type PlantInfo struct {
+	Name          constant.Plant `gorm:"primary_key;type:text"`
+	GrowTime      int64          `gorm:"not null"` // Seconds
+	Reward        int            `gorm:"not null;default:1"`
+	SellPrice     int64          `gorm:"not null;default:0"`
+	Icon          *string        `gorm:"type:text;default:null"`
+	Enabled       bool           `gorm:"not null;default:true"`
+	AvailableFrom *time.Time     `gorm:"default:null"`
+	AvailableTo   *time.Time     `gorm:"default:null"`
+	SortOrder     int            `gorm:"not null;default:0"`
+	BaseModel
+}
+
+func (p PlantInfo) GrowDuration() time.Duration {
+	return time.Duration(p.GrowTime) * time.Second
+}
+
+// IsAvailable reports whether the plant is enabled and inside its availability window
+func (p PlantInfo) IsAvailable(now time.Time) bool {
+	if !p.Enabled {
+		return false
+	}
+	if p.AvailableFrom != nil && now.Before(*p.AvailableFrom) {
+		return false
+	}
+	if p.AvailableTo != nil && now.After(*p.AvailableTo) {
+		return false
+	}
+	return true
+}
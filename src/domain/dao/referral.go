@@ -0,0 +1,38 @@
+package dao
+
+import (
+	"crazyfarmbackend/src/constant"
+	"github.com/google/uuid"
+)
+
+// ReferralReward is one tier of the referral program, the referrer is paid once per referral
+// when the referral reaches Milestone
+type ReferralReward struct {
+	ID             string                     `gorm:"primaryKey;type:text"`
+	Milestone      constant.ReferralMilestone `gorm:"type:text;not null"`
+	Threshold      int                        `gorm:"not null;default:0"` // Crops harvested or farm level, unused for SIGNUP
+	Rewards        []RewardLine               `gorm:"serializer:json"`
+	PremiumRewards []RewardLine               `gorm:"serializer:json"` // Paid instead of Rewards for premium referrals when set
+	Enabled        bool                       `gorm:"not null;default:true"`
+	SortOrder      int                        `gorm:"not null;default:0"`
+}
+
+// RewardsFor returns the bundle paid for a referral, premium referrals get the premium bundle when there is one
+func (r ReferralReward) RewardsFor(premium bool) []RewardLine {
+	if premium && len(r.PremiumRewards) > 0 {
+		return r.PremiumRewards
+	}
+	return r.Rewards
+}
+
+// ReferralPayout records a paid tier, the unique index makes every payout happen once
+type ReferralPayout struct {
+	ID         uuid.UUID    `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	ReferrerID uuid.UUID    `gorm:"type:uuid;not null;index"`
+	ReferralID uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_referral_payout"`
+	Referral   User         `gorm:"foreignKey:ReferralID;column:referral_id;not null;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	RewardID   string       `gorm:"type:text;not null;uniqueIndex:idx_referral_payout"`
+	Premium    bool         `gorm:"not null;default:false"`
+	Rewards    []RewardLine `gorm:"serializer:json"`
+	BaseModel
+}
@@ -0,0 +1,10 @@
+package dao
+
+import "crazyfarmbackend/src/constant"
+
+// RewardLine is one entry of a reward bundle, Plant is only set for REWARD_PLANT
+type RewardLine struct {
+	Kind   constant.RewardKind `json:"kind"`
+	Plant  constant.Plant      `json:"plant,omitempty"`
+	Amount int64               `json:"amount"`
+}
@@ -0,0 +1,26 @@
+package dao
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Session is one sign in of a user, access tokens carry its id and die with it
+type Session struct {
+	ID           uuid.UUID  `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	UserID       uuid.UUID  `gorm:"not null;index"`
+	UserAuthID   uuid.UUID  `gorm:"not null"`
+	UserAuth     UserAuth   `gorm:"foreignKey:UserAuthID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	RefreshHash  string     `gorm:"type:text;not null;uniqueIndex"`
+	PreviousHash *string    `gorm:"type:text;index"` // Hash rotated away last, presenting it again means the token leaked
+	UserAgent    string     `gorm:"type:text;not null;default:''"`
+	IP           string     `gorm:"type:text;not null;default:''"`
+	ExpiresAt    time.Time  `gorm:"not null"`
+	LastUsedAt   time.Time  `gorm:"not null"`
+	RevokedAt    *time.Time `gorm:"default:null"`
+	BaseModel
+}
+
+func (s Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
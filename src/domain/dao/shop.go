@@ -0,0 +1,44 @@
+package dao
+
+import (
+	"crazyfarmbackend/src/constant"
+	"github.com/google/uuid"
+)
+
+// ShopItem is a purchasable catalog entry, rows are edited in the database so prices and stock change without a redeploy
+type ShopItem struct {
+	ID            uuid.UUID   `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	Name          string      `gorm:"type:text;not null"`
+	Icon          *string     `gorm:"type:text;default:null"`
+	Contents      []ItemStack `gorm:"serializer:json"`
+	PriceCoins    int64       `gorm:"not null;default:0"`
+	PriceItems    []ItemStack `gorm:"serializer:json"`
+	PurchaseLimit int         `gorm:"not null;default:0"` // Per user, 0 means unlimited
+	Stock         *int        `gorm:"default:null"`       // Global, null means unlimited
+	Enabled       bool        `gorm:"not null;default:true"`
+	SortOrder     int         `gorm:"not null;default:0"`
+	BaseModel
+}
+
+type ShopPurchase struct {
+	ID         uuid.UUID `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	UserID     uuid.UUID `gorm:"not null;index:idx_shop_purchase_user_item"`
+	User       User      `gorm:"foreignKey:UserID;column:user_id;not null;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	ShopItemID uuid.UUID `gorm:"not null;index:idx_shop_purchase_user_item"`
+	ShopItem   ShopItem  `gorm:"foreignKey:ShopItemID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Quantity   int       `gorm:"not null"`
+	PriceCoins int64     `gorm:"not null;default:0"`
+	BaseModel
+}
+
+// Sale records produce sold back to the game, the coin sink and faucet data for economy dashboards
+type Sale struct {
+	ID        uuid.UUID      `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	UserID    uuid.UUID      `gorm:"not null;index"`
+	User      User           `gorm:"foreignKey:UserID;column:user_id;not null;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Plant     constant.Plant `gorm:"not null"`
+	Quantity  int            `gorm:"not null"`
+	UnitPrice int64          `gorm:"not null"`
+	Coins     int64          `gorm:"not null"`
+	BaseModel
+}
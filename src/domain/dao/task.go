@@ -2,27 +2,154 @@ package dao
 
 import (
 	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/pkg"
 	"github.com/google/uuid"
+	"time"
 )
 
 type Task struct {
-	ID            uuid.UUID              `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
-	Name          string                 `gorm:"type:text;default:null"`
-	Icon          *string                `gorm:"type:text;default:null"`
-	Reward        constant.Plant         `gorm:"not null"`
-	RewardAmount  int                    `gorm:"type:int;default:0"`
-	NeedDoneTimes int                    `gorm:"type:int;default:0"`
-	Type          constant.Task          `gorm:"type:text;default:null"`
-	Data          map[string]interface{} `gorm:"serializer:json"`
+	ID              uuid.UUID               `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	Name            string                  `gorm:"type:text;default:null"`
+	Description     *string                 `gorm:"type:text;default:null"`
+	ButtonLabel     *string                 `gorm:"type:text;default:null"`
+	Translations    map[string]TaskText     `gorm:"serializer:json"` // Keyed by primary language subtag, e.g. "ru"
+	Icon            *string                 `gorm:"type:text;default:null"`
+	Reward          constant.Plant          `gorm:"not null"` // Single plant reward of tasks without Rewards
+	RewardAmount    int                     `gorm:"type:int;default:0"`
+	Rewards         []RewardLine            `gorm:"serializer:json"`
+	NeedDoneTimes   int                     `gorm:"type:int;default:0"`
+	Type            constant.Task           `gorm:"type:text;default:null"`
+	Data            map[string]interface{}  `gorm:"serializer:json"`
+	Recurrence      constant.TaskRecurrence `gorm:"type:text;not null;default:''"`
+	RecurrenceHours int                     `gorm:"type:int;not null;default:0"` // Cycle length of HOURS recurrence
+	StartsAt        *time.Time              `gorm:"default:null"`
+	EndsAt          *time.Time              `gorm:"default:null"`
+	Prerequisites   []uuid.UUID             `gorm:"serializer:json"` // Tasks the user must have claimed first
+	Audience        TaskAudience            `gorm:"serializer:json"`
+	Category        string                  `gorm:"type:text;not null;default:''"` // Tasks are grouped by category in the task list
+	SortOrder       int                     `gorm:"type:int;not null;default:0"`
+	Enabled         bool                    `gorm:"not null;default:true"` // Disabled tasks are hidden from players but keep their history
 	BaseModel
 }
 
+// TaskStats summarizes the completions of one task across all users and cycles
+type TaskStats struct {
+	TaskID        uuid.UUID
+	DoneUsers     int64 // Users with a completed but unclaimed cycle
+	FinishedUsers int64 // Users who claimed at least one cycle
+	Claims        int64
+	RewardsPaid   int64 // Plants
+	CoinsPaid     int64
+}
+
+// TaskText holds the player facing texts of a task in one language, empty fields fall back to the task's own
+type TaskText struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	ButtonLabel string `json:"button_label,omitempty"`
+}
+
+// Localize resolves the texts in the first of languages the task is translated to. Fields the translation
+// leaves empty, and all fields when no language matches, come from the untranslated columns
+func (t Task) Localize(languages []string) TaskText {
+	text := TaskText{Name: t.Name}
+	if t.Description != nil {
+		text.Description = *t.Description
+	}
+	if t.ButtonLabel != nil {
+		text.ButtonLabel = *t.ButtonLabel
+	}
+	for _, language := range languages {
+		translation, ok := t.Translations[language]
+		if !ok {
+			continue
+		}
+		if translation.Name != "" {
+			text.Name = translation.Name
+		}
+		if translation.Description != "" {
+			text.Description = translation.Description
+		}
+		if translation.ButtonLabel != "" {
+			text.ButtonLabel = translation.ButtonLabel
+		}
+		break
+	}
+	return text
+}
+
+// RewardBundle returns the lines paid out on claim, tasks from before bundles pay their single plant reward
+func (t Task) RewardBundle() []RewardLine {
+	if len(t.Rewards) > 0 {
+		return t.Rewards
+	}
+	if t.Reward == "" || t.RewardAmount <= 0 {
+		return nil
+	}
+	return []RewardLine{{Kind: constant.REWARD_PLANT, Plant: t.Reward, Amount: int64(t.RewardAmount)}}
+}
+
+// TaskAudience limits who sees a task, zero values do not restrict
+type TaskAudience struct {
+	LanguageCodes []string `json:"language_codes,omitempty"` // Matched on the primary subtag, "en" covers "en-US"
+	MinFarmLvl    int      `json:"min_farm_lvl,omitempty"`
+	MaxFarmLvl    int      `json:"max_farm_lvl,omitempty"`
+	Referred      *bool    `json:"referred,omitempty"` // Whether the user joined through a referral link
+}
+
+// IsActive reports whether now is inside the task's scheduling window
+func (t Task) IsActive(now time.Time) bool {
+	if t.StartsAt != nil && now.Before(*t.StartsAt) {
+		return false
+	}
+	if t.EndsAt != nil && !now.Before(*t.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// TaskCycle is one completion window of a task, one-shot tasks have a single cycle with an empty key
+type TaskCycle struct {
+	Key   string
+	Start time.Time
+	End   time.Time
+}
+
+// Cycle returns the cycle now falls in. Cycles are computed in UTC: daily ones reset at midnight,
+// weekly ones on Monday midnight and N hour ones on multiples of N hours since the unix epoch,
+// counted from Unix() because Truncate would align to Go's zero time instead
+func (t Task) Cycle(now time.Time) TaskCycle {
+	var start, end time.Time
+	switch t.Recurrence {
+	case constant.TASK_RECURRENCE_DAILY:
+		start = pkg.StartOfDay(now)
+		end = start.AddDate(0, 0, 1)
+	case constant.TASK_RECURRENCE_WEEKLY:
+		start = pkg.StartOfWeek(now)
+		end = start.AddDate(0, 0, 7)
+	case constant.TASK_RECURRENCE_HOURS:
+		if t.RecurrenceHours <= 0 {
+			return TaskCycle{}
+		}
+		length := int64(t.RecurrenceHours) * int64(time.Hour/time.Second)
+		unix := now.Unix()
+		start = time.Unix(unix-((unix%length)+length)%length, 0).UTC()
+		end = start.Add(time.Duration(length) * time.Second)
+	default:
+		return TaskCycle{}
+	}
+	return TaskCycle{Key: start.Format(time.RFC3339), Start: start, End: end}
+}
+
+// TaskComplete is the status of a task in one cycle, past cycles stay as completion history
 type TaskComplete struct {
-	ID     uuid.UUID                   `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
-	UserID uuid.UUID                   `gorm:"not null"`
-	User   User                        `gorm:"foreignKey:UserID;column:user_id;not null;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
-	TaskID uuid.UUID                   `gorm:"not null"`
-	Task   Task                        `gorm:"not null;default:1"`
-	Status constant.TaskCompleteStatus `gorm:"not null;"`
+	ID        uuid.UUID                   `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	UserID    uuid.UUID                   `gorm:"not null;uniqueIndex:idx_task_complete_user_task_cycle"`
+	User      User                        `gorm:"foreignKey:UserID;column:user_id;not null;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	TaskID    uuid.UUID                   `gorm:"not null;uniqueIndex:idx_task_complete_user_task_cycle"`
+	Task      Task                        `gorm:"not null;default:1"`
+	Cycle     string                      `gorm:"type:text;not null;default:'';uniqueIndex:idx_task_complete_user_task_cycle"`
+	Status    constant.TaskCompleteStatus `gorm:"not null;"`
+	ClaimedAt *time.Time                  `gorm:"default:null"`
 	BaseModel
 }
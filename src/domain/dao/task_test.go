@@ -0,0 +1,49 @@
+package dao
+
+import (
+	"crazyfarmbackend/src/constant"
+	"testing"
+	"time"
+)
+
+func TestTaskCycle(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	tests := []struct {
+		name       string
+		task       Task
+		now        time.Time
+		start, end string
+	}{
+		{"one shot", Task{}, at("2024-03-13T15:04:05Z"), "", ""},
+		{"daily", Task{Recurrence: constant.TASK_RECURRENCE_DAILY}, at("2024-03-13T15:04:05Z"), "2024-03-13T00:00:00Z", "2024-03-14T00:00:00Z"},
+		{"daily in another zone", Task{Recurrence: constant.TASK_RECURRENCE_DAILY}, at("2024-03-14T01:00:00+03:00"), "2024-03-13T00:00:00Z", "2024-03-14T00:00:00Z"},
+		{"weekly", Task{Recurrence: constant.TASK_RECURRENCE_WEEKLY}, at("2024-03-13T15:04:05Z"), "2024-03-11T00:00:00Z", "2024-03-18T00:00:00Z"},
+		{"weekly on monday", Task{Recurrence: constant.TASK_RECURRENCE_WEEKLY}, at("2024-03-11T00:00:00Z"), "2024-03-11T00:00:00Z", "2024-03-18T00:00:00Z"},
+		{"weekly on sunday", Task{Recurrence: constant.TASK_RECURRENCE_WEEKLY}, at("2024-03-17T23:59:59Z"), "2024-03-11T00:00:00Z", "2024-03-18T00:00:00Z"},
+		{"6 hours", Task{Recurrence: constant.TASK_RECURRENCE_HOURS, RecurrenceHours: 6}, at("2024-03-13T15:04:05Z"), "2024-03-13T12:00:00Z", "2024-03-13T18:00:00Z"},
+		// 1970-01-01 plus a multiple of 7 hours, not of the hours since year one
+		{"7 hours", Task{Recurrence: constant.TASK_RECURRENCE_HOURS, RecurrenceHours: 7}, at("2024-03-13T15:04:05Z"), "2024-03-13T10:00:00Z", "2024-03-13T17:00:00Z"},
+		{"hours on the boundary", Task{Recurrence: constant.TASK_RECURRENCE_HOURS, RecurrenceHours: 7}, at("2024-03-13T17:00:00Z"), "2024-03-13T17:00:00Z", "2024-03-14T00:00:00Z"},
+		{"hours unset", Task{Recurrence: constant.TASK_RECURRENCE_HOURS}, at("2024-03-13T15:04:05Z"), "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cycle := tt.task.Cycle(tt.now)
+			if tt.start == "" {
+				if cycle != (TaskCycle{}) {
+					t.Fatalf("cycle %+v, want none", cycle)
+				}
+				return
+			}
+			if !cycle.Start.Equal(at(tt.start)) || !cycle.End.Equal(at(tt.end)) || cycle.Key != tt.start {
+				t.Fatalf("cycle %s - %s key %q, want %s - %s", cycle.Start, cycle.End, cycle.Key, tt.start, tt.end)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package dao
+
+import (
+	"crazyfarmbackend/src/constant"
+	"github.com/google/uuid"
+	"time"
+)
+
+type Gift struct {
+	ID         uuid.UUID      `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	FromUserID uuid.UUID      `gorm:"not null;index"`
+	FromUser   User           `gorm:"foreignKey:FromUserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	ToUserID   uuid.UUID      `gorm:"not null;index"`
+	ToUser     User           `gorm:"foreignKey:ToUserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Plant      constant.Plant `gorm:"not null"`
+	Quantity   int            `gorm:"not null"`
+	BaseModel
+}
+
+// TradeOffer swaps Give from the sender for Want from the receiver once the receiver accepts
+type TradeOffer struct {
+	ID         uuid.UUID            `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	FromUserID uuid.UUID            `gorm:"not null;index"`
+	FromUser   User                 `gorm:"foreignKey:FromUserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	ToUserID   uuid.UUID            `gorm:"not null;index"`
+	ToUser     User                 `gorm:"foreignKey:ToUserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Give       []ItemStack          `gorm:"serializer:json"`
+	Want       []ItemStack          `gorm:"serializer:json"`
+	Status     constant.TradeStatus `gorm:"type:text;not null;default:PENDING"`
+	ResolvedAt *time.Time           `gorm:"default:null"`
+	BaseModel
+}
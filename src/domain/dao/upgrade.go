@@ -0,0 +1,9 @@
+package dao
+
+type FarmLevel struct {
+	Lvl       int         `gorm:"primary_key;autoIncrement:false"`
+	MaxFields int         `gorm:"not null"`
+	Cost      []ItemStack `gorm:"serializer:json"`
+	CoinCost  int64       `gorm:"not null;default:0"`
+	BaseModel
+}
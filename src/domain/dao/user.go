@@ -9,19 +9,23 @@ type UserAuth struct {
 	ID         uuid.UUID `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
 	UserID     uuid.UUID `gorm:"not null"`
 	User       User      `gorm:"foreignKey:UserID;column:user_id;not null;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
-	AuthData   string    `gorm:"type:text;not null"`
-	AuthMethod string    `gorm:"type:text;not null"`
+	AuthData   string    `gorm:"type:text;not null;uniqueIndex:idx_user_auth_identity"`
+	AuthMethod string    `gorm:"type:text;not null;uniqueIndex:idx_user_auth_identity"`
 	BaseModel
 }
 
 type User struct {
-	ID           uuid.UUID `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
-	TgId         int64     `gorm:"not null;default:0"`
-	FirstName    *string   `gorm:"type:text;default:null"`
-	LastName     *string   `gorm:"type:text;default:null"`
-	Username     *string   `gorm:"type:text;default:null"`
-	Icon         *string   `gorm:"type:text;default:null"`
-	LanguageCode *string   `gorm:"type:text;default:null"`
+	ID           uuid.UUID     `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	TgId         int64         `gorm:"not null;default:0"`
+	FirstName    *string       `gorm:"type:text;default:null"`
+	LastName     *string       `gorm:"type:text;default:null"`
+	Username     *string       `gorm:"type:text;default:null"`
+	Icon         *string       `gorm:"type:text;default:null"`
+	LanguageCode *string       `gorm:"type:text;default:null"`
+	IsPremium    bool          `gorm:"not null;default:false"` // Telegram Premium, refreshed on every sign in
+	Coins        int64         `gorm:"not null;default:0"`
+	Role         constant.Role `gorm:"type:text;not null;default:PLAYER"`
+	MergedIntoID *uuid.UUID    `gorm:"type:uuid;default:null"` // Account this duplicate was merged into, it has no auth methods left
 	BaseModel
 }
 
@@ -30,15 +34,17 @@ type UserUpgrade struct {
 	UserID  uuid.UUID `gorm:"not null"`
 	User    User      `gorm:"foreignKey:UserID;column:user_id;not null;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 	FarmLvl int       `gorm:"not null;default:1"`
+	FarmXp  int64     `gorm:"not null;default:0"`
 	BaseModel
 }
 
 type UserField struct {
 	ID      uuid.UUID      `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
-	UserID  uuid.UUID      `gorm:"not null"`
+	UserID  uuid.UUID      `gorm:"not null;uniqueIndex:idx_user_field"`
 	User    User           `gorm:"foreignKey:UserID;column:user_id;not null;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
-	FieldID int            `gorm:"not null;"`
+	FieldID int            `gorm:"not null;uniqueIndex:idx_user_field"`
 	Plant   constant.Plant `gorm:"not null;"`
+	Stolen  int            `gorm:"not null;default:0"` // Part of the reward already taken by a friend
 	BaseModel
 }
 
@@ -48,4 +54,5 @@ type UserReferral struct {
 	Referrer   User      `gorm:"foreignKey:ReferrerID;column:referrer_id;not null;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 	ReferralId uuid.UUID `gorm:"not null;uniqueIndex"`
 	Referral   User      `gorm:"foreignKey:ReferralId;column:referral_id;not null;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Premium    bool      `gorm:"not null;default:false"` // Referral had Telegram Premium when signing up, selects the premium tiers
 }
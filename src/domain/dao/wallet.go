@@ -0,0 +1,18 @@
+package dao
+
+import (
+	"crazyfarmbackend/src/constant"
+	"github.com/google/uuid"
+)
+
+// WalletTransaction is one coin balance change, TxID makes replays of the same change a no-op
+type WalletTransaction struct {
+	ID           uuid.UUID             `gorm:"primary_key;type:uuid;default:gen_random_uuid()"`
+	UserID       uuid.UUID             `gorm:"not null;index"`
+	User         User                  `gorm:"foreignKey:UserID;column:user_id;not null;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	TxID         string                `gorm:"type:text;not null;uniqueIndex"`
+	Amount       int64                 `gorm:"not null"`
+	BalanceAfter int64                 `gorm:"not null"`
+	Reason       constant.WalletReason `gorm:"type:text;not null"`
+	BaseModel
+}
@@ -0,0 +1,28 @@
+package dto
+
+import (
+	"crazyfarmbackend/src/constant"
+	"github.com/google/uuid"
+)
+
+type RolePermissions struct {
+	Role        constant.Role         `json:"Role"`
+	Permissions []constant.Permission `json:"Permissions"`
+}
+
+type StaffUser struct {
+	User User          `json:"User"`
+	Role constant.Role `json:"Role"`
+}
+
+type SetRoleRequest struct {
+	UserID uuid.UUID     `json:"UserID" validate:"required"`
+	Role   constant.Role `json:"Role" validate:"required"`
+}
+
+// GrantItemsRequest puts Amount of Plant into the inventory of UserID, a negative amount takes items back
+type GrantItemsRequest struct {
+	UserID uuid.UUID      `json:"UserID" validate:"required"`
+	Plant  constant.Plant `json:"Plant" validate:"required"`
+	Amount int            `json:"Amount" validate:"required"`
+}
@@ -0,0 +1,60 @@
+package dto
+
+import "github.com/google/uuid"
+
+// TonProof is the account and ton_proof a TON Connect wallet returns on connect
+type TonProof struct {
+	Address   string        `json:"address"`
+	Network   string        `json:"network"`
+	PublicKey string        `json:"public_key"`
+	Proof     TonProofProof `json:"proof"`
+}
+
+type TonProofProof struct {
+	Timestamp int64          `json:"timestamp"`
+	Domain    TonProofDomain `json:"domain"`
+	Signature string         `json:"signature"`
+	Payload   string         `json:"payload"`
+	StateInit string         `json:"state_init"`
+}
+
+type TonProofDomain struct {
+	LengthBytes uint32 `json:"lengthBytes"`
+	Value       string `json:"value"`
+}
+
+// TonProofPayload is the challenge the wallet has to sign
+type TonProofPayload struct {
+	Payload   string `json:"payload"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+type EmailCodeRequest struct {
+	Email string `json:"email" validate:"required,max=254"`
+}
+
+// EmailAuthData is the data of the email auth method
+type EmailAuthData struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+type AuthMethod struct {
+	ID       uuid.UUID `json:"ID"`
+	Method   string    `json:"Method"`
+	Identity string    `json:"Identity"`
+	LinkedAt int64     `json:"LinkedAt"`
+	Current  bool      `json:"Current"` // Method the calling session signed in with
+}
+
+// AuthConflict answers a link whose identity belongs to another account, the merge token lets the
+// caller merge that account into theirs without signing in to it again
+type AuthConflict struct {
+	Method     string `json:"method"`
+	MergeToken string `json:"mergeToken"`
+	ExpiresAt  int64  `json:"expiresAt"`
+}
+
+type MergeRequest struct {
+	MergeToken string `json:"mergeToken" validate:"required"`
+}
@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"crazyfarmbackend/src/constant"
+	"github.com/google/uuid"
+)
+
+type FarmField struct {
+	FieldID        int            `json:"FieldID"`
+	Plant          constant.Plant `json:"Plant"`
+	ReadyAt        int64          `json:"ReadyAt"`
+	Stolen         int            `json:"Stolen"`
+	Stealable      bool           `json:"Stealable"`
+	StealableUntil int64          `json:"StealableUntil"`
+}
+
+type FarmVisit struct {
+	Owner          UserReferral `json:"owner"`
+	FarmLvl        int          `json:"farm_lvl"`
+	Fields         []FarmField  `json:"fields"`
+	StealCooldown  int64        `json:"steal_cooldown"`
+	VictimCooldown int64        `json:"victim_cooldown"`
+}
+
+type Theft struct {
+	ID       uuid.UUID      `json:"ID"`
+	VictimID uuid.UUID      `json:"VictimID"`
+	FieldID  int            `json:"FieldID"`
+	Plant    constant.Plant `json:"Plant"`
+	Amount   int            `json:"Amount"`
+}
+
+type Notification struct {
+	ID        uuid.UUID                 `json:"ID"`
+	Type      constant.NotificationType `json:"Type"`
+	Actor     UserReferral              `json:"Actor"`
+	Plant     constant.Plant            `json:"Plant"`
+	Amount    int                       `json:"Amount"`
+	Read      bool                      `json:"Read"`
+	CreatedAt int64                     `json:"CreatedAt"`
+}
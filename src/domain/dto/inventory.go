@@ -7,6 +7,32 @@ type InventoryItem struct {
 	Quantity int            `json:"Quantity"`
 }
 
+type ItemStack struct {
+	Plant  constant.Plant `json:"Plant"`
+	Amount int            `json:"Amount"`
+}
+
 type GetAllItemsResponse struct {
 	Items []InventoryItem `json:"items"`
 }
+
+type HarvestResult struct {
+	FieldID int            `json:"FieldID"`
+	Plant   constant.Plant `json:"Plant"`
+	Amount  int            `json:"Amount"`
+}
+
+type LedgerEntry struct {
+	ID           uint64                `json:"ID"`
+	Plant        constant.Plant        `json:"Plant"`
+	Amount       int                   `json:"Amount"`
+	BalanceAfter int                   `json:"BalanceAfter"`
+	Reason       constant.LedgerReason `json:"Reason"`
+	RefID        string                `json:"RefID"`
+	CreatedAt    int64                 `json:"CreatedAt"`
+}
+
+type InventoryHistoryResponse struct {
+	Items      []LedgerEntry `json:"items"`
+	NextCursor *string       `json:"next_cursor"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+import "crazyfarmbackend/src/constant"
+
+type LeaderboardEntry struct {
+	Rank  int64        `json:"Rank"`
+	User  UserReferral `json:"User"`
+	Score int64        `json:"Score"`
+}
+
+type Leaderboard struct {
+	Board   constant.LeaderboardBoard `json:"board"`
+	Scope   string                    `json:"scope"`
+	Period  string                    `json:"period"`
+	Entries []LeaderboardEntry        `json:"entries"`
+	Me      LeaderboardEntry          `json:"me"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+import "crazyfarmbackend/src/constant"
+
+type Plant struct {
+	Name          constant.Plant `json:"Name"`
+	GrowTime      int64          `json:"GrowTime"`
+	Reward        int            `json:"Reward"`
+	SellPrice     int64          `json:"SellPrice"`
+	Icon          *string        `json:"Icon"`
+	Enabled       bool           `json:"Enabled"`
+	AvailableFrom *int64         `json:"AvailableFrom"`
+	AvailableTo   *int64         `json:"AvailableTo"`
+	SortOrder     int            `json:"SortOrder"`
+}
+
+// PlantRequest is the admin create and update body, times are unix seconds. Create needs GrowTime and
+// Reward, an update writes only the fields the body sets. An empty Icon and a zero AvailableFrom or
+// AvailableTo clear them
+type PlantRequest struct {
+	Name          constant.Plant `json:"Name"`
+	GrowTime      *int64         `json:"GrowTime" validate:"min=1"`
+	Reward        *int           `json:"Reward" validate:"min=1"`
+	SellPrice     *int64         `json:"SellPrice" validate:"min=0"`
+	Icon          *string        `json:"Icon"`
+	Enabled       *bool          `json:"Enabled"` // Left out: enabled on create, unchanged on update
+	AvailableFrom *int64         `json:"AvailableFrom"`
+	AvailableTo   *int64         `json:"AvailableTo"`
+	SortOrder     *int           `json:"SortOrder"`
+}
@@ -0,0 +1,39 @@
+package dto
+
+import "crazyfarmbackend/src/constant"
+
+type ReferralReward struct {
+	ID             string                     `json:"ID"`
+	Milestone      constant.ReferralMilestone `json:"Milestone"`
+	Threshold      int                        `json:"Threshold"`
+	Rewards        []RewardLine               `json:"Rewards"`
+	PremiumRewards []RewardLine               `json:"PremiumRewards"`
+	Enabled        bool                       `json:"Enabled"`
+	SortOrder      int                        `json:"SortOrder"`
+}
+
+// ReferralRewardRequest is the admin body creating or replacing the tier with ID, the reward lines are
+// checked by the service
+type ReferralRewardRequest struct {
+	ID             string                     `json:"ID" validate:"required"`
+	Milestone      constant.ReferralMilestone `json:"Milestone" validate:"required"`
+	Threshold      int                        `json:"Threshold" validate:"min=0"`
+	Rewards        []RewardLine               `json:"Rewards"`
+	PremiumRewards []RewardLine               `json:"PremiumRewards"`
+	Enabled        bool                       `json:"Enabled"`
+	SortOrder      int                        `json:"SortOrder"`
+}
+
+type ReferralPayout struct {
+	Referral  UserReferral `json:"Referral"`
+	RewardID  string       `json:"RewardID"`
+	Premium   bool         `json:"Premium"`
+	Rewards   []RewardLine `json:"Rewards"`
+	CreatedAt int64        `json:"CreatedAt"`
+}
+
+// ReferralProgram lists the tiers a referrer can earn and what they were paid so far
+type ReferralProgram struct {
+	Tiers   []ReferralReward `json:"tiers"`
+	Payouts []ReferralPayout `json:"payouts"`
+}
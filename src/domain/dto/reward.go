@@ -0,0 +1,11 @@
+package dto
+
+import "crazyfarmbackend/src/constant"
+
+// RewardLine is one entry of a reward bundle, Plant is only set for PLANT rewards and
+// Amount is the level to unlock for FARM_LEVEL rewards
+type RewardLine struct {
+	Kind   constant.RewardKind `json:"Kind" validate:"required"`
+	Plant  constant.Plant      `json:"Plant,omitempty"`
+	Amount int64               `json:"Amount" validate:"min=1"`
+}
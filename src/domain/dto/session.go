@@ -0,0 +1,12 @@
+package dto
+
+// SessionTokens is a short lived access token and the refresh token that renews it
+type SessionTokens struct {
+	Token        string `json:"token"`
+	ExpiresAt    int64  `json:"expiresAt"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
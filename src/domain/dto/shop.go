@@ -0,0 +1,74 @@
+package dto
+
+import (
+	"crazyfarmbackend/src/constant"
+	"github.com/google/uuid"
+)
+
+type ShopItem struct {
+	ID                 uuid.UUID   `json:"ID"`
+	Name               string      `json:"Name"`
+	Icon               *string     `json:"Icon"`
+	Contents           []ItemStack `json:"Contents"`
+	PriceCoins         int64       `json:"PriceCoins"`
+	PriceItems         []ItemStack `json:"PriceItems"`
+	PurchaseLimit      int         `json:"PurchaseLimit"`
+	RemainingPurchases *int        `json:"RemainingPurchases"`
+	Stock              *int        `json:"Stock"`
+}
+
+// ShopItemDefinition is the admin view of a shop item, disabled items included
+type ShopItemDefinition struct {
+	ID            uuid.UUID   `json:"ID"`
+	Name          string      `json:"Name"`
+	Icon          *string     `json:"Icon"`
+	Contents      []ItemStack `json:"Contents"`
+	PriceCoins    int64       `json:"PriceCoins"`
+	PriceItems    []ItemStack `json:"PriceItems"`
+	PurchaseLimit int         `json:"PurchaseLimit"`
+	Stock         *int        `json:"Stock"`
+	Enabled       bool        `json:"Enabled"`
+	SortOrder     int         `json:"SortOrder"`
+}
+
+// ShopItemRequest is the admin create and update body. Create needs Name and Contents, an update
+// writes only the fields the body sets. An empty Icon clears it and a Stock of -1 makes it unlimited
+type ShopItemRequest struct {
+	Name          *string     `json:"Name" validate:"min=1"`
+	Icon          *string     `json:"Icon"`
+	Contents      []ItemStack `json:"Contents"`
+	PriceCoins    *int64      `json:"PriceCoins" validate:"min=0"`
+	PriceItems    []ItemStack `json:"PriceItems"` // Left out: unchanged on update, an empty list clears it
+	PurchaseLimit *int        `json:"PurchaseLimit" validate:"min=0"`
+	Stock         *int        `json:"Stock" validate:"min=-1"`
+	Enabled       *bool       `json:"Enabled"` // Left out: enabled on create, unchanged on update
+	SortOrder     *int        `json:"SortOrder"`
+}
+
+type ShopPurchase struct {
+	ID         uuid.UUID   `json:"ID"`
+	ShopItemID uuid.UUID   `json:"ShopItemID"`
+	Quantity   int         `json:"Quantity"`
+	PriceCoins int64       `json:"PriceCoins"`
+	Received   []ItemStack `json:"Received"`
+}
+
+type SellPrice struct {
+	Plant     constant.Plant `json:"Plant"`
+	UnitPrice int64          `json:"UnitPrice"`
+}
+
+type SellPricesResponse struct {
+	Prices         []SellPrice `json:"prices"`
+	DailyCap       int64       `json:"daily_cap"` // 0 when selling is not capped
+	SoldToday      int64       `json:"sold_today"`
+	RemainingToday *int64      `json:"remaining_today"` // Null without a cap
+}
+
+type Sale struct {
+	ID        uuid.UUID      `json:"ID"`
+	Plant     constant.Plant `json:"Plant"`
+	Quantity  int            `json:"Quantity"`
+	UnitPrice int64          `json:"UnitPrice"`
+	Coins     int64          `json:"Coins"`
+}
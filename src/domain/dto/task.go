@@ -7,12 +7,108 @@ import (
 
 type Task struct {
 	ID            uuid.UUID
-	Name          string
+	Name          string // Texts are in the language resolved for the request
+	Description   *string
+	ButtonLabel   *string
 	Icon          *string
 	Reward        constant.Plant
 	RewardAmount  int
+	Rewards       []RewardLine // Every line paid out on claim
 	NeedDoneTimes int
 	Type          constant.Task
+	Category      string
 	Data          map[string]interface{}
 	Status        constant.TaskCompleteStatus
+	Progress      *int // Progress towards NeedDoneTimes, nil for all or nothing task types
+	Recurrence    constant.TaskRecurrence
+	ResetsAt      *int64 // Unix time the current cycle ends, nil for one-shot tasks
+	EndsAt        *int64 // Unix time the task is withdrawn, nil if it runs indefinitely
+}
+
+type TaskAudience struct {
+	LanguageCodes []string `json:"LanguageCodes"`
+	MinFarmLvl    int      `json:"MinFarmLvl" validate:"min=0"`
+	MaxFarmLvl    int      `json:"MaxFarmLvl" validate:"min=0"`
+	Referred      *bool    `json:"Referred"`
+}
+
+// TaskDefinition is the full task as admins manage it, times are unix seconds
+type TaskDefinition struct {
+	ID              uuid.UUID               `json:"ID"`
+	Name            string                  `json:"Name"`
+	Description     *string                 `json:"Description"`
+	ButtonLabel     *string                 `json:"ButtonLabel"`
+	Translations    map[string]TaskText     `json:"Translations"`
+	Icon            *string                 `json:"Icon"`
+	Reward          constant.Plant          `json:"Reward"`
+	RewardAmount    int                     `json:"RewardAmount"`
+	Rewards         []RewardLine            `json:"Rewards"`
+	NeedDoneTimes   int                     `json:"NeedDoneTimes"`
+	Type            constant.Task           `json:"Type"`
+	Data            map[string]interface{}  `json:"Data"`
+	Recurrence      constant.TaskRecurrence `json:"Recurrence"`
+	RecurrenceHours int                     `json:"RecurrenceHours"`
+	StartsAt        *int64                  `json:"StartsAt"`
+	EndsAt          *int64                  `json:"EndsAt"`
+	Prerequisites   []uuid.UUID             `json:"Prerequisites"`
+	Audience        TaskAudience            `json:"Audience"`
+	Category        string                  `json:"Category"`
+	SortOrder       int                     `json:"SortOrder"`
+	Enabled         bool                    `json:"Enabled"`
+}
+
+// TaskText is a translation of the task texts, keyed by primary language subtag in TaskRequest.Translations
+type TaskText struct {
+	Name        string `json:"Name"`
+	Description string `json:"Description"`
+	ButtonLabel string `json:"ButtonLabel"`
+}
+
+// TaskRequest is the admin create and update body, Data must match the schema of Type, see TaskType.
+// Rewards takes precedence over the single Reward and RewardAmount
+type TaskRequest struct {
+	Name            string                  `json:"Name" validate:"required"`
+	Description     *string                 `json:"Description"`
+	ButtonLabel     *string                 `json:"ButtonLabel"`
+	Translations    map[string]TaskText     `json:"Translations"`
+	Icon            *string                 `json:"Icon"`
+	Reward          constant.Plant          `json:"Reward"`
+	RewardAmount    int                     `json:"RewardAmount" validate:"min=0"`
+	Rewards         []RewardLine            `json:"Rewards"`
+	NeedDoneTimes   int                     `json:"NeedDoneTimes" validate:"min=0"`
+	Type            constant.Task           `json:"Type" validate:"required"`
+	Data            map[string]interface{}  `json:"Data"`
+	Recurrence      constant.TaskRecurrence `json:"Recurrence"`
+	RecurrenceHours int                     `json:"RecurrenceHours" validate:"min=0"`
+	StartsAt        *int64                  `json:"StartsAt"`
+	EndsAt          *int64                  `json:"EndsAt"`
+	Prerequisites   []uuid.UUID             `json:"Prerequisites"`
+	Audience        TaskAudience            `json:"Audience"`
+	Category        string                  `json:"Category"`
+	SortOrder       int                     `json:"SortOrder"`
+	Enabled         *bool                   `json:"Enabled"` // Left out: enabled on create, unchanged on update
+}
+
+type TaskReorderRequest struct {
+	TaskIDs []uuid.UUID `json:"TaskIDs" validate:"required,min=1"`
+}
+
+// TaskType documents a registered task type, Data is an example of its Data schema. Tasks of a type
+// that is not Recurring must have no Recurrence
+type TaskType struct {
+	Type       constant.Task `json:"Type"`
+	Measurable bool          `json:"Measurable"`
+	Recurring  bool          `json:"Recurring"`
+	Data       interface{}   `json:"Data"`
+}
+
+type TaskStats struct {
+	TaskID        uuid.UUID `json:"TaskID"`
+	Name          string    `json:"Name"`
+	Enabled       bool      `json:"Enabled"`
+	DoneUsers     int64     `json:"DoneUsers"`
+	FinishedUsers int64     `json:"FinishedUsers"`
+	Claims        int64     `json:"Claims"`
+	RewardsPaid   int64     `json:"RewardsPaid"`
+	CoinsPaid     int64     `json:"CoinsPaid"`
 }
@@ -0,0 +1,34 @@
+package dto
+
+import (
+	"crazyfarmbackend/src/constant"
+	"github.com/google/uuid"
+)
+
+type Gift struct {
+	ID       uuid.UUID      `json:"ID"`
+	ToUserID uuid.UUID      `json:"ToUserID"`
+	Plant    constant.Plant `json:"Plant"`
+	Quantity int            `json:"Quantity"`
+}
+
+type TradeOfferRequest struct {
+	ToUserID uuid.UUID   `json:"ToUserID" validate:"required"`
+	Give     []ItemStack `json:"Give"`
+	Want     []ItemStack `json:"Want"`
+}
+
+type TradeOffer struct {
+	ID         uuid.UUID            `json:"ID"`
+	FromUserID uuid.UUID            `json:"FromUserID"`
+	ToUserID   uuid.UUID            `json:"ToUserID"`
+	Give       []ItemStack          `json:"Give"`
+	Want       []ItemStack          `json:"Want"`
+	Status     constant.TradeStatus `json:"Status"`
+	CreatedAt  int64                `json:"CreatedAt"`
+}
+
+type TradeOffersResponse struct {
+	Incoming []TradeOffer `json:"incoming"`
+	Outgoing []TradeOffer `json:"outgoing"`
+}
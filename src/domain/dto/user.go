@@ -20,14 +20,34 @@ type User struct {
 }
 
 type UserUpgrade struct {
-	FarmLvl   int `json:"FarmLvl"`
-	MaxFields int `json:"MaxFields"`
+	FarmLvl   int        `json:"FarmLvl"`
+	FarmXp    int64      `json:"FarmXp"`
+	MaxFields int        `json:"MaxFields"`
+	Next      *FarmLevel `json:"Next"`
+}
+
+type FarmLevel struct {
+	Lvl       int         `json:"Lvl"`
+	MaxFields int         `json:"MaxFields"`
+	Cost      []ItemStack `json:"Cost"`
+	CoinCost  int64       `json:"CoinCost"`
+}
+
+// FarmLevelRequest is the admin body creating or replacing level Lvl, the costs are checked by the service
+type FarmLevelRequest struct {
+	Lvl       int         `json:"Lvl" validate:"min=1"`
+	MaxFields int         `json:"MaxFields" validate:"min=1"`
+	Cost      []ItemStack `json:"Cost"`
+	CoinCost  int64       `json:"CoinCost" validate:"min=0"`
 }
 
 type UserField struct {
-	FieldID   int            `json:"FieldID"`
-	Plant     constant.Plant `json:"Plant"`
-	PlantTime int64          `json:"PlantTime"`
+	FieldID       int            `json:"FieldID"`
+	Plant         constant.Plant `json:"Plant"`
+	PlantTime     int64          `json:"PlantTime"`
+	ReadyAt       int64          `json:"ReadyAt"`
+	RemainingTime int64          `json:"RemainingTime"`
+	Stolen        int            `json:"Stolen"`
 }
 
 type UserReferral struct {
@@ -39,6 +59,6 @@ type UserReferral struct {
 }
 
 type UserAuthResponse struct {
-	User  User   `json:"user"`
-	Token string `json:"token"`
+	User User `json:"user"`
+	SessionTokens
 }
@@ -0,0 +1,21 @@
+package dto
+
+import "github.com/google/uuid"
+
+type Wallet struct {
+	Coins int64 `json:"Coins"`
+}
+
+// CoinCredit is the payload published on the coin receive subject
+type CoinCredit struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+	Amount int64     `json:"amount" validate:"required,min=1"`
+	TxID   string    `json:"tx_id" validate:"required,max=128"`
+}
+
+type CoinCreditReply struct {
+	OK        bool   `json:"ok"`
+	Duplicate bool   `json:"duplicate"`
+	Balance   int64  `json:"balance"`
+	Error     string `json:"error,omitempty"`
+}
@@ -0,0 +1,165 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+var ErrInvalidBoc = errors.New("invalid bag of cells")
+
+var bocMagic = []byte{0xb5, 0xee, 0x9c, 0x72}
+
+// Enough for any wallet state init, larger bags are rejected before parsing
+const bocMaxCells = 1024
+
+// bocCell is a level 0 cell of a parsed bag of cells
+type bocCell struct {
+	d1, d2 byte
+	data   []byte // Data as serialized, including the completion tag
+	bitLen int
+	refs   []*bocCell
+	hash   []byte
+	depth  uint16
+}
+
+// bit reports the i-th data bit, bits past the end read as zero
+func (c *bocCell) bit(i int) bool {
+	if i < 0 || i >= c.bitLen {
+		return false
+	}
+	return c.data[i/8]&(0x80>>(i%8)) != 0
+}
+
+// bytesAt reads n whole bytes starting at an arbitrary bit offset
+func (c *bocCell) bytesAt(offset, n int) ([]byte, bool) {
+	if offset < 0 || offset+n*8 > c.bitLen {
+		return nil, false
+	}
+	out := make([]byte, n)
+	for i := 0; i < n*8; i++ {
+		if c.bit(offset + i) {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out, true
+}
+
+// parseBoc parses a single root bag of cells and computes the representation hash of every cell
+func parseBoc(boc []byte) (*bocCell, error) {
+	if len(boc) < 6 || !bytes.Equal(boc[:4], bocMagic) {
+		return nil, ErrInvalidBoc
+	}
+	flags := boc[4]
+	hasIdx, hasCrc := flags&0x80 != 0, flags&0x40 != 0
+	sizeBytes, offBytes := int(flags&0x07), int(boc[5])
+	if sizeBytes == 0 || sizeBytes > 4 || offBytes == 0 || offBytes > 8 {
+		return nil, ErrInvalidBoc
+	}
+	if hasCrc {
+		if len(boc) < 10 {
+			return nil, ErrInvalidBoc
+		}
+		body := boc[:len(boc)-4]
+		if crc32.Checksum(body, crc32.MakeTable(crc32.Castagnoli)) != binary.LittleEndian.Uint32(boc[len(boc)-4:]) {
+			return nil, ErrInvalidBoc
+		}
+		boc = body
+	}
+
+	rest := boc[6:]
+	read := func(n int) (int, bool) {
+		if len(rest) < n {
+			return 0, false
+		}
+		var v uint64
+		for _, b := range rest[:n] {
+			v = v<<8 | uint64(b)
+		}
+		rest = rest[n:]
+		return int(v), v <= uint64(len(boc))*8
+	}
+	cellsNum, ok1 := read(sizeBytes)
+	rootsNum, ok2 := read(sizeBytes)
+	_, ok3 := read(sizeBytes)
+	totSize, ok4 := read(offBytes)
+	rootIdx, ok5 := read(sizeBytes)
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 || rootsNum != 1 || cellsNum == 0 || cellsNum > bocMaxCells || rootIdx >= cellsNum {
+		return nil, ErrInvalidBoc
+	}
+	if hasIdx {
+		if len(rest) < cellsNum*offBytes {
+			return nil, ErrInvalidBoc
+		}
+		rest = rest[cellsNum*offBytes:]
+	}
+	if len(rest) != totSize {
+		return nil, ErrInvalidBoc
+	}
+
+	cells := make([]*bocCell, cellsNum)
+	refIndexes := make([][]int, cellsNum)
+	for i := range cells {
+		if len(rest) < 2 {
+			return nil, ErrInvalidBoc
+		}
+		d1, d2 := rest[0], rest[1]
+		rest = rest[2:]
+		// Only ordinary and library cells of level 0 appear in a state init
+		if d1>>5 != 0 || d1&0x07 > 4 {
+			return nil, ErrInvalidBoc
+		}
+		dataLen := int(d2+1) / 2
+		if len(rest) < dataLen {
+			return nil, ErrInvalidBoc
+		}
+		cell := &bocCell{d1: d1, d2: d2, data: rest[:dataLen], bitLen: dataLen * 8}
+		rest = rest[dataLen:]
+		if d2%2 == 1 {
+			// Odd d2 means a partial last byte, its lowest set bit is the completion tag
+			last := cell.data[dataLen-1]
+			if last == 0 {
+				return nil, ErrInvalidBoc
+			}
+			trailing := 0
+			for last&(1<<trailing) == 0 {
+				trailing++
+			}
+			cell.bitLen -= trailing + 1
+		}
+		for r := 0; r < int(d1&0x07); r++ {
+			ref, ok := read(sizeBytes)
+			// Children always follow their parent, which also rules out cycles
+			if !ok || ref <= i || ref >= cellsNum {
+				return nil, ErrInvalidBoc
+			}
+			refIndexes[i] = append(refIndexes[i], ref)
+		}
+		cells[i] = cell
+	}
+	if len(rest) != 0 {
+		return nil, ErrInvalidBoc
+	}
+
+	for i := cellsNum - 1; i >= 0; i-- {
+		cell := cells[i]
+		h := sha256.New()
+		h.Write([]byte{cell.d1, cell.d2})
+		h.Write(cell.data)
+		for _, ref := range refIndexes[i] {
+			child := cells[ref]
+			cell.refs = append(cell.refs, child)
+			if child.depth+1 > cell.depth {
+				cell.depth = child.depth + 1
+			}
+			h.Write([]byte{byte(child.depth >> 8), byte(child.depth)})
+		}
+		for _, child := range cell.refs {
+			h.Write(child.hash)
+		}
+		cell.hash = h.Sum(nil)
+	}
+	return cells[rootIdx], nil
+}
@@ -0,0 +1,177 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+)
+
+// testCell is an ordinary cell built independently of the parser, with its expected hash
+type testCell struct {
+	data   []byte
+	bitLen int
+	refs   []*testCell
+}
+
+func newTestCell(data []byte, bitLen int, refs ...*testCell) *testCell {
+	return &testCell{data: data, bitLen: bitLen, refs: refs}
+}
+
+func (c *testCell) descriptors() (byte, byte) {
+	return byte(len(c.refs)), byte(c.bitLen/8 + (c.bitLen+7)/8)
+}
+
+// padded returns the data bytes with the completion tag set after a partial last byte
+func (c *testCell) padded() []byte {
+	out := make([]byte, (c.bitLen+7)/8)
+	copy(out, c.data)
+	if c.bitLen%8 != 0 {
+		last := len(out) - 1
+		out[last] &= ^byte(0xff >> (c.bitLen % 8))
+		out[last] |= 0x80 >> (c.bitLen % 8)
+	}
+	return out
+}
+
+func (c *testCell) depth() uint16 {
+	var depth uint16
+	for _, ref := range c.refs {
+		if ref.depth()+1 > depth {
+			depth = ref.depth() + 1
+		}
+	}
+	return depth
+}
+
+func (c *testCell) hash() []byte {
+	d1, d2 := c.descriptors()
+	h := sha256.New()
+	h.Write([]byte{d1, d2})
+	h.Write(c.padded())
+	for _, ref := range c.refs {
+		_ = binary.Write(h, binary.BigEndian, ref.depth())
+	}
+	for _, ref := range c.refs {
+		h.Write(ref.hash())
+	}
+	return h.Sum(nil)
+}
+
+// serializeBoc writes a single root bag of cells with one byte cell indexes and two byte offsets
+func serializeBoc(root *testCell, withCrc bool) []byte {
+	var cells []*testCell
+	var flatten func(*testCell)
+	flatten = func(c *testCell) {
+		cells = append(cells, c)
+		for _, ref := range c.refs {
+			flatten(ref)
+		}
+	}
+	flatten(root)
+	index := make(map[*testCell]int, len(cells))
+	for i, c := range cells {
+		index[c] = i
+	}
+
+	body := new(bytes.Buffer)
+	for _, c := range cells {
+		d1, d2 := c.descriptors()
+		body.Write([]byte{d1, d2})
+		body.Write(c.padded())
+		for _, ref := range c.refs {
+			body.WriteByte(byte(index[ref]))
+		}
+	}
+
+	flags := byte(0x01)
+	if withCrc {
+		flags |= 0x40
+	}
+	boc := new(bytes.Buffer)
+	boc.Write(bocMagic)
+	boc.Write([]byte{flags, 2, byte(len(cells)), 1, 0})
+	_ = binary.Write(boc, binary.BigEndian, uint16(body.Len()))
+	boc.WriteByte(0)
+	boc.Write(body.Bytes())
+	if withCrc {
+		_ = binary.Write(boc, binary.LittleEndian, crc32.Checksum(boc.Bytes(), crc32.MakeTable(crc32.Castagnoli)))
+	}
+	return boc.Bytes()
+}
+
+func TestParseBoc(t *testing.T) {
+	leaf := newTestCell([]byte{0xde, 0xad, 0xbe}, 20)
+	root := newTestCell([]byte{0x30}, 5, newTestCell([]byte{0x01}, 8), newTestCell(bytes.Repeat([]byte{0xab}, 40), 321, leaf))
+	valid := serializeBoc(root, false)
+
+	tests := []struct {
+		name string
+		boc  []byte
+		want *testCell // Nil when the bag is invalid
+	}{
+		{"valid", valid, root},
+		{"valid with crc", serializeBoc(root, true), root},
+		{"single cell", serializeBoc(leaf, false), leaf},
+		{"empty", nil, nil},
+		{"bad magic", append([]byte{0xb5, 0xee, 0x9c, 0x73}, valid[4:]...), nil},
+		{"crc mismatch", func() []byte {
+			boc := serializeBoc(root, true)
+			boc[len(boc)-1] ^= 0xff
+			return boc
+		}(), nil},
+		{"truncated", valid[:len(valid)-1], nil},
+		{"trailing bytes", append(append([]byte{}, valid...), 0), nil},
+		{"reference to an earlier cell", func() []byte {
+			boc := append([]byte{}, valid...)
+			// The first reference of the root: 12 header bytes, two descriptors and one data byte
+			boc[12+3] = 0
+			return boc
+		}(), nil},
+		{"too many cells", func() []byte {
+			boc := append([]byte{}, valid...)
+			boc[6] = 0xff
+			return boc
+		}(), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cell, err := parseBoc(tt.boc)
+			if tt.want == nil {
+				if !errors.Is(err, ErrInvalidBoc) {
+					t.Fatalf("error %v, want ErrInvalidBoc", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(cell.hash, tt.want.hash()) {
+				t.Fatalf("root hash %x, want %x", cell.hash, tt.want.hash())
+			}
+		})
+	}
+}
+
+func TestBocCellBits(t *testing.T) {
+	cell, err := parseBoc(serializeBoc(newTestCell([]byte{0xa5, 0xf0}, 12), false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cell.bitLen != 12 {
+		t.Fatalf("bit length %d, want 12", cell.bitLen)
+	}
+	for i, want := range []bool{true, false, true, false, false, true, false, true, true, true, true, true, false} {
+		if cell.bit(i) != want {
+			t.Errorf("bit %d = %t, want %t", i, cell.bit(i), want)
+		}
+	}
+	if got, ok := cell.bytesAt(4, 1); !ok || got[0] != 0x5f {
+		t.Errorf("bytesAt(4, 1) = %x, %t, want 5f", got, ok)
+	}
+	if _, ok := cell.bytesAt(8, 1); ok {
+		t.Error("bytesAt past the end succeeded")
+	}
+}
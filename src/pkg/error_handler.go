@@ -21,9 +21,18 @@ func PanicHandler(c *gin.Context) {
 		case constant.Unauthorized.GetResponseStatus():
 			c.JSON(http.StatusUnauthorized, BuildResponse_(key, message))
 			c.Abort()
+		case constant.Forbidden.GetResponseStatus():
+			c.JSON(http.StatusForbidden, BuildResponse_(key, message))
+			c.Abort()
+		case constant.TooManyRequests.GetResponseStatus():
+			c.JSON(http.StatusTooManyRequests, BuildResponse_(key, message))
+			c.Abort()
 		case constant.WrongDataBody.GetResponseStatus():
 			c.JSON(http.StatusBadRequest, BuildResponse_(key, message))
 			c.Abort()
+		case constant.InvalidRequest.GetResponseStatus():
+			c.JSON(http.StatusBadRequest, BuildResponse_(key, message))
+			c.Abort()
 		case constant.WrongBody.GetResponseStatus():
 			c.JSON(http.StatusBadRequest, BuildResponse_(key, message))
 			c.Abort()
@@ -34,6 +34,19 @@ func validateStruct(v reflect.Value) error {
 		if validate == "" {
 			continue
 		}
+		// A pointer field is required to be present, a nil one is left out and skips the other rules
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				if strings.Contains(validate, "required") {
+					return fmt.Errorf("field %s is required", fieldType.Name)
+				}
+				continue
+			}
+			if err := validateField(field.Elem(), validate); err != nil {
+				return fmt.Errorf("in field %s: %w", fieldType.Name, err)
+			}
+			continue
+		}
 		if field.Kind() == reflect.Struct {
 			if err := validateStruct(field); err != nil {
 				return fmt.Errorf("in field %s: %w", fieldType.Name, err)
@@ -0,0 +1,26 @@
+package pkg
+
+import "testing"
+
+func TestUnmarshalAndValidatePointers(t *testing.T) {
+	type request struct {
+		Required *int   `json:"Required" validate:"required"`
+		Min      *int64 `json:"Min" validate:"min=1"`
+	}
+	tests := []struct {
+		body    string
+		wantErr bool
+	}{
+		{`{"Required": 0}`, false},
+		{`{"Required": 0, "Min": 1}`, false},
+		{`{"Required": 0, "Min": 0}`, true},
+		{`{}`, true},
+		{`{"Min": 5}`, true},
+	}
+	for _, tt := range tests {
+		var v request
+		if err := UnmarshalAndValidate([]byte(tt.body), &v); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.body, err, tt.wantErr)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package pkg
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -9,27 +13,40 @@ import (
 )
 
 var jwtKey = []byte(os.Getenv("JWT_KEY"))
-var jwtTtl = time.Hour * 24
 
-func CreateJwtToken(userId uuid.UUID) (string, error) {
+// Access tokens are short lived, clients renew them with the refresh token of their session
+const defaultAccessTokenTtl = 15 * time.Minute
+
+func accessTokenTtl() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("ACCESS_TOKEN_TTL"))
+	if err != nil || ttl <= 0 {
+		return defaultAccessTokenTtl
+	}
+	return ttl
+}
+
+// CreateJwtToken issues an access token for the auth method and session, it returns the token and its expiry
+func CreateJwtToken(userAuthId, sessionId uuid.UUID) (string, time.Time, error) {
+	expiresAt := time.Now().Add(accessTokenTtl())
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256,
 		jwt.MapClaims{
-			"user_auth_id": userId.String(),
-			"exp":          time.Now().Add(jwtTtl).Unix(),
+			"user_auth_id": userAuthId.String(),
+			"sid":          sessionId.String(),
+			"exp":          expiresAt.Unix(),
 		})
 
 	tokenString, err := token.SignedString(jwtKey)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
-	return tokenString, nil
+	return tokenString, expiresAt, nil
 }
 
 func VerifyJwtToken(tokenString string) (*jwt.Token, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		return jwtKey, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		return nil, err
@@ -41,6 +58,61 @@ func VerifyJwtToken(tokenString string) (*jwt.Token, error) {
 	return token, nil
 }
 
+// CreateMergeToken proves for ttl that the holder signed in to the account sourceUserId and may merge it
+// into targetUserId. It carries no session, so AuthMiddleware rejects it as an access token
+func CreateMergeToken(sourceUserId, targetUserId uuid.UUID, ttl time.Duration) (string, time.Time, error) {
+	expiresAt := time.Now().Add(ttl)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256,
+		jwt.MapClaims{
+			"typ":          "merge",
+			"merge_source": sourceUserId.String(),
+			"merge_target": targetUserId.String(),
+			"exp":          expiresAt.Unix(),
+		})
+	tokenString, err := token.SignedString(jwtKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return tokenString, expiresAt, nil
+}
+
+// VerifyMergeToken returns the source and target accounts of a merge token
+func VerifyMergeToken(tokenString string) (uuid.UUID, uuid.UUID, error) {
+	token, err := VerifyJwtToken(tokenString)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != "merge" {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("not a merge token")
+	}
+	sourceStr, _ := claims["merge_source"].(string)
+	targetStr, _ := claims["merge_target"].(string)
+	source, err := uuid.Parse(sourceStr)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid merge source")
+	}
+	target, err := uuid.Parse(targetStr)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid merge target")
+	}
+	return source, target, nil
+}
+
+// NewRefreshToken returns an opaque random refresh token, only its hash is stored
+func NewRefreshToken() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // Helper function to return a string or nil
 func GetNullableString(s string) interface{} {
 	if s == "" {
@@ -0,0 +1,54 @@
+package pkg
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrimaryLanguage normalizes a language tag to its lower case primary subtag, "en-GB" becomes "en"
+func PrimaryLanguage(code string) string {
+	primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(code)), "-")
+	primary, _, _ = strings.Cut(primary, "_")
+	return primary
+}
+
+// ParseAcceptLanguage returns the primary subtags of an Accept-Language header ordered by quality,
+// the wildcard and languages with q=0 are dropped
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		language string
+		quality  float64
+	}
+	var entries []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		language := PrimaryLanguage(tag)
+		if language == "" || language == "*" {
+			continue
+		}
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality <= 0 {
+			continue
+		}
+		entries = append(entries, weighted{language: language, quality: quality})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].quality > entries[j].quality })
+
+	seen := make(map[string]bool, len(entries))
+	languages := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !seen[entry.language] {
+			seen[entry.language] = true
+			languages = append(languages, entry.language)
+		}
+	}
+	return languages
+}
@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+)
+
+// MailSender delivers transactional mail such as sign in codes
+type MailSender interface {
+	Send(to, subject, body string) error
+}
+
+// LogMailSender is the local stub, it writes every mail to the debug log instead of sending it
+type LogMailSender struct{}
+
+func (LogMailSender) Send(to, subject, body string) error {
+	log.Debugf("Mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+// SmtpMailSender sends plain text mail through an SMTP relay, authenticating when a username is set
+type SmtpMailSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func (s *SmtpMailSender) Send(to, subject, body string) error {
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid recipient %q", to)
+	}
+	message := "From: " + s.from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" + body
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{to}, []byte(message))
+}
+
+// MailSenderInit picks the sender from MAIL_SENDER: smtp sends through SMTP_HOST, log writes mail to the
+// debug log for local development. Nil when unset, features that mail are then disabled
+func MailSenderInit() MailSender {
+	switch os.Getenv("MAIL_SENDER") {
+	case "smtp":
+		host, from := os.Getenv("SMTP_HOST"), os.Getenv("MAIL_FROM")
+		if host == "" || from == "" {
+			log.Error("MAIL_SENDER is smtp but SMTP_HOST or MAIL_FROM is not set, mail disabled")
+			return nil
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		sender := &SmtpMailSender{addr: net.JoinHostPort(host, port), from: from}
+		if username := os.Getenv("SMTP_USERNAME"); username != "" {
+			sender.auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+		}
+		return sender
+	case "log":
+		log.Warn("MAIL_SENDER is log, mail is written to the debug log and never delivered")
+		return LogMailSender{}
+	case "":
+		log.Warn("MAIL_SENDER is not set, mail disabled")
+		return nil
+	default:
+		log.Error("Unknown MAIL_SENDER ", os.Getenv("MAIL_SENDER"), ", mail disabled")
+		return nil
+	}
+}
@@ -0,0 +1,10 @@
+package pkg
+
+// CheckedMul multiplies a and b, ok is false when the product does not fit the type
+func CheckedMul[T int | int64](a, b T) (T, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	return c, c/b == a && (a < 0) == (b < 0) == (c > 0)
+}
@@ -0,0 +1,31 @@
+package pkg
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCheckedMul(t *testing.T) {
+	tests := []struct {
+		a, b int64
+		want int64
+		ok   bool
+	}{
+		{0, math.MaxInt64, 0, true},
+		{7, 6, 42, true},
+		{-7, 6, -42, true},
+		{-7, -6, 42, true},
+		{math.MaxInt64, 1, math.MaxInt64, true},
+		{math.MaxInt64, 2, 0, false},
+		{1 << 32, 1 << 32, 0, false},
+		{math.MinInt64, -1, 0, false},
+		{-1, math.MinInt64, 0, false},
+		{1_000_000_000_000, 10_000_000, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := CheckedMul(tt.a, tt.b)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("CheckedMul(%d, %d) = %d, %t, want %d, %t", tt.a, tt.b, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	rateLimitBucket = "rate_limits"
+	// Keys idle this long are dropped by NATS, a bucket refilled in less time is full by then anyway
+	rateLimitKeyTtl = 24 * time.Hour
+	// Concurrent takes on one key retry their compare and swap this often before giving up on NATS
+	rateLimitCasAttempts = 3
+	// Past this many buckets in memory the full ones are dropped, then the ones closest to full until a
+	// tenth of the room is free again
+	rateLimitMemorySize = 100_000
+)
+
+var rateLimitKeyInvalid = regexp.MustCompile(`[^-/_=.a-zA-Z0-9]`)
+
+// RateLimit is a token bucket: up to Burst requests at once, refilled at Burst requests per Period
+type RateLimit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseRateLimit reads a limit written as "<burst>/<period>", e.g. "20/1m"
+func ParseRateLimit(value string) (RateLimit, error) {
+	burstStr, periodStr, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("rate limit %q is not <burst>/<period>", value)
+	}
+	burst, err := strconv.Atoi(burstStr)
+	if err != nil || burst <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q has a bad burst", value)
+	}
+	period, err := time.ParseDuration(periodStr)
+	if err != nil || period <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q has a bad period", value)
+	}
+	return RateLimit{Burst: burst, Period: period}, nil
+}
+
+func (r RateLimit) String() string {
+	return fmt.Sprintf("%d/%s", r.Burst, r.Period)
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // Until the next token, zero when allowed
+	ResetAfter time.Duration // Until the bucket is full again
+}
+
+// RateLimiter takes a token from the bucket of key, creating it full on first use
+type RateLimiter interface {
+	Take(key string, limit RateLimit) (RateLimitResult, error)
+}
+
+type rateBucket struct {
+	Tokens    float64 `json:"t"`
+	UpdatedAt int64   `json:"u"` // Unix nanoseconds
+}
+
+// take refills the bucket for the time passed and takes a token. A denied take leaves the bucket as it
+// was, so it does not need to be stored
+func (b rateBucket) take(limit RateLimit, now time.Time) (rateBucket, RateLimitResult, bool) {
+	perToken := limit.Period / time.Duration(limit.Burst)
+	tokens := float64(limit.Burst)
+	if b.UpdatedAt != 0 {
+		elapsed := now.Sub(time.Unix(0, b.UpdatedAt))
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		tokens = math.Min(float64(limit.Burst), b.Tokens+float64(elapsed)/float64(perToken))
+	}
+	if tokens < 1 {
+		return b, RateLimitResult{
+			RetryAfter: time.Duration((1 - tokens) * float64(perToken)),
+			ResetAfter: time.Duration((float64(limit.Burst) - tokens) * float64(perToken)),
+		}, false
+	}
+	tokens--
+	return rateBucket{Tokens: tokens, UpdatedAt: now.UnixNano()}, RateLimitResult{
+		Allowed:    true,
+		Remaining:  int(tokens),
+		ResetAfter: time.Duration((float64(limit.Burst) - tokens) * float64(perToken)),
+	}, true
+}
+
+// MemoryRateLimiter keeps the buckets of this instance only
+type MemoryRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]memoryBucket
+	size    int
+}
+
+type memoryBucket struct {
+	rateBucket
+	fullAt time.Time
+}
+
+func (m *MemoryRateLimiter) Take(key string, limit RateLimit) (RateLimitResult, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.buckets) >= m.size {
+		m.pruneLocked(now)
+	}
+	bucket, result, changed := m.buckets[key].take(limit, now)
+	if changed {
+		m.buckets[key] = memoryBucket{rateBucket: bucket, fullAt: now.Add(result.ResetAfter)}
+	}
+	return result, nil
+}
+
+// pruneLocked drops the buckets that have refilled, a missing bucket counts as full. When that frees too
+// little it drops the buckets that refill soonest, a flood of fresh keys from rotating addresses goes
+// first while the drained buckets of throttled clients are kept
+func (m *MemoryRateLimiter) pruneLocked(now time.Time) {
+	for key, bucket := range m.buckets {
+		if !now.Before(bucket.fullAt) {
+			delete(m.buckets, key)
+		}
+	}
+	keep := m.size - m.size/10
+	if len(m.buckets) <= keep {
+		return
+	}
+	keys := make([]string, 0, len(m.buckets))
+	for key := range m.buckets {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return m.buckets[keys[i]].fullAt.Before(m.buckets[keys[j]].fullAt) })
+	for _, key := range keys[:len(keys)-keep] {
+		delete(m.buckets, key)
+	}
+}
+
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{buckets: make(map[string]memoryBucket), size: rateLimitMemorySize}
+}
+
+// NatsRateLimiter shares the buckets between instances through a NATS key value bucket, updates are
+// compare and swap on the revision. While NATS fails the limits of this instance apply instead
+type NatsRateLimiter struct {
+	kv       nats.KeyValue
+	fallback *MemoryRateLimiter
+}
+
+func (n *NatsRateLimiter) Take(key string, limit RateLimit) (RateLimitResult, error) {
+	key = rateLimitKeyInvalid.ReplaceAllString(key, "_")
+	for attempt := 0; attempt < rateLimitCasAttempts; attempt++ {
+		result, err := n.tryTake(key, limit)
+		if err == nil {
+			return result, nil
+		}
+		if !errors.Is(err, nats.ErrKeyExists) && !isWrongLastSequence(err) {
+			log.Warn("Rate limit over NATS failed, limiting locally: ", err)
+			break
+		}
+	}
+	return n.fallback.Take(key, limit)
+}
+
+func (n *NatsRateLimiter) tryTake(key string, limit RateLimit) (RateLimitResult, error) {
+	var bucket rateBucket
+	var revision uint64
+	entry, err := n.kv.Get(key)
+	switch {
+	case errors.Is(err, nats.ErrKeyNotFound):
+	case err != nil:
+		return RateLimitResult{}, err
+	default:
+		revision = entry.Revision()
+		if err := json.Unmarshal(entry.Value(), &bucket); err != nil {
+			// Starting over with a full bucket beats failing on a broken value forever
+			bucket = rateBucket{}
+		}
+	}
+
+	bucket, result, changed := bucket.take(limit, time.Now())
+	if !changed {
+		return result, nil
+	}
+	data, err := json.Marshal(bucket)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if revision == 0 {
+		_, err = n.kv.Create(key, data)
+	} else {
+		_, err = n.kv.Update(key, data, revision)
+	}
+	return result, err
+}
+
+func isWrongLastSequence(err error) bool {
+	var apiErr *nats.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode == nats.JSErrCodeStreamWrongLastSequence
+}
+
+func NewNatsRateLimiter(nc *nats.Conn) (*NatsRateLimiter, error) {
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, err
+	}
+	kv, err := js.KeyValue(rateLimitBucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:  rateLimitBucket,
+			History: 1,
+			TTL:     rateLimitKeyTtl,
+			Storage: nats.MemoryStorage,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &NatsRateLimiter{kv: kv, fallback: NewMemoryRateLimiter()}, nil
+}
+
+// RateLimiterInit picks the backend from RATE_LIMIT_BACKEND: memory by default, nats to share the
+// limits between instances
+func RateLimiterInit(nc *nats.Conn) RateLimiter {
+	if os.Getenv("RATE_LIMIT_BACKEND") != "nats" {
+		return NewMemoryRateLimiter()
+	}
+	if nc == nil {
+		log.Warn("NATS is not connected, rate limits are kept in memory")
+		return NewMemoryRateLimiter()
+	}
+	limiter, err := NewNatsRateLimiter(nc)
+	if err != nil {
+		log.Error("Rate limit bucket: ", err, ", rate limits are kept in memory")
+		return NewMemoryRateLimiter()
+	}
+	return limiter
+}
@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    RateLimit
+		wantErr bool
+	}{
+		{value: "20/1m", want: RateLimit{Burst: 20, Period: time.Minute}},
+		{value: " 5/10m ", want: RateLimit{Burst: 5, Period: 10 * time.Minute}},
+		{value: "1/1h30m", want: RateLimit{Burst: 1, Period: 90 * time.Minute}},
+		{value: "20", wantErr: true},
+		{value: "", wantErr: true},
+		{value: "/1m", wantErr: true},
+		{value: "0/1m", wantErr: true},
+		{value: "-1/1m", wantErr: true},
+		{value: "x/1m", wantErr: true},
+		{value: "20/", wantErr: true},
+		{value: "20/60", wantErr: true},
+		{value: "20/0s", wantErr: true},
+		{value: "20/-1m", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseRateLimit(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateBucketTake(t *testing.T) {
+	// A token every second
+	limit := RateLimit{Burst: 10, Period: 10 * time.Second}
+	now := time.Unix(1_700_000_000, 0)
+	at := func(d time.Duration) int64 { return now.Add(d).UnixNano() }
+
+	tests := []struct {
+		name       string
+		bucket     rateBucket
+		want       RateLimitResult
+		wantTokens float64
+	}{
+		{
+			name:       "new bucket starts full",
+			want:       RateLimitResult{Allowed: true, Remaining: 9, ResetAfter: time.Second},
+			wantTokens: 9,
+		},
+		{
+			name:   "empty",
+			bucket: rateBucket{Tokens: 0, UpdatedAt: at(0)},
+			want:   RateLimitResult{RetryAfter: time.Second, ResetAfter: 10 * time.Second},
+		},
+		{
+			name:   "half a token",
+			bucket: rateBucket{Tokens: 0.5, UpdatedAt: at(0)},
+			want:   RateLimitResult{RetryAfter: 500 * time.Millisecond, ResetAfter: 9500 * time.Millisecond},
+		},
+		{
+			name:       "refilled for the time passed",
+			bucket:     rateBucket{Tokens: 0, UpdatedAt: at(-3 * time.Second)},
+			want:       RateLimitResult{Allowed: true, Remaining: 2, ResetAfter: 8 * time.Second},
+			wantTokens: 2,
+		},
+		{
+			name:       "refill stops at the burst",
+			bucket:     rateBucket{Tokens: 5, UpdatedAt: at(-time.Hour)},
+			want:       RateLimitResult{Allowed: true, Remaining: 9, ResetAfter: time.Second},
+			wantTokens: 9,
+		},
+		{
+			name:       "clock going back refills nothing",
+			bucket:     rateBucket{Tokens: 2, UpdatedAt: at(5 * time.Second)},
+			want:       RateLimitResult{Allowed: true, Remaining: 1, ResetAfter: 9 * time.Second},
+			wantTokens: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, result, changed := tt.bucket.take(limit, now)
+			if result != tt.want {
+				t.Errorf("result %+v, want %+v", result, tt.want)
+			}
+			if changed != tt.want.Allowed {
+				t.Errorf("changed %v, want %v", changed, tt.want.Allowed)
+			}
+			if !changed {
+				if bucket != tt.bucket {
+					t.Errorf("denied take changed the bucket to %+v", bucket)
+				}
+				return
+			}
+			if bucket.Tokens != tt.wantTokens || bucket.UpdatedAt != now.UnixNano() {
+				t.Errorf("bucket %+v, want %v tokens at %d", bucket, tt.wantTokens, now.UnixNano())
+			}
+		})
+	}
+
+	t.Run("burst then denied", func(t *testing.T) {
+		var bucket rateBucket
+		for i := 0; i < limit.Burst; i++ {
+			var allowed bool
+			if bucket, _, allowed = bucket.take(limit, now); !allowed {
+				t.Fatalf("take %d denied within the burst", i+1)
+			}
+		}
+		if _, _, allowed := bucket.take(limit, now); allowed {
+			t.Fatal("take past the burst allowed")
+		}
+	})
+}
+
+func TestMemoryRateLimiterPrune(t *testing.T) {
+	limiter := NewMemoryRateLimiter()
+	limiter.size = 100
+	strict := RateLimit{Burst: 1, Period: time.Hour}
+	loose := RateLimit{Burst: 1000, Period: time.Second}
+
+	// A throttled client first, then a flood of fresh keys that refill within milliseconds
+	if result, _ := limiter.Take("email_code:email:player@example.com", strict); !result.Allowed {
+		t.Fatal("first take denied")
+	}
+	for i := 0; i < 1000; i++ {
+		limiter.Take(fmt.Sprintf("auth:ip:10.0.%d.%d", i/256, i%256), loose)
+	}
+
+	if len(limiter.buckets) >= limiter.size {
+		t.Fatalf("%d buckets kept, limit is %d", len(limiter.buckets), limiter.size)
+	}
+	if result, _ := limiter.Take("email_code:email:player@example.com", strict); result.Allowed {
+		t.Fatal("the flood reset the bucket of a throttled client")
+	}
+}
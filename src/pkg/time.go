@@ -0,0 +1,24 @@
+package pkg
+
+import (
+	"fmt"
+	"time"
+)
+
+// StartOfDay returns midnight UTC of the day t falls in, game days are UTC based
+func StartOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// StartOfWeek returns Monday midnight UTC of the ISO week t falls in
+func StartOfWeek(t time.Time) time.Time {
+	day := StartOfDay(t)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// WeekKey names the ISO week t falls in, e.g. 2024-W07
+func WeekKey(t time.Time) string {
+	year, week := t.UTC().ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
@@ -0,0 +1,186 @@
+package pkg
+
+import (
+	"bytes"
+	"crazyfarmbackend/src/domain/dto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrTonProofInvalid = errors.New("ton proof is invalid")
+
+const (
+	tonProofPrefix     = "ton-proof-item-v2/"
+	tonConnectPrefix   = "ton-connect"
+	tonPayloadMacLabel = "ton-proof-payload"
+	// Wallets sign a little ahead of our clock now and then
+	tonProofClockSkew = time.Minute
+)
+
+// Bit offsets of the public key in the data of the standard wallets: v2, v3 and v4, v5
+var tonWalletKeyOffsets = []int{32, 64, 65}
+
+// NewTonProofPayload returns a challenge for a wallet to sign. It carries its own expiry and a MAC under
+// JWT_KEY, so forged or expired payloads are rejected without a lookup. Callers store it to make it
+// single use
+func NewTonProofPayload(ttl time.Duration) (string, time.Time, error) {
+	expiresAt := time.Now().Add(ttl)
+	payload := make([]byte, 16)
+	if _, err := rand.Read(payload[:8]); err != nil {
+		return "", time.Time{}, err
+	}
+	binary.BigEndian.PutUint64(payload[8:], uint64(expiresAt.Unix()))
+	return hex.EncodeToString(append(payload, tonPayloadMac(payload)...)), expiresAt, nil
+}
+
+func tonPayloadMac(payload []byte) []byte {
+	mac := hmac.New(sha256.New, jwtKey)
+	mac.Write([]byte(tonPayloadMacLabel))
+	mac.Write(payload)
+	return mac.Sum(nil)[:16]
+}
+
+func checkTonProofPayload(payload string, now time.Time) error {
+	raw, err := hex.DecodeString(payload)
+	if err != nil || len(raw) != 32 {
+		return fmt.Errorf("%w: unknown payload", ErrTonProofInvalid)
+	}
+	if !hmac.Equal(raw[16:], tonPayloadMac(raw[:16])) {
+		return fmt.Errorf("%w: unknown payload", ErrTonProofInvalid)
+	}
+	if now.Unix() > int64(binary.BigEndian.Uint64(raw[8:16])) {
+		return fmt.Errorf("%w: payload expired", ErrTonProofInvalid)
+	}
+	return nil
+}
+
+// VerifyTonProof checks a TON Connect ton_proof: the payload was issued by us and is still valid, the
+// domain is one of ours, the address is derived from the state init holding the public key, and the
+// signature of that key covers all of it. It returns the raw address, "workchain:hex"
+func VerifyTonProof(proof dto.TonProof, domains []string, network string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	if network != "" && proof.Network != network {
+		return "", fmt.Errorf("%w: wrong network", ErrTonProofInvalid)
+	}
+	if err := checkTonProofPayload(proof.Proof.Payload, now); err != nil {
+		return "", err
+	}
+	domain := proof.Proof.Domain
+	if int(domain.LengthBytes) != len(domain.Value) || !containsString(domains, domain.Value) {
+		return "", fmt.Errorf("%w: unknown domain", ErrTonProofInvalid)
+	}
+	signedAt := time.Unix(proof.Proof.Timestamp, 0)
+	if signedAt.Before(now.Add(-ttl)) || signedAt.After(now.Add(tonProofClockSkew)) {
+		return "", fmt.Errorf("%w: proof expired", ErrTonProofInvalid)
+	}
+
+	workchain, addressHash, err := parseTonRawAddress(proof.Address)
+	if err != nil {
+		return "", err
+	}
+	publicKey, err := hex.DecodeString(proof.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return "", fmt.Errorf("%w: bad public key", ErrTonProofInvalid)
+	}
+	if err := checkTonStateInit(proof.Proof.StateInit, addressHash, publicKey); err != nil {
+		return "", err
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(proof.Proof.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return "", fmt.Errorf("%w: bad signature", ErrTonProofInvalid)
+	}
+	message := new(bytes.Buffer)
+	message.WriteString(tonProofPrefix)
+	_ = binary.Write(message, binary.BigEndian, workchain)
+	message.Write(addressHash)
+	_ = binary.Write(message, binary.LittleEndian, domain.LengthBytes)
+	message.WriteString(domain.Value)
+	_ = binary.Write(message, binary.LittleEndian, uint64(proof.Proof.Timestamp))
+	message.WriteString(proof.Proof.Payload)
+	messageHash := sha256.Sum256(message.Bytes())
+
+	full := append([]byte{0xff, 0xff}, tonConnectPrefix...)
+	full = append(full, messageHash[:]...)
+	fullHash := sha256.Sum256(full)
+	if !ed25519.Verify(publicKey, fullHash[:], signature) {
+		return "", fmt.Errorf("%w: signature mismatch", ErrTonProofInvalid)
+	}
+	return fmt.Sprintf("%d:%s", workchain, hex.EncodeToString(addressHash)), nil
+}
+
+func parseTonRawAddress(address string) (int32, []byte, error) {
+	workchainStr, hashStr, ok := strings.Cut(address, ":")
+	if !ok {
+		return 0, nil, fmt.Errorf("%w: address must be in raw form", ErrTonProofInvalid)
+	}
+	workchain, err := strconv.ParseInt(workchainStr, 10, 32)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: bad workchain", ErrTonProofInvalid)
+	}
+	hash, err := hex.DecodeString(hashStr)
+	if err != nil || len(hash) != 32 {
+		return 0, nil, fmt.Errorf("%w: bad address hash", ErrTonProofInvalid)
+	}
+	return int32(workchain), hash, nil
+}
+
+// checkTonStateInit verifies that the state init hashes to the address and that its data holds the public key
+func checkTonStateInit(stateInit string, addressHash, publicKey []byte) error {
+	boc, err := base64.StdEncoding.DecodeString(stateInit)
+	if err != nil {
+		return fmt.Errorf("%w: bad state init", ErrTonProofInvalid)
+	}
+	root, err := parseBoc(boc)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTonProofInvalid, err)
+	}
+	if !bytes.Equal(root.hash, addressHash) {
+		return fmt.Errorf("%w: state init does not match the address", ErrTonProofInvalid)
+	}
+
+	// StateInit: split_depth:(Maybe (## 5)) special:(Maybe TickTock) code:(Maybe ^Cell) data:(Maybe ^Cell) ...
+	pos := 0
+	if root.bit(pos) {
+		pos += 5
+	}
+	pos++
+	if root.bit(pos) {
+		pos += 2
+	}
+	pos++
+	dataRef := 0
+	if root.bit(pos) {
+		dataRef++
+	}
+	pos++
+	if !root.bit(pos) || dataRef >= len(root.refs) {
+		return fmt.Errorf("%w: state init has no data", ErrTonProofInvalid)
+	}
+	data := root.refs[dataRef]
+	for _, offset := range tonWalletKeyOffsets {
+		if key, ok := data.bytesAt(offset, ed25519.PublicKeySize); ok && bytes.Equal(key, publicKey) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: public key does not belong to the wallet", ErrTonProofInvalid)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
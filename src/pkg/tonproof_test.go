@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"bytes"
+	"crazyfarmbackend/src/domain/dto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+const testTonDomain = "farm.example.com"
+
+// testWallet is a v4 style wallet: its data cell holds seqno, subwallet id and the public key at bit 64
+type testWallet struct {
+	publicKey  ed25519.PublicKey
+	privateKey ed25519.PrivateKey
+	stateInit  *testCell
+}
+
+func newTestWallet(t *testing.T) testWallet {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 41)
+	binary.BigEndian.PutUint32(data[4:], 698983191)
+	copy(data[8:], publicKey)
+	code := newTestCell([]byte("wallet code"), 88)
+	// No split depth, no special, code and data present, no library: 0 0 1 1 0
+	stateInit := newTestCell([]byte{0x30}, 5, code, newTestCell(data, 321))
+	return testWallet{publicKey: publicKey, privateKey: privateKey, stateInit: stateInit}
+}
+
+func (w testWallet) address() string {
+	return "0:" + hex.EncodeToString(w.stateInit.hash())
+}
+
+// sign builds the ton_proof a wallet returns, following the TON Connect signature scheme
+func (w testWallet) sign(address, domain string, timestamp int64, payload string) dto.TonProof {
+	workchain, hash, _ := parseTonRawAddress(address)
+	message := new(bytes.Buffer)
+	message.WriteString("ton-proof-item-v2/")
+	_ = binary.Write(message, binary.BigEndian, workchain)
+	message.Write(hash)
+	_ = binary.Write(message, binary.LittleEndian, uint32(len(domain)))
+	message.WriteString(domain)
+	_ = binary.Write(message, binary.LittleEndian, uint64(timestamp))
+	message.WriteString(payload)
+	messageHash := sha256.Sum256(message.Bytes())
+	fullHash := sha256.Sum256(append(append([]byte{0xff, 0xff}, "ton-connect"...), messageHash[:]...))
+
+	return dto.TonProof{
+		Address:   address,
+		Network:   "-239",
+		PublicKey: hex.EncodeToString(w.publicKey),
+		Proof: dto.TonProofProof{
+			Timestamp: timestamp,
+			Domain:    dto.TonProofDomain{LengthBytes: uint32(len(domain)), Value: domain},
+			Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(w.privateKey, fullHash[:])),
+			Payload:   payload,
+			StateInit: base64.StdEncoding.EncodeToString(serializeBoc(w.stateInit, true)),
+		},
+	}
+}
+
+func TestVerifyTonProof(t *testing.T) {
+	const ttl = 15 * time.Minute
+	wallet, other := newTestWallet(t), newTestWallet(t)
+	payload, _, err := NewTonProofPayload(ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiredPayload, _, err := NewTonProofPayload(-time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	valid := func() dto.TonProof {
+		return wallet.sign(wallet.address(), testTonDomain, now, payload)
+	}
+
+	tests := []struct {
+		name  string
+		proof dto.TonProof
+		valid bool
+	}{
+		{"valid", valid(), true},
+		{"wrong network", func() dto.TonProof {
+			proof := valid()
+			proof.Network = "-3"
+			return proof
+		}(), false},
+		{"unknown domain", wallet.sign(wallet.address(), "evil.example.com", now, payload), false},
+		{"domain length mismatch", func() dto.TonProof {
+			proof := valid()
+			proof.Proof.Domain.LengthBytes++
+			return proof
+		}(), false},
+		{"signed too long ago", wallet.sign(wallet.address(), testTonDomain, now-int64(ttl.Seconds())-60, payload), false},
+		{"signed in the future", wallet.sign(wallet.address(), testTonDomain, now+600, payload), false},
+		{"payload expired", wallet.sign(wallet.address(), testTonDomain, now, expiredPayload), false},
+		{"payload forged", wallet.sign(wallet.address(), testTonDomain, now, hex.EncodeToString(make([]byte, 32))), false},
+		{"payload not hex", wallet.sign(wallet.address(), testTonDomain, now, "payload"), false},
+		{"address not raw", func() dto.TonProof {
+			proof := valid()
+			proof.Address = "EQ" + proof.Address[2:]
+			return proof
+		}(), false},
+		{"state init of another wallet", func() dto.TonProof {
+			proof := valid()
+			proof.Proof.StateInit = base64.StdEncoding.EncodeToString(serializeBoc(other.stateInit, true))
+			return proof
+		}(), false},
+		{"public key not in the wallet", func() dto.TonProof {
+			proof := valid()
+			proof.PublicKey = hex.EncodeToString(other.publicKey)
+			return proof
+		}(), false},
+		{"state init not a bag of cells", func() dto.TonProof {
+			proof := valid()
+			proof.Proof.StateInit = base64.StdEncoding.EncodeToString([]byte("state init"))
+			return proof
+		}(), false},
+		{"signed by another key", func() dto.TonProof {
+			proof := valid()
+			proof.Proof.Signature = other.sign(wallet.address(), testTonDomain, now, payload).Proof.Signature
+			return proof
+		}(), false},
+		{"signed for another workchain", func() dto.TonProof {
+			proof := wallet.sign("-1:"+wallet.address()[2:], testTonDomain, now, payload)
+			proof.Address = wallet.address()
+			return proof
+		}(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, err := VerifyTonProof(tt.proof, []string{testTonDomain}, "-239", ttl)
+			if !tt.valid {
+				if !errors.Is(err, ErrTonProofInvalid) {
+					t.Fatalf("error %v, want ErrTonProofInvalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if address != wallet.address() {
+				t.Fatalf("address %s, want %s", address, wallet.address())
+			}
+		})
+	}
+}
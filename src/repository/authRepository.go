@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/domain/dao"
+	"crypto/subtle"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+const (
+	// Wrong guesses allowed before a code has to be requested again, resends within the code window
+	// do not give new guesses
+	emailCodeMaxAttempts = 5
+	// Per address caps on codes sent and wrong codes tried
+	emailCodesPerHour    = 5
+	emailCodesPerDay     = 20
+	emailAttemptsPerHour = 10
+	emailAttemptsPerDay  = 30
+	// Postgres advisory lock class serializing the sends and sign ins of one address
+	emailAuthLockClass = 0x656d // "em"
+)
+
+const (
+	emailEventSend    = "send"
+	emailEventAttempt = "attempt"
+)
+
+var (
+	ErrEmailCodeCooldown    = errors.New("email code was sent recently")
+	ErrEmailCodeInvalid     = errors.New("email code is invalid or expired")
+	ErrEmailCodeLimit       = errors.New("too many email codes or attempts for the address")
+	ErrTonProofNonceInvalid = errors.New("ton proof payload is unknown or was already used")
+)
+
+type AuthRepository interface {
+	SaveEmailCode(code dao.EmailCode, cooldown time.Duration) error
+	ConsumeEmailCode(email, codeHash string) error
+	SaveTonProofNonce(payload string, expiresAt time.Time) error
+	ConsumeTonProofNonce(payload string) error
+}
+
+type AuthRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func lockEmail(tx *gorm.DB, email string) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(?, hashtext(?))", emailAuthLockClass, email).Error
+}
+
+// emailLimitReached reports whether the address used up the hourly or daily cap of one event kind
+func emailLimitReached(tx *gorm.DB, email, kind string, perHour, perDay int64, now time.Time) (bool, error) {
+	var counts struct {
+		Hour int64
+		Day  int64
+	}
+	err := tx.Model(&dao.EmailAuthEvent{}).
+		Select("COUNT(*) FILTER (WHERE created_at > ?) AS hour, COUNT(*) AS day", now.Add(-time.Hour)).
+		Where("email = ? AND kind = ? AND created_at > ?", email, kind, now.Add(-24*time.Hour)).
+		Scan(&counts).Error
+	if err != nil {
+		return false, err
+	}
+	return counts.Hour >= perHour || counts.Day >= perDay, nil
+}
+
+// SaveEmailCode stores a new code for the address, replacing the previous one unless that was sent
+// less than cooldown ago. The wrong guesses of an unexpired previous code carry over, and the address
+// is refused new codes while they or the hourly and daily caps are used up
+func (a *AuthRepositoryImpl) SaveEmailCode(code dao.EmailCode, cooldown time.Duration) error {
+	var result error
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockEmail(tx, code.Email); err != nil {
+			return err
+		}
+		err := tx.Where("email = ? AND created_at < ?", code.Email, code.SentAt.Add(-24*time.Hour)).
+			Delete(&dao.EmailAuthEvent{}).Error
+		if err != nil {
+			return err
+		}
+
+		var previous []dao.EmailCode
+		if err := tx.Where("email = ?", code.Email).Limit(1).Find(&previous).Error; err != nil {
+			return err
+		}
+		if len(previous) > 0 {
+			if previous[0].SentAt.After(code.SentAt.Add(-cooldown)) {
+				result = ErrEmailCodeCooldown
+				return nil
+			}
+			if code.SentAt.Before(previous[0].ExpiresAt) {
+				code.Attempts = previous[0].Attempts
+			}
+		}
+		if code.Attempts >= emailCodeMaxAttempts {
+			result = ErrEmailCodeLimit
+			return nil
+		}
+		for _, limit := range []struct {
+			kind            string
+			perHour, perDay int64
+		}{
+			{emailEventSend, emailCodesPerHour, emailCodesPerDay},
+			{emailEventAttempt, emailAttemptsPerHour, emailAttemptsPerDay},
+		} {
+			reached, err := emailLimitReached(tx, code.Email, limit.kind, limit.perHour, limit.perDay, code.SentAt)
+			if err != nil {
+				return err
+			}
+			if reached {
+				result = ErrEmailCodeLimit
+				return nil
+			}
+		}
+
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "email"}},
+			DoUpdates: clause.AssignmentColumns([]string{"code_hash", "attempts", "expires_at", "sent_at"}),
+		}).Create(&code).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&dao.EmailAuthEvent{Email: code.Email, Kind: emailEventSend, CreatedAt: code.SentAt}).Error
+	})
+	if err != nil {
+		log.Error("Error saving email code: ", err)
+		return err
+	}
+	return result
+}
+
+// ConsumeEmailCode deletes the code of the address when codeHash matches it. A mismatch counts as an
+// attempt, and codes that expired or ran out of attempts never match
+func (a *AuthRepositoryImpl) ConsumeEmailCode(email, codeHash string) error {
+	var result error
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockEmail(tx, email); err != nil {
+			return err
+		}
+		var code dao.EmailCode
+		err := tx.Where("email = ?", email).First(&code).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			result = ErrEmailCodeInvalid
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if now.After(code.ExpiresAt) || code.Attempts >= emailCodeMaxAttempts {
+			result = ErrEmailCodeInvalid
+			return nil
+		}
+		reached, err := emailLimitReached(tx, email, emailEventAttempt, emailAttemptsPerHour, emailAttemptsPerDay, now)
+		if err != nil {
+			return err
+		}
+		if reached {
+			result = ErrEmailCodeLimit
+			return nil
+		}
+		if subtle.ConstantTimeCompare([]byte(code.CodeHash), []byte(codeHash)) != 1 {
+			// Committed even though the sign in fails, so guesses are really counted
+			result = ErrEmailCodeInvalid
+			if err := tx.Model(&code).Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+				return err
+			}
+			return tx.Create(&dao.EmailAuthEvent{Email: email, Kind: emailEventAttempt, CreatedAt: now}).Error
+		}
+		return tx.Delete(&code).Error
+	})
+	if err != nil {
+		log.Error("Error consuming email code: ", err)
+		return err
+	}
+	return result
+}
+
+// SaveTonProofNonce remembers a payload handed out to a wallet until it expires, expired ones are
+// dropped on the way
+func (a *AuthRepositoryImpl) SaveTonProofNonce(payload string, expiresAt time.Time) error {
+	if err := a.db.Where("expires_at < ?", time.Now()).Delete(&dao.TonProofNonce{}).Error; err != nil {
+		log.Error("Error deleting expired ton proof payloads: ", err)
+		return err
+	}
+	if err := a.db.Create(&dao.TonProofNonce{Payload: payload, ExpiresAt: expiresAt}).Error; err != nil {
+		log.Error("Error saving ton proof payload: ", err)
+		return err
+	}
+	return nil
+}
+
+// ConsumeTonProofNonce deletes an unexpired payload, only one sign in can delete it so a proof can
+// not be replayed
+func (a *AuthRepositoryImpl) ConsumeTonProofNonce(payload string) error {
+	result := a.db.Where("payload = ? AND expires_at > ?", payload, time.Now()).Delete(&dao.TonProofNonce{})
+	if result.Error != nil {
+		log.Error("Error consuming ton proof payload: ", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTonProofNonceInvalid
+	}
+	return nil
+}
+
+func AuthRepositoryInit(db *gorm.DB) *AuthRepositoryImpl {
+	if err := db.AutoMigrate(&dao.EmailCode{}, &dao.EmailAuthEvent{}, &dao.TonProofNonce{}); err != nil {
+		log.Error("Error during AutoMigrate: ", err)
+	}
+	return &AuthRepositoryImpl{db: db}
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/domain/dao"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func testAuthRepository(t *testing.T) (*AuthRepositoryImpl, *gorm.DB) {
+	t.Helper()
+	db := testDB(t)
+	return AuthRepositoryInit(db), db
+}
+
+func testEmail(t *testing.T, db *gorm.DB) string {
+	t.Helper()
+	email := uuid.NewString() + "@example.com"
+	t.Cleanup(func() {
+		db.Where("email = ?", email).Delete(&dao.EmailCode{})
+		db.Where("email = ?", email).Delete(&dao.EmailAuthEvent{})
+	})
+	return email
+}
+
+func TestEmailCodeAttemptsSurviveResend(t *testing.T) {
+	repository, db := testAuthRepository(t)
+	email := testEmail(t, db)
+	send := func(hash string) error {
+		now := time.Now()
+		return repository.SaveEmailCode(dao.EmailCode{Email: email, CodeHash: hash, ExpiresAt: now.Add(10 * time.Minute), SentAt: now}, 0)
+	}
+
+	if err := send("first"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < emailCodeMaxAttempts-1; i++ {
+		if err := repository.ConsumeEmailCode(email, "wrong"); !errors.Is(err, ErrEmailCodeInvalid) {
+			t.Fatalf("guess %d: %v, want ErrEmailCodeInvalid", i, err)
+		}
+	}
+	if err := send("second"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.ConsumeEmailCode(email, "wrong"); !errors.Is(err, ErrEmailCodeInvalid) {
+		t.Fatalf("last guess: %v, want ErrEmailCodeInvalid", err)
+	}
+	// The resend did not reset the guesses, so the right code no longer matches
+	if err := repository.ConsumeEmailCode(email, "second"); !errors.Is(err, ErrEmailCodeInvalid) {
+		t.Fatalf("code after the guesses ran out: %v, want ErrEmailCodeInvalid", err)
+	}
+	if err := send("third"); !errors.Is(err, ErrEmailCodeLimit) {
+		t.Fatalf("resend after the guesses ran out: %v, want ErrEmailCodeLimit", err)
+	}
+}
+
+func TestEmailCodeCaps(t *testing.T) {
+	repository, db := testAuthRepository(t)
+	email := testEmail(t, db)
+	now := time.Now()
+	code := dao.EmailCode{Email: email, CodeHash: "code", ExpiresAt: now.Add(10 * time.Minute), SentAt: now}
+
+	tests := []struct {
+		name   string
+		events []dao.EmailAuthEvent
+		want   error
+	}{
+		{"first code", nil, nil},
+		{"codes this hour", repeatEvents(email, emailEventSend, now.Add(-time.Minute), emailCodesPerHour), ErrEmailCodeLimit},
+		{"codes today", repeatEvents(email, emailEventSend, now.Add(-2*time.Hour), emailCodesPerDay), ErrEmailCodeLimit},
+		{"codes yesterday", repeatEvents(email, emailEventSend, now.Add(-25*time.Hour), emailCodesPerDay), nil},
+		{"attempts this hour", repeatEvents(email, emailEventAttempt, now.Add(-time.Minute), emailAttemptsPerHour), ErrEmailCodeLimit},
+		{"attempts today", repeatEvents(email, emailEventAttempt, now.Add(-2*time.Hour), emailAttemptsPerDay), ErrEmailCodeLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.Where("email = ?", email).Delete(&dao.EmailCode{})
+			db.Where("email = ?", email).Delete(&dao.EmailAuthEvent{})
+			if len(tt.events) > 0 {
+				if err := db.Create(&tt.events).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := repository.SaveEmailCode(code, time.Minute); !errors.Is(err, tt.want) {
+				t.Fatalf("error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func repeatEvents(email, kind string, at time.Time, n int) []dao.EmailAuthEvent {
+	events := make([]dao.EmailAuthEvent, n)
+	for i := range events {
+		events[i] = dao.EmailAuthEvent{Email: email, Kind: kind, CreatedAt: at}
+	}
+	return events
+}
+
+func TestTonProofNonceSingleUse(t *testing.T) {
+	repository, db := testAuthRepository(t)
+	payload, expired := uuid.NewString(), uuid.NewString()
+	t.Cleanup(func() { db.Where("payload IN ?", []string{payload, expired}).Delete(&dao.TonProofNonce{}) })
+
+	if err := repository.SaveTonProofNonce(payload, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&dao.TonProofNonce{Payload: expired, ExpiresAt: time.Now().Add(-time.Second)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.ConsumeTonProofNonce(payload); err != nil {
+		t.Fatal(err)
+	}
+	for _, nonce := range []string{payload, expired, uuid.NewString()} {
+		if err := repository.ConsumeTonProofNonce(nonce); !errors.Is(err, ErrTonProofNonceInvalid) {
+			t.Errorf("consuming %s: %v, want ErrTonProofNonceInvalid", nonce, err)
+		}
+	}
+}
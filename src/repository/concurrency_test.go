@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"errors"
+	"gorm.io/gorm"
+	"sync"
+	"testing"
+)
+
+// TestPlantAndClaimConcurrent plants the same field and claims the same task cycle from many requests at
+// once, as double taps and replayed requests do. Exactly one of each may go through
+func TestPlantAndClaimConcurrent(t *testing.T) {
+	db := testDB(t)
+	CounterRepositoryInit(db)
+	WalletRepositoryInit(db)
+	tasks := TaskRepositoryInit(db, nil)
+	inventory := &InventoryRepositoryImpl{db: db}
+	rewards := RewardRepositoryInit(db)
+	user := testUser(t, db)
+	plant := constant.Plant("TEST_PLANT")
+
+	task, err := tasks.CreateTask(dao.Task{Name: "concurrency test", Reward: plant, RewardAmount: 1,
+		Type: constant.FRIENDS, Data: map[string]interface{}{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM task_completes WHERE task_id = ?", task.ID)
+		db.Delete(&dao.Task{}, "id = ?", task.ID)
+	})
+	const seeds, attempts, cycle = 5, 20, "2026-01-01"
+	if err := inventory.AdjustItemQuantity(user.ID, plant, seeds, constant.LEDGER_ADMIN_GRANT, "test"); err != nil {
+		t.Fatal(err)
+	}
+	bundle := []dao.RewardLine{{Kind: constant.REWARD_PLANT, Plant: plant, Amount: 3}}
+	refID := task.ID.String() + "@" + cycle
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := inventory.PlantField(user.ID, 1, plant); err != nil && !errors.Is(err, ErrFieldOccupied) {
+				errs <- err
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			// The claim as TaskService.CheckTask runs it
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if _, err := tasks.WithTx(tx).MarkClaimed(user.ID, task.ID, cycle); err != nil {
+					return err
+				}
+				return rewards.WithTx(tx).GrantRewards(user.ID, bundle, constant.LEDGER_TASK_CLAIM, constant.WALLET_TASK_CLAIM, refID)
+			})
+			if err != nil && !errors.Is(err, ErrTaskAlreadyClaimed) {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	var fields, completions, seedDebits, claimCredits int64
+	db.Model(&dao.UserField{}).Where("user_id = ? AND field_id = 1", user.ID).Count(&fields)
+	db.Model(&dao.TaskComplete{}).Where("user_id = ? AND task_id = ?", user.ID, task.ID).Count(&completions)
+	db.Model(&dao.InventoryLedger{}).Where("user_id = ? AND reason = ?", user.ID, constant.LEDGER_PLANT).Count(&seedDebits)
+	db.Model(&dao.InventoryLedger{}).Where("user_id = ? AND reason = ? AND ref_id = ?", user.ID, constant.LEDGER_TASK_CLAIM, refID).
+		Count(&claimCredits)
+	if fields != 1 || seedDebits != 1 {
+		t.Errorf("%d field rows and %d seed debits, want one of each", fields, seedDebits)
+	}
+	if completions != 1 || claimCredits != 1 {
+		t.Errorf("%d completions and %d reward ledger rows, want one of each", completions, claimCredits)
+	}
+	if quantity, _ := inventory.GetItemQuantity(user.ID, plant); quantity != seeds-1+3 {
+		t.Errorf("quantity %d, want %d", quantity, seeds-1+3)
+	}
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/pkg"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Hourly buckets older than this are pruned at startup, it bounds the longest recurring task cycle
+const counterBucketRetention = 35 * 24 * time.Hour
+
+type CounterRepository interface {
+	GetCounter(userId uuid.UUID, counter constant.Counter, key string) (int64, error)
+	GetCounterSince(userId uuid.UUID, counter constant.Counter, key string, since time.Time) (int64, error)
+	GetCounters(userId uuid.UUID, counter constant.Counter) (map[string]int64, error)
+	GetCounterBuckets(userId uuid.UUID, counter constant.Counter, since time.Time) ([]dao.UserCounterBucket, error)
+	RecordLogin(userId uuid.UUID) (int64, error)
+	GetLoginStreak(userId uuid.UUID) (int64, error)
+}
+
+type CounterRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (r *CounterRepositoryImpl) GetCounter(userId uuid.UUID, counter constant.Counter, key string) (int64, error) {
+	var counters []dao.UserCounter
+	err := r.db.Where("user_id = ? AND counter = ? AND key = ?", userId, counter, key).Limit(1).Find(&counters).Error
+	if err != nil || len(counters) == 0 {
+		return 0, err
+	}
+	return counters[0].Value, nil
+}
+
+// GetCounterSince sums the hourly buckets from the hour since falls in
+func (r *CounterRepositoryImpl) GetCounterSince(userId uuid.UUID, counter constant.Counter, key string, since time.Time) (int64, error) {
+	var value int64
+	err := r.db.Model(&dao.UserCounterBucket{}).
+		Select("COALESCE(SUM(value), 0)").
+		Where("user_id = ? AND counter = ? AND key = ? AND hour >= ?", userId, counter, key, since.UTC().Truncate(time.Hour)).
+		Scan(&value).Error
+	return value, err
+}
+
+// GetCounters returns the lifetime values of every key of counter
+func (r *CounterRepositoryImpl) GetCounters(userId uuid.UUID, counter constant.Counter) (map[string]int64, error) {
+	var counters []dao.UserCounter
+	if err := r.db.Where("user_id = ? AND counter = ?", userId, counter).Find(&counters).Error; err != nil {
+		return nil, err
+	}
+	values := make(map[string]int64, len(counters))
+	for _, userCounter := range counters {
+		values[userCounter.Key] = userCounter.Value
+	}
+	return values, nil
+}
+
+// GetCounterBuckets returns the hourly buckets of every key of counter from the hour since falls in
+func (r *CounterRepositoryImpl) GetCounterBuckets(userId uuid.UUID, counter constant.Counter, since time.Time) ([]dao.UserCounterBucket, error) {
+	var buckets []dao.UserCounterBucket
+	err := r.db.Where("user_id = ? AND counter = ? AND hour >= ?", userId, counter, since.UTC().Truncate(time.Hour)).
+		Find(&buckets).Error
+	return buckets, err
+}
+
+// incrementCounter adds amount to the per plant counter and to the COUNTER_KEY_ANY total, both lifetime
+// and in the current hour bucket. Callers pass their open transaction so the counter moves together
+// with the gameplay action
+func incrementCounter(db *gorm.DB, userId uuid.UUID, counter constant.Counter, plant constant.Plant, amount int64) error {
+	keys := []string{constant.COUNTER_KEY_ANY}
+	if plant != "" {
+		keys = append(keys, string(plant))
+	}
+	hour := time.Now().UTC().Truncate(time.Hour)
+	for _, key := range keys {
+		err := db.Exec(`
+			INSERT INTO user_counters (user_id, counter, key, value, updated_at)
+			VALUES (?, ?, ?, ?, NOW())
+			ON CONFLICT (user_id, counter, key) DO UPDATE
+			SET value = user_counters.value + EXCLUDED.value, updated_at = EXCLUDED.updated_at`,
+			userId, counter, key, amount).Error
+		if err != nil {
+			return err
+		}
+		err = db.Exec(`
+			INSERT INTO user_counter_buckets (user_id, counter, key, hour, value)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (user_id, counter, key, hour) DO UPDATE
+			SET value = user_counter_buckets.value + EXCLUDED.value`,
+			userId, counter, key, hour, amount).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordLogin advances the daily login streak in one statement: a second login on the same UTC day
+// keeps it, a login on the following day extends it and a longer gap restarts it at 1. The hour is
+// marked in the COUNTER_LOGIN buckets for recurring login tasks
+func (r *CounterRepositoryImpl) RecordLogin(userId uuid.UUID) (int64, error) {
+	today := pkg.StartOfDay(time.Now())
+	yesterday := today.AddDate(0, 0, -1)
+	var counters []dao.UserCounter
+	err := r.db.Raw(`
+		INSERT INTO user_counters (user_id, counter, key, value, updated_at)
+		VALUES (?, ?, ?, 1, NOW())
+		ON CONFLICT (user_id, counter, key) DO UPDATE
+		SET value = CASE
+				WHEN user_counters.updated_at >= ? THEN user_counters.value
+				WHEN user_counters.updated_at >= ? THEN user_counters.value + 1
+				ELSE 1
+			END,
+			updated_at = EXCLUDED.updated_at
+		RETURNING value`,
+		userId, constant.COUNTER_LOGIN_STREAK, constant.COUNTER_KEY_ANY, today, yesterday).Scan(&counters).Error
+	if err != nil {
+		log.Error("Error recording login: ", err)
+		return 0, err
+	}
+	err = r.db.Exec(`
+		INSERT INTO user_counter_buckets (user_id, counter, key, hour, value)
+		VALUES (?, ?, ?, ?, 1)
+		ON CONFLICT (user_id, counter, key, hour) DO NOTHING`,
+		userId, constant.COUNTER_LOGIN, constant.COUNTER_KEY_ANY, time.Now().UTC().Truncate(time.Hour)).Error
+	if err != nil {
+		log.Error("Error recording login hour: ", err)
+		return 0, err
+	}
+	if len(counters) == 0 {
+		return 0, nil
+	}
+	return counters[0].Value, nil
+}
+
+// GetLoginStreak returns the streak still alive today, a streak whose last login is before yesterday is broken
+func (r *CounterRepositoryImpl) GetLoginStreak(userId uuid.UUID) (int64, error) {
+	var counters []dao.UserCounter
+	err := r.db.Where("user_id = ? AND counter = ? AND key = ?", userId, constant.COUNTER_LOGIN_STREAK, constant.COUNTER_KEY_ANY).
+		Limit(1).Find(&counters).Error
+	if err != nil || len(counters) == 0 {
+		return 0, err
+	}
+	if counters[0].UpdatedAt.Before(pkg.StartOfDay(time.Now()).AddDate(0, 0, -1)) {
+		return 0, nil
+	}
+	return counters[0].Value, nil
+}
+
+func CounterRepositoryInit(db *gorm.DB) *CounterRepositoryImpl {
+	if err := db.AutoMigrate(&dao.UserCounter{}, &dao.UserCounterBucket{}); err != nil {
+		log.Error("Error during AutoMigrate: ", err)
+	}
+	if err := db.Where("hour < ?", time.Now().Add(-counterBucketRetention)).Delete(&dao.UserCounterBucket{}).Error; err != nil {
+		log.Error("Error pruning counter buckets: ", err)
+	}
+	return &CounterRepositoryImpl{db: db}
+}
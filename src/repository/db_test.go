@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/domain/dao"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"os"
+	"testing"
+)
+
+// testDB connects to the scratch database at TEST_DB_DSN and migrates the user and inventory tables,
+// tests needing a database are skipped without one
+func testDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		SkipDefaultTransaction: true,
+		TranslateError:         true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Skip("database is not reachable: ", err)
+	}
+	UserRepositoryInit(db)
+	InventoryRepositoryInit(db)
+	return db
+}
+
+// testUser creates a user that is removed together with its inventory when the test ends
+func testUser(t testing.TB, db *gorm.DB) dao.User {
+	t.Helper()
+	user := dao.User{}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { deleteTestUser(db, user.ID.String()) })
+	return user
+}
+
+func deleteTestUser(db *gorm.DB, userId string) {
+	for _, table := range []string{"inventory_ledgers", "inventory_items", "user_fields", "user_upgrades",
+		"sessions", "user_auths", "wallet_transactions", "leaderboard_scores", "task_completes",
+		"user_counter_buckets", "user_counters"} {
+		if db.Migrator().HasTable(table) {
+			db.Exec("DELETE FROM "+table+" WHERE user_id = ?", userId)
+		}
+	}
+	for _, table := range []string{"referral_payouts", "user_referrals"} {
+		if db.Migrator().HasTable(table) {
+			db.Exec("DELETE FROM "+table+" WHERE referrer_id = ? OR referral_id = ?", userId, userId)
+		}
+	}
+	db.Exec("DELETE FROM users WHERE id = ?", userId)
+}
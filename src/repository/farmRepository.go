@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"bytes"
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"errors"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	ErrThiefCooldown  = errors.New("thief is on steal cooldown")
+	ErrVictimCooldown = errors.New("victim was robbed recently")
+	ErrAlreadyStolen  = errors.New("field already stolen from")
+)
+
+type FarmRepository interface {
+	Steal(field dao.UserField, theft dao.Theft, thiefCooldown, victimCooldown time.Duration) (dao.Theft, error)
+	GetLastTheftAt(column string, userId uuid.UUID) (*time.Time, error)
+	GetNotifications(userId uuid.UUID, limit int) ([]dao.Notification, error)
+	MarkNotificationsRead(userId uuid.UUID) error
+}
+
+type FarmRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// GetLastTheftAt returns the newest theft where column ("thief_id" or "victim_id") is userId
+func (f *FarmRepositoryImpl) GetLastTheftAt(column string, userId uuid.UUID) (*time.Time, error) {
+	return lastTheftAt(f.db, column, userId)
+}
+
+func lastTheftAt(db *gorm.DB, column string, userId uuid.UUID) (*time.Time, error) {
+	var thefts []dao.Theft
+	err := db.Where(column+" = ?", userId).Order("created_at DESC").Limit(1).Find(&thefts).Error
+	if err != nil || len(thefts) == 0 {
+		return nil, err
+	}
+	return &thefts[0].CreatedAt, nil
+}
+
+func onCooldown(db *gorm.DB, column string, userId uuid.UUID, cooldown time.Duration) (bool, error) {
+	if cooldown <= 0 {
+		return false, nil
+	}
+	last, err := lastTheftAt(db, column, userId)
+	if err != nil || last == nil {
+		return false, err
+	}
+	return time.Since(*last) < cooldown, nil
+}
+
+// Steal moves theft.Amount of the victim's crop on field to the thief. Both user rows are locked in id
+// order so parallel steals serialize on the cooldowns without deadlocking, and the stolen guard on the
+// field lets only one friend rob each crop. The update keys on the row id that was checked, a crop
+// harvested and replanted in the meantime is a new row and is not touched
+func (f *FarmRepositoryImpl) Steal(field dao.UserField, theft dao.Theft, thiefCooldown, victimCooldown time.Duration) (dao.Theft, error) {
+	err := f.db.Transaction(func(tx *gorm.DB) error {
+		userIds := []uuid.UUID{theft.ThiefID, theft.VictimID}
+		if bytes.Compare(userIds[0][:], userIds[1][:]) > 0 {
+			userIds[0], userIds[1] = userIds[1], userIds[0]
+		}
+		for _, userId := range userIds {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userId).First(&dao.User{}).Error; err != nil {
+				return err
+			}
+		}
+
+		thiefBusy, err := onCooldown(tx, "thief_id", theft.ThiefID, thiefCooldown)
+		if err != nil {
+			return err
+		}
+		if thiefBusy {
+			return ErrThiefCooldown
+		}
+		victimBusy, err := onCooldown(tx, "victim_id", theft.VictimID, victimCooldown)
+		if err != nil {
+			return err
+		}
+		if victimBusy {
+			return ErrVictimCooldown
+		}
+
+		result := tx.Model(&dao.UserField{}).
+			Where("id = ? AND user_id = ? AND stolen = 0", field.ID, theft.VictimID).
+			Update("stolen", theft.Amount)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAlreadyStolen
+		}
+
+		if err := tx.Create(&theft).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&dao.Notification{
+			UserID:  theft.VictimID,
+			Type:    constant.NOTIFICATION_STEAL,
+			ActorID: theft.ThiefID,
+			Plant:   theft.Plant,
+			Amount:  theft.Amount,
+		}).Error; err != nil {
+			return err
+		}
+		return adjustItemQuantity(tx, theft.ThiefID, theft.Plant, theft.Amount, constant.LEDGER_STEAL, theft.ID.String())
+	})
+	if err != nil {
+		return dao.Theft{}, err
+	}
+	return theft, nil
+}
+
+func (f *FarmRepositoryImpl) GetNotifications(userId uuid.UUID, limit int) ([]dao.Notification, error) {
+	var notifications []dao.Notification
+	err := f.db.Where("user_id = ?", userId).
+		Preload("Actor").
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&notifications).Error
+	if err != nil {
+		log.Error("Error getting notifications: ", err)
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (f *FarmRepositoryImpl) MarkNotificationsRead(userId uuid.UUID) error {
+	return f.db.Model(&dao.Notification{}).
+		Where("user_id = ? AND read = ?", userId, false).
+		Update("read", true).Error
+}
+
+func FarmRepositoryInit(db *gorm.DB) *FarmRepositoryImpl {
+	if err := db.AutoMigrate(&dao.Theft{}, &dao.Notification{}); err != nil {
+		log.Error("Error during AutoMigrate: ", err)
+	}
+	return &FarmRepositoryImpl{db: db}
+}
@@ -6,25 +6,39 @@ import (
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type InventoryRepository interface {
-	GetAllInventoryItems(userId uuid.UUID) ([]dao.InventoryItem, error)
-	AdjustItemQuantity(userId uuid.UUID, plant constant.Plant, amount int) error
+	GetAllInventoryItems(userId uuid.UUID, plants []constant.Plant) ([]dao.InventoryItem, error)
+	AdjustItemQuantity(userId uuid.UUID, plant constant.Plant, amount int, reason constant.LedgerReason, refID string) error
 	GetItemQuantity(userId uuid.UUID, plant constant.Plant) (int, error)
+	GetItemQuantities(userId uuid.UUID, plants []constant.Plant) (map[constant.Plant]int, error)
 	GetMyFields(userId uuid.UUID) ([]dao.UserField, error)
 	GetMyField(userId uuid.UUID, fieldID int) (*dao.UserField, error)
 	PlantField(userId uuid.UUID, fieldID int, plant constant.Plant) (dao.UserField, error)
+	HarvestField(field dao.UserField, reward int) (int, error)
+	GetHistory(userId uuid.UUID, cursor uint64, limit int) ([]dao.InventoryLedger, error)
+	CheckLedger(fix bool) ([]dao.LedgerMismatch, error)
+	WithTx(tx *gorm.DB) InventoryRepository
 }
 
+var (
+	ErrNotEnoughItems = errors.New("not enough items")
+	ErrFieldOccupied  = errors.New("field already planted")
+	ErrFieldEmpty     = errors.New("field already harvested")
+)
+
 type InventoryRepositoryImpl struct {
 	db *gorm.DB
 }
 
-func (u *InventoryRepositoryImpl) GetAllInventoryItems(userId uuid.UUID) ([]dao.InventoryItem, error) {
+// GetAllInventoryItems returns one row per catalog plant in catalog order, creating missing rows
+func (u *InventoryRepositoryImpl) GetAllInventoryItems(userId uuid.UUID, plants []constant.Plant) ([]dao.InventoryItem, error) {
 	var inventoryItems []dao.InventoryItem
-	err := u.db.Where("user_id = ? AND plant IN ?", userId, constant.Plants).Find(&inventoryItems).Error
+	err := u.db.Where("user_id = ? AND plant IN ?", userId, plants).Find(&inventoryItems).Error
 	if err != nil {
 		return nil, err
 	}
@@ -33,7 +47,7 @@ func (u *InventoryRepositoryImpl) GetAllInventoryItems(userId uuid.UUID) ([]dao.
 		existingPlants[item.Plant] = true
 	}
 	var newItems []dao.InventoryItem
-	for _, plant := range constant.Plants {
+	for _, plant := range plants {
 		if !existingPlants[plant] {
 			newItems = append(newItems, dao.InventoryItem{
 				UserID: userId,
@@ -42,14 +56,19 @@ func (u *InventoryRepositoryImpl) GetAllInventoryItems(userId uuid.UUID) ([]dao.
 		}
 	}
 	if len(newItems) > 0 {
-		if err := u.db.Create(&newItems).Error; err != nil {
+		// A parallel request may create the same rows, read them back instead of failing
+		if err := u.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&newItems).Error; err != nil {
+			return nil, err
+		}
+		inventoryItems = nil
+		err = u.db.Where("user_id = ? AND plant IN ?", userId, plants).Find(&inventoryItems).Error
+		if err != nil {
 			return nil, err
 		}
-		inventoryItems = append(inventoryItems, newItems...)
 	}
 
-	orderedInventoryItems := make([]dao.InventoryItem, 0, len(constant.Plants))
-	for _, plant := range constant.Plants {
+	orderedInventoryItems := make([]dao.InventoryItem, 0, len(plants))
+	for _, plant := range plants {
 		for _, item := range inventoryItems {
 			if item.Plant == plant {
 				orderedInventoryItems = append(orderedInventoryItems, item)
@@ -61,28 +80,49 @@ func (u *InventoryRepositoryImpl) GetAllInventoryItems(userId uuid.UUID) ([]dao.
 	return orderedInventoryItems, nil
 }
 
-func (u *InventoryRepositoryImpl) AdjustItemQuantity(userId uuid.UUID, plant constant.Plant, amount int) error {
+func (u *InventoryRepositoryImpl) AdjustItemQuantity(userId uuid.UUID, plant constant.Plant, amount int, reason constant.LedgerReason, refID string) error {
+	return adjustItemQuantity(u.db, userId, plant, amount, reason, refID)
+}
+
+// adjustItemQuantity changes the balance with a single conditional statement, so concurrent
+// debits can never overdraw, and appends the matching ledger row in the same transaction
+func adjustItemQuantity(db *gorm.DB, userId uuid.UUID, plant constant.Plant, amount int, reason constant.LedgerReason, refID string) error {
 	if amount == 0 {
 		return fmt.Errorf("amount must not be zero")
 	}
 
-	var item dao.InventoryItem
-	err := u.db.Where("user_id = ? AND plant = ?", userId, plant).First(&item).Error
-	if err != nil {
-		return err // Item not found or other error
-	}
-
-	item.Quantity += amount // This will handle both increase and decrease
-
-	if item.Quantity < 0 {
-		return fmt.Errorf("quantity cannot be negative")
-	}
-
-	if err := u.db.Save(&item).Error; err != nil {
-		return err
-	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		var updated []dao.InventoryItem
+		var err error
+		if amount > 0 {
+			// Inventory rows are created lazily, credit upserts into a fresh one
+			err = tx.Raw(`
+				INSERT INTO inventory_items (user_id, plant, quantity, created_at)
+				VALUES (?, ?, ?, NOW())
+				ON CONFLICT (user_id, plant) DO UPDATE SET quantity = inventory_items.quantity + EXCLUDED.quantity
+				RETURNING quantity`, userId, plant, amount).Scan(&updated).Error
+		} else {
+			err = tx.Raw(`
+				UPDATE inventory_items SET quantity = quantity + ?
+				WHERE user_id = ? AND plant = ? AND quantity >= ?
+				RETURNING quantity`, amount, userId, plant, -amount).Scan(&updated).Error
+		}
+		if err != nil {
+			return err
+		}
+		if len(updated) == 0 {
+			return ErrNotEnoughItems
+		}
 
-	return nil
+		return tx.Create(&dao.InventoryLedger{
+			UserID:       userId,
+			Plant:        plant,
+			Amount:       amount,
+			BalanceAfter: updated[0].Quantity,
+			Reason:       reason,
+			RefID:        refID,
+		}).Error
+	})
 }
 
 func (u *InventoryRepositoryImpl) GetItemQuantity(userId uuid.UUID, plant constant.Plant) (int, error) {
@@ -94,6 +134,19 @@ func (u *InventoryRepositoryImpl) GetItemQuantity(userId uuid.UUID, plant consta
 	return item.Quantity, nil
 }
 
+// GetItemQuantities reads the held quantity of several plants at once, plants without a row are absent
+func (u *InventoryRepositoryImpl) GetItemQuantities(userId uuid.UUID, plants []constant.Plant) (map[constant.Plant]int, error) {
+	var items []dao.InventoryItem
+	if err := u.db.Where("user_id = ? AND plant IN ?", userId, plants).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	quantities := make(map[constant.Plant]int, len(items))
+	for _, item := range items {
+		quantities[item.Plant] = item.Quantity
+	}
+	return quantities, nil
+}
+
 func (u *InventoryRepositoryImpl) GetMyFields(userId uuid.UUID) ([]dao.UserField, error) {
 	var userFields []dao.UserField
 	if err := u.db.Where("user_id = ?", userId).Find(&userFields).Error; err != nil {
@@ -112,22 +165,176 @@ func (u *InventoryRepositoryImpl) GetMyField(userId uuid.UUID, fieldID int) (*da
 	return &userFields, nil
 }
 
+// PlantField spends one seed and occupies the field atomically, the unique (user_id, field_id)
+// index rejects a parallel plant on the same field
 func (u *InventoryRepositoryImpl) PlantField(userId uuid.UUID, fieldID int, plant constant.Plant) (dao.UserField, error) {
 	userField := dao.UserField{
 		UserID:  userId,
 		FieldID: fieldID,
 		Plant:   plant,
 	}
-	if err := u.db.Save(&userField).Error; err != nil {
+	err := u.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&userField).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrFieldOccupied
+			}
+			return err
+		}
+		if err := adjustItemQuantity(tx, userId, plant, -1, constant.LEDGER_PLANT, userField.ID.String()); err != nil {
+			return err
+		}
+		return incrementCounter(tx, userId, constant.COUNTER_PLANTED, plant, 1)
+	})
+	if err != nil {
 		return dao.UserField{}, err
 	}
 
 	return userField, nil
 }
 
+// HarvestField clears the field and credits the reward minus whatever was stolen from it,
+// the stolen part is read from the deleted row so a steal racing the harvest is never counted twice
+func (u *InventoryRepositoryImpl) HarvestField(field dao.UserField, reward int) (int, error) {
+	var amount int
+	err := u.db.Transaction(func(tx *gorm.DB) error {
+		var deleted []dao.UserField
+		if err := tx.Clauses(clause.Returning{}).Where("id = ?", field.ID).Delete(&deleted).Error; err != nil {
+			return err
+		}
+		if len(deleted) == 0 {
+			return ErrFieldEmpty
+		}
+		amount = reward - deleted[0].Stolen
+		if amount <= 0 {
+			return nil
+		}
+		if err := adjustItemQuantity(tx, field.UserID, field.Plant, amount, constant.LEDGER_HARVEST, field.ID.String()); err != nil {
+			return err
+		}
+		if err := incrementCounter(tx, field.UserID, constant.COUNTER_HARVESTED, field.Plant, int64(amount)); err != nil {
+			return err
+		}
+		return addLeaderboardScore(tx, field.UserID, constant.LEADERBOARD_HARVESTED, int64(amount))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return max(amount, 0), nil
+}
+
+// GetHistory returns ledger rows newest first, cursor is the last seen ledger id or 0 for the first page
+func (u *InventoryRepositoryImpl) GetHistory(userId uuid.UUID, cursor uint64, limit int) ([]dao.InventoryLedger, error) {
+	query := u.db.Where("user_id = ?", userId)
+	if cursor > 0 {
+		query = query.Where("id < ?", cursor)
+	}
+	var entries []dao.InventoryLedger
+	if err := query.Order("id DESC").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// CheckLedger rebuilds balances from the ledger and reports every inventory row that disagrees,
+// with fix set the inventory quantities are overwritten by the ledger sums
+func (u *InventoryRepositoryImpl) CheckLedger(fix bool) ([]dao.LedgerMismatch, error) {
+	var mismatches []dao.LedgerMismatch
+	err := u.db.Raw(`
+		SELECT COALESCE(i.user_id, l.user_id) AS user_id,
+		       COALESCE(i.plant, l.plant) AS plant,
+		       COALESCE(l.total, 0) AS ledger,
+		       COALESCE(i.quantity, 0) AS inventory
+		FROM inventory_items i
+		FULL OUTER JOIN (
+			SELECT user_id, plant, SUM(amount) AS total
+			FROM inventory_ledgers
+			GROUP BY user_id, plant
+		) l ON l.user_id = i.user_id AND l.plant = i.plant
+		WHERE COALESCE(l.total, 0) <> COALESCE(i.quantity, 0)`).Scan(&mismatches).Error
+	if err != nil {
+		return nil, err
+	}
+	if !fix {
+		return mismatches, nil
+	}
+
+	for _, mismatch := range mismatches {
+		result := u.db.Model(&dao.InventoryItem{}).
+			Where("user_id = ? AND plant = ?", mismatch.UserID, mismatch.Plant).
+			Update("quantity", mismatch.Ledger)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			item := dao.InventoryItem{UserID: mismatch.UserID, Plant: mismatch.Plant, Quantity: mismatch.Ledger}
+			if err := u.db.Create(&item).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+	return mismatches, nil
+}
+
+// seedOpeningBalances records balances that predate the ledger so rebuilding from it stays exact
+func (u *InventoryRepositoryImpl) seedOpeningBalances() {
+	err := u.db.Exec(`
+		INSERT INTO inventory_ledgers (user_id, plant, amount, balance_after, reason, created_at)
+		SELECT i.user_id, i.plant, i.quantity, i.quantity, ?, NOW()
+		FROM inventory_items i
+		WHERE i.quantity <> 0 AND NOT EXISTS (
+			SELECT 1 FROM inventory_ledgers l WHERE l.user_id = i.user_id AND l.plant = i.plant
+		)`, constant.LEDGER_OPENING_BALANCE).Error
+	if err != nil {
+		log.Error("Error seeding opening balances: ", err)
+	}
+}
+
+// WithTx returns a repository bound to an open transaction
+func (u *InventoryRepositoryImpl) WithTx(tx *gorm.DB) InventoryRepository {
+	return &InventoryRepositoryImpl{db: tx}
+}
+
+// dedupeInventoryItems folds rows of the same user and plant into the oldest one, summing their
+// quantities. Older deployments have such duplicates and the unique index can not be built over them
+func dedupeInventoryItems(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&dao.InventoryItem{}) || db.Migrator().HasIndex(&dao.InventoryItem{}, "idx_inventory_user_plant") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		duplicates := `
+			SELECT user_id, plant, SUM(quantity) AS total, (ARRAY_AGG(id ORDER BY created_at, id))[1] AS keep_id
+			FROM inventory_items GROUP BY user_id, plant HAVING COUNT(*) > 1`
+		result := tx.Exec(`
+			UPDATE inventory_items i SET quantity = d.total
+			FROM (` + duplicates + `) d
+			WHERE i.id = d.keep_id`)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			log.Warnf("Merging duplicate inventory rows of %d user plants", result.RowsAffected)
+		}
+		return tx.Exec(`
+			DELETE FROM inventory_items i
+			USING (` + duplicates + `) d
+			WHERE i.user_id = d.user_id AND i.plant = d.plant AND i.id <> d.keep_id`).Error
+	})
+}
+
 func InventoryRepositoryInit(db *gorm.DB) *InventoryRepositoryImpl {
-	_ = db.AutoMigrate(&dao.InventoryItem{})
-	return &InventoryRepositoryImpl{
+	if err := dedupeInventoryItems(db); err != nil {
+		log.Fatal("Error merging duplicate inventory rows: ", err)
+	}
+	// Credits upsert on (user_id, plant), without the unique index every one of them fails
+	if err := db.AutoMigrate(&dao.InventoryItem{}, &dao.InventoryLedger{}); err != nil {
+		log.Fatal("Error during AutoMigrate: ", err)
+	}
+	if !db.Migrator().HasIndex(&dao.InventoryItem{}, "idx_inventory_user_plant") {
+		log.Fatal("Unique index idx_inventory_user_plant is missing")
+	}
+	repository := &InventoryRepositoryImpl{
 		db: db,
 	}
+	repository.seedOpeningBalances()
+	return repository
 }
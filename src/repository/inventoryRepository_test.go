@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAdjustItemQuantityConcurrent(t *testing.T) {
+	db := testDB(t)
+	repository := &InventoryRepositoryImpl{db: db}
+	user := testUser(t, db)
+	plant := constant.Plant("TEST_PLANT")
+
+	const opening, credits, debits = 10, 20, 50
+	if err := repository.AdjustItemQuantity(user.ID, plant, opening, constant.LEDGER_ADMIN_GRANT, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	var debited atomic.Int64
+	var wg sync.WaitGroup
+	errs := make(chan error, credits+debits)
+	for i := 0; i < credits+debits; i++ {
+		wg.Add(1)
+		go func(credit bool) {
+			defer wg.Done()
+			amount := -1
+			if credit {
+				amount = 1
+			}
+			err := repository.AdjustItemQuantity(user.ID, plant, amount, constant.LEDGER_ADMIN_GRANT, "test")
+			switch {
+			case err == nil && !credit:
+				debited.Add(1)
+			case errors.Is(err, ErrNotEnoughItems) && !credit:
+			case err != nil:
+				errs <- err
+			}
+		}(i < credits)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	quantity, err := repository.GetItemQuantity(user.ID, plant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := opening + credits - int(debited.Load()); quantity != want {
+		t.Fatalf("quantity %d, want %d", quantity, want)
+	}
+	if debited.Load() > opening+credits {
+		t.Fatalf("%d debits succeeded, only %d items existed", debited.Load(), opening+credits)
+	}
+
+	var ledger struct {
+		Sum      int
+		Negative int
+	}
+	err = db.Model(&dao.InventoryLedger{}).
+		Select("COALESCE(SUM(amount), 0) AS sum, COUNT(*) FILTER (WHERE balance_after < 0) AS negative").
+		Where("user_id = ? AND plant = ?", user.ID, plant).Scan(&ledger).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ledger.Sum != quantity || ledger.Negative != 0 {
+		t.Fatalf("ledger sums to %d with %d negative balances, quantity is %d", ledger.Sum, ledger.Negative, quantity)
+	}
+}
+
+func TestAdjustItemQuantityOverdraw(t *testing.T) {
+	db := testDB(t)
+	repository := &InventoryRepositoryImpl{db: db}
+	user := testUser(t, db)
+	plant := constant.Plant("TEST_PLANT")
+
+	err := repository.AdjustItemQuantity(user.ID, plant, -1, constant.LEDGER_ADMIN_GRANT, "test")
+	if !errors.Is(err, ErrNotEnoughItems) {
+		t.Fatalf("debit of an empty inventory: %v, want ErrNotEnoughItems", err)
+	}
+	if err := repository.AdjustItemQuantity(user.ID, plant, 0, constant.LEDGER_ADMIN_GRANT, "test"); err == nil {
+		t.Fatal("zero adjustment accepted")
+	}
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/pkg"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	// Weekly standings older than this are dropped on refresh
+	leaderboardWeeklyRetention = 8 * 7 * 24 * time.Hour
+	// Postgres advisory lock key held while the inventory worth is rebuilt
+	leaderboardRefreshLock = 0x6c6272 // "lbr"
+)
+
+// Accounts merged into another keep their standings as history, the boards leave them out
+const leaderboardNotMerged = "NOT EXISTS (SELECT 1 FROM users WHERE users.id = leaderboard_scores.user_id AND users.merged_into_id IS NOT NULL)"
+
+type LeaderboardRepository interface {
+	GetTop(board constant.LeaderboardBoard, period string, limit int) ([]dao.LeaderboardScore, error)
+	GetRank(board constant.LeaderboardBoard, period string, userId uuid.UUID) (int64, int64, error)
+	GetScores(board constant.LeaderboardBoard, period string, userIds []uuid.UUID) (map[uuid.UUID]int64, error)
+	RefreshInventoryWorth(minAge time.Duration) (bool, error)
+}
+
+type LeaderboardRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// addLeaderboardScore adds amount to the all time and the current week standing of userId,
+// callers pass their open transaction so the score moves together with the change it counts
+func addLeaderboardScore(db *gorm.DB, userId uuid.UUID, board constant.LeaderboardBoard, amount int64) error {
+	for _, period := range []string{constant.LEADERBOARD_ALL_TIME, pkg.WeekKey(time.Now())} {
+		err := db.Exec(`
+			INSERT INTO leaderboard_scores (board, period, user_id, score, updated_at)
+			VALUES (?, ?, ?, ?, NOW())
+			ON CONFLICT (board, period, user_id) DO UPDATE
+			SET score = leaderboard_scores.score + EXCLUDED.score, updated_at = EXCLUDED.updated_at`,
+			board, period, userId, amount).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *LeaderboardRepositoryImpl) GetTop(board constant.LeaderboardBoard, period string, limit int) ([]dao.LeaderboardScore, error) {
+	var scores []dao.LeaderboardScore
+	err := l.db.Where("board = ? AND period = ? AND score > 0", board, period).
+		Where(leaderboardNotMerged).
+		Preload("User").
+		Order("score DESC, user_id").
+		Limit(limit).
+		Find(&scores).Error
+	if err != nil {
+		log.Error("Error getting leaderboard: ", err)
+		return nil, err
+	}
+	return scores, nil
+}
+
+// GetRank returns the score of userId and its competition rank, users with equal scores share a rank.
+// The count walks the rank index above the user's score only
+func (l *LeaderboardRepositoryImpl) GetRank(board constant.LeaderboardBoard, period string, userId uuid.UUID) (int64, int64, error) {
+	var scores []dao.LeaderboardScore
+	err := l.db.Where("board = ? AND period = ? AND user_id = ?", board, period, userId).Limit(1).Find(&scores).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	var score int64
+	if len(scores) > 0 {
+		score = scores[0].Score
+	}
+
+	var higher int64
+	err = l.db.Model(&dao.LeaderboardScore{}).
+		Where("board = ? AND period = ? AND score > ?", board, period, score).
+		Where(leaderboardNotMerged).
+		Count(&higher).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	return score, higher + 1, nil
+}
+
+func (l *LeaderboardRepositoryImpl) GetScores(board constant.LeaderboardBoard, period string, userIds []uuid.UUID) (map[uuid.UUID]int64, error) {
+	var scores []dao.LeaderboardScore
+	err := l.db.Where("board = ? AND period = ? AND user_id IN ?", board, period, userIds).Find(&scores).Error
+	if err != nil {
+		return nil, err
+	}
+	byUser := make(map[uuid.UUID]int64, len(scores))
+	for _, score := range scores {
+		byUser[score.UserID] = score.Score
+	}
+	return byUser, nil
+}
+
+// RefreshInventoryWorth rebuilds the inventory worth standings from current sell prices in one statement
+// and drops expired weekly standings. It runs under an advisory lock and skips, returning false, while
+// another instance is refreshing or the standings were rebuilt less than minAge ago
+func (l *LeaderboardRepositoryImpl) RefreshInventoryWorth(minAge time.Duration) (bool, error) {
+	refreshed := false
+	err := l.db.Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", leaderboardRefreshLock).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+		if minAge > 0 {
+			var last *time.Time
+			err := tx.Model(&dao.LeaderboardScore{}).
+				Where("board = ? AND period = ?", constant.LEADERBOARD_INVENTORY_WORTH, constant.LEADERBOARD_ALL_TIME).
+				Select("MAX(updated_at)").Scan(&last).Error
+			if err != nil {
+				return err
+			}
+			if last != nil && time.Since(*last) < minAge {
+				return nil
+			}
+		}
+
+		err := tx.Exec(`
+			INSERT INTO leaderboard_scores (board, period, user_id, score, updated_at)
+			SELECT ?, ?, i.user_id, COALESCE(SUM(i.quantity * p.sell_price), 0), NOW()
+			FROM inventory_items i
+			LEFT JOIN plant_infos p ON p.name = i.plant
+			GROUP BY i.user_id
+			ON CONFLICT (board, period, user_id) DO UPDATE
+			SET score = EXCLUDED.score, updated_at = EXCLUDED.updated_at`,
+			constant.LEADERBOARD_INVENTORY_WORTH, constant.LEADERBOARD_ALL_TIME).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("period <> ? AND updated_at < ?", constant.LEADERBOARD_ALL_TIME, time.Now().Add(-leaderboardWeeklyRetention)).
+			Delete(&dao.LeaderboardScore{}).Error
+		refreshed = err == nil
+		return err
+	})
+	return refreshed, err
+}
+
+// backfill builds the standings from the ledger and referrals the first time the table is created
+func (l *LeaderboardRepositoryImpl) backfill() {
+	var count int64
+	if err := l.db.Model(&dao.LeaderboardScore{}).Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	err := l.db.Transaction(func(tx *gorm.DB) error {
+		harvested := `
+			INSERT INTO leaderboard_scores (board, period, user_id, score, updated_at)
+			SELECT ?, ?, user_id, SUM(amount), NOW()
+			FROM inventory_ledgers
+			WHERE reason = ? AND created_at >= ?
+			GROUP BY user_id`
+		if err := tx.Exec(harvested, constant.LEADERBOARD_HARVESTED, constant.LEADERBOARD_ALL_TIME,
+			constant.LEDGER_HARVEST, time.Time{}).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := tx.Exec(harvested, constant.LEADERBOARD_HARVESTED, pkg.WeekKey(now),
+			constant.LEDGER_HARVEST, pkg.StartOfWeek(now)).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`
+			INSERT INTO leaderboard_scores (board, period, user_id, score, updated_at)
+			SELECT ?, ?, referrer_id, COUNT(*), NOW()
+			FROM user_referrals
+			GROUP BY referrer_id`,
+			constant.LEADERBOARD_REFERRALS, constant.LEADERBOARD_ALL_TIME).Error
+	})
+	if err != nil {
+		log.Error("Error backfilling leaderboards: ", err)
+		return
+	}
+	if _, err := l.RefreshInventoryWorth(0); err != nil {
+		log.Error("Error refreshing inventory worth: ", err)
+	}
+}
+
+func LeaderboardRepositoryInit(db *gorm.DB) *LeaderboardRepositoryImpl {
+	if err := db.AutoMigrate(&dao.LeaderboardScore{}); err != nil {
+		log.Error("Error during AutoMigrate: ", err)
+	}
+	repository := &LeaderboardRepositoryImpl{db: db}
+	repository.backfill()
+	return repository
+}
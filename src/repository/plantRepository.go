@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// Other instances pick up catalog edits after at most this long
+const plantCacheTtl = time.Minute
+
+var (
+	ErrPlantNotFound = errors.New("plant not found")
+	ErrPlantInUse    = errors.New("plant is still planted or held in inventories")
+)
+
+type PlantRepository interface {
+	GetPlants() ([]dao.PlantInfo, error)
+	GetPlant(name constant.Plant) (dao.PlantInfo, bool)
+	GetPlantNames() []constant.Plant
+	IsValidPlant(name constant.Plant) bool
+	CreatePlant(plant dao.PlantInfo) (dao.PlantInfo, error)
+	UpdatePlant(name constant.Plant, updates map[string]interface{}) (dao.PlantInfo, error)
+	DeletePlant(name constant.Plant) error
+}
+
+type PlantRepositoryImpl struct {
+	db *gorm.DB
+
+	mu       sync.RWMutex
+	plants   []dao.PlantInfo
+	byName   map[constant.Plant]dao.PlantInfo
+	loadedAt time.Time
+}
+
+// cached returns the catalog ordered by sort order, reloading it once the ttl has passed
+func (p *PlantRepositoryImpl) cached() ([]dao.PlantInfo, map[constant.Plant]dao.PlantInfo, error) {
+	p.mu.RLock()
+	if p.plants != nil && time.Since(p.loadedAt) < plantCacheTtl {
+		defer p.mu.RUnlock()
+		return p.plants, p.byName, nil
+	}
+	p.mu.RUnlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.plants != nil && time.Since(p.loadedAt) < plantCacheTtl {
+		return p.plants, p.byName, nil
+	}
+	var plants []dao.PlantInfo
+	if err := p.db.Order("sort_order, name").Find(&plants).Error; err != nil {
+		log.Error("Error loading plant catalog: ", err)
+		return nil, nil, err
+	}
+	byName := make(map[constant.Plant]dao.PlantInfo, len(plants))
+	for _, plant := range plants {
+		byName[plant.Name] = plant
+	}
+	p.plants, p.byName, p.loadedAt = plants, byName, time.Now()
+	return p.plants, p.byName, nil
+}
+
+func (p *PlantRepositoryImpl) invalidate() {
+	p.mu.Lock()
+	p.plants, p.byName = nil, nil
+	p.mu.Unlock()
+}
+
+func (p *PlantRepositoryImpl) GetPlants() ([]dao.PlantInfo, error) {
+	plants, _, err := p.cached()
+	return plants, err
+}
+
+func (p *PlantRepositoryImpl) GetPlant(name constant.Plant) (dao.PlantInfo, bool) {
+	_, byName, err := p.cached()
+	if err != nil {
+		return dao.PlantInfo{}, false
+	}
+	plant, ok := byName[name]
+	return plant, ok
+}
+
+// GetPlantNames lists every catalog plant, disabled ones included, the set every inventory holds a row for
+func (p *PlantRepositoryImpl) GetPlantNames() []constant.Plant {
+	plants, _, err := p.cached()
+	if err != nil {
+		return nil
+	}
+	names := make([]constant.Plant, 0, len(plants))
+	for _, plant := range plants {
+		names = append(names, plant.Name)
+	}
+	return names
+}
+
+func (p *PlantRepositoryImpl) IsValidPlant(name constant.Plant) bool {
+	plant, ok := p.GetPlant(name)
+	return ok && plant.Enabled
+}
+
+func (p *PlantRepositoryImpl) CreatePlant(plant dao.PlantInfo) (dao.PlantInfo, error) {
+	// Select all columns so a plant created disabled is not overwritten by the column default
+	if err := p.db.Select("*").Create(&plant).Error; err != nil {
+		log.Error("Error creating plant: ", err)
+		return dao.PlantInfo{}, err
+	}
+	p.invalidate()
+	return plant, nil
+}
+
+func (p *PlantRepositoryImpl) UpdatePlant(name constant.Plant, updates map[string]interface{}) (dao.PlantInfo, error) {
+	result := p.db.Model(&dao.PlantInfo{}).Where("name = ?", name).Updates(updates)
+	if result.Error != nil {
+		log.Error("Error updating plant: ", result.Error)
+		return dao.PlantInfo{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return dao.PlantInfo{}, ErrPlantNotFound
+	}
+	p.invalidate()
+
+	var plant dao.PlantInfo
+	if err := p.db.Where("name = ?", name).First(&plant).Error; err != nil {
+		return dao.PlantInfo{}, err
+	}
+	return plant, nil
+}
+
+// DeletePlant removes a plant nobody holds or grows, plants in use should be disabled instead
+func (p *PlantRepositoryImpl) DeletePlant(name constant.Plant) error {
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		var inUse bool
+		err := tx.Raw(`
+			SELECT EXISTS (SELECT 1 FROM user_fields WHERE plant = ?)
+			    OR EXISTS (SELECT 1 FROM inventory_items WHERE plant = ? AND quantity > 0)`, name, name).
+			Scan(&inUse).Error
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return ErrPlantInUse
+		}
+
+		if err := tx.Where("plant = ?", name).Delete(&dao.InventoryItem{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("name = ?", name).Delete(&dao.PlantInfo{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPlantNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrPlantNotFound) && !errors.Is(err, ErrPlantInUse) {
+			log.Error("Error deleting plant: ", err)
+		}
+		return err
+	}
+	p.invalidate()
+	return nil
+}
+
+func (p *PlantRepositoryImpl) seedPlants() {
+	var count int64
+	if err := p.db.Model(&dao.PlantInfo{}).Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	plants := make([]dao.PlantInfo, len(constant.DefaultPlants))
+	for i, plant := range constant.DefaultPlants {
+		plants[i] = dao.PlantInfo{
+			Name:      plant.Name,
+			GrowTime:  int64(plant.GrowTime / time.Second),
+			Reward:    plant.Reward,
+			SellPrice: plant.SellPrice,
+			SortOrder: i,
+		}
+	}
+	if err := p.db.Create(&plants).Error; err != nil {
+		log.Error("Error seeding plants: ", err)
+	}
+}
+
+func PlantRepositoryInit(db *gorm.DB) *PlantRepositoryImpl {
+	if err := db.AutoMigrate(&dao.PlantInfo{}); err != nil {
+		log.Error("Error during AutoMigrate: ", err)
+	}
+	repository := &PlantRepositoryImpl{db: db}
+	repository.seedPlants()
+	return repository
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tiers seeded into an empty referral_rewards table
+var defaultReferralRewards = []dao.ReferralReward{
+	{
+		ID:             "signup",
+		Milestone:      constant.REFERRAL_SIGNUP,
+		Rewards:        []dao.RewardLine{{Kind: constant.REWARD_COINS, Amount: 10}},
+		PremiumRewards: []dao.RewardLine{{Kind: constant.REWARD_COINS, Amount: 30}},
+		Enabled:        true,
+	},
+	{
+		ID:             "first_harvest",
+		Milestone:      constant.REFERRAL_HARVEST,
+		Threshold:      1,
+		Rewards:        []dao.RewardLine{{Kind: constant.REWARD_PLANT, Plant: constant.ROSE, Amount: 2}},
+		PremiumRewards: []dao.RewardLine{{Kind: constant.REWARD_PLANT, Plant: constant.ROSE, Amount: 5}},
+		Enabled:        true,
+		SortOrder:      1,
+	},
+	{
+		ID:             "farm_level_2",
+		Milestone:      constant.REFERRAL_FARM_LEVEL,
+		Threshold:      2,
+		Rewards:        []dao.RewardLine{{Kind: constant.REWARD_COINS, Amount: 20}},
+		PremiumRewards: []dao.RewardLine{{Kind: constant.REWARD_COINS, Amount: 50}},
+		Enabled:        true,
+		SortOrder:      2,
+	},
+}
+
+var ErrReferralRewardPaid = errors.New("referral reward already paid")
+
+type ReferralRepository interface {
+	GetReferralRewards() ([]dao.ReferralReward, error)
+	SaveReferralReward(reward dao.ReferralReward) (dao.ReferralReward, error)
+	GetReferral(referralId uuid.UUID) (*dao.UserReferral, error)
+	GetPaidRewardIds(referralId uuid.UUID) (map[string]bool, error)
+	GetPayouts(referrerId uuid.UUID, limit int) ([]dao.ReferralPayout, error)
+	PayReferralReward(payout dao.ReferralPayout) (dao.ReferralPayout, error)
+}
+
+type ReferralRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (r *ReferralRepositoryImpl) GetReferralRewards() ([]dao.ReferralReward, error) {
+	var rewards []dao.ReferralReward
+	if err := r.db.Order("sort_order, id").Find(&rewards).Error; err != nil {
+		log.Error("Error getting referral rewards: ", err)
+		return nil, err
+	}
+	return rewards, nil
+}
+
+// SaveReferralReward creates or replaces the tier with reward.ID
+func (r *ReferralRepositoryImpl) SaveReferralReward(reward dao.ReferralReward) (dao.ReferralReward, error) {
+	// Select all columns so a tier saved disabled is not overwritten by the column default
+	err := r.db.Clauses(clause.OnConflict{UpdateAll: true}).Select("*").Create(&reward).Error
+	if err != nil {
+		log.Error("Error saving referral reward: ", err)
+		return dao.ReferralReward{}, err
+	}
+	return reward, nil
+}
+
+// GetReferral returns who referred referralId, nil if the user signed up without a referral
+func (r *ReferralRepositoryImpl) GetReferral(referralId uuid.UUID) (*dao.UserReferral, error) {
+	var referrals []dao.UserReferral
+	if err := r.db.Where("referral_id = ?", referralId).Limit(1).Find(&referrals).Error; err != nil {
+		return nil, err
+	}
+	if len(referrals) == 0 {
+		return nil, nil
+	}
+	return &referrals[0], nil
+}
+
+func (r *ReferralRepositoryImpl) GetPaidRewardIds(referralId uuid.UUID) (map[string]bool, error) {
+	var rewardIds []string
+	if err := r.db.Model(&dao.ReferralPayout{}).Where("referral_id = ?", referralId).Pluck("reward_id", &rewardIds).Error; err != nil {
+		return nil, err
+	}
+	paid := make(map[string]bool, len(rewardIds))
+	for _, rewardId := range rewardIds {
+		paid[rewardId] = true
+	}
+	return paid, nil
+}
+
+func (r *ReferralRepositoryImpl) GetPayouts(referrerId uuid.UUID, limit int) ([]dao.ReferralPayout, error) {
+	var payouts []dao.ReferralPayout
+	err := r.db.Where("referrer_id = ?", referrerId).
+		Preload("Referral").
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&payouts).Error
+	if err != nil {
+		log.Error("Error getting referral payouts: ", err)
+		return nil, err
+	}
+	return payouts, nil
+}
+
+// PayReferralReward records the payout and grants its rewards to the referrer in one transaction.
+// The unique index on referral and tier lets only one of parallel attempts through, the others get
+// ErrReferralRewardPaid and grant nothing
+func (r *ReferralRepositoryImpl) PayReferralReward(payout dao.ReferralPayout) (dao.ReferralPayout, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&payout)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrReferralRewardPaid
+		}
+		if err := tx.Create(&dao.Notification{
+			UserID:  payout.ReferrerID,
+			Type:    constant.NOTIFICATION_REFERRAL_REWARD,
+			ActorID: payout.ReferralID,
+		}).Error; err != nil {
+			return err
+		}
+		refID := fmt.Sprintf("%s@%s", payout.ReferralID, payout.RewardID)
+		return grantRewards(tx, payout.ReferrerID, payout.Rewards,
+			constant.LEDGER_REFERRAL_REWARD, constant.WALLET_REFERRAL_REWARD, refID)
+	})
+	if err != nil {
+		return dao.ReferralPayout{}, err
+	}
+	return payout, nil
+}
+
+func (r *ReferralRepositoryImpl) seedReferralRewards() {
+	var count int64
+	if err := r.db.Model(&dao.ReferralReward{}).Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	if err := r.db.Create(&defaultReferralRewards).Error; err != nil {
+		log.Error("Error seeding referral rewards: ", err)
+	}
+}
+
+func ReferralRepositoryInit(db *gorm.DB) *ReferralRepositoryImpl {
+	if err := db.AutoMigrate(&dao.ReferralReward{}, &dao.ReferralPayout{}); err != nil {
+		log.Error("Error during AutoMigrate: ", err)
+	}
+	repository := &ReferralRepositoryImpl{db: db}
+	repository.seedReferralRewards()
+	return repository
+}
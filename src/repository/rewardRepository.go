@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+type RewardRepository interface {
+	GrantRewards(userId uuid.UUID, rewards []dao.RewardLine, ledgerReason constant.LedgerReason, walletReason constant.WalletReason, refID string) error
+	WithTx(tx *gorm.DB) RewardRepository
+}
+
+type RewardRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// GrantRewards pays out a reward bundle in one transaction, plants are booked in the inventory ledger
+// and coins in the wallet under refID, so either every line is granted or none
+func (r *RewardRepositoryImpl) GrantRewards(userId uuid.UUID, rewards []dao.RewardLine, ledgerReason constant.LedgerReason, walletReason constant.WalletReason, refID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return grantRewards(tx, userId, rewards, ledgerReason, walletReason, refID)
+	})
+}
+
+func grantRewards(db *gorm.DB, userId uuid.UUID, rewards []dao.RewardLine, ledgerReason constant.LedgerReason, walletReason constant.WalletReason, refID string) error {
+	upgradeLocked := false
+	for _, reward := range rewards {
+		if reward.Amount <= 0 {
+			continue
+		}
+		switch reward.Kind {
+		case constant.REWARD_PLANT:
+			if err := adjustItemQuantity(db, userId, reward.Plant, int(reward.Amount), ledgerReason, refID); err != nil {
+				return err
+			}
+		case constant.REWARD_COINS:
+			// Wallet transaction ids are global, the user id keeps claims of the same task apart
+			txID := fmt.Sprintf("%s:%s:%s", strings.ToLower(string(walletReason)), userId, refID)
+			if _, err := adjustCoins(db, userId, reward.Amount, walletReason, txID); err != nil {
+				return err
+			}
+		case constant.REWARD_FARM_XP, constant.REWARD_FARM_LEVEL:
+			if !upgradeLocked {
+				if err := lockUserUpgrade(db, userId); err != nil {
+					return err
+				}
+				upgradeLocked = true
+			}
+			column, value := "farm_xp", gorm.Expr("farm_xp + ?", reward.Amount)
+			if reward.Kind == constant.REWARD_FARM_LEVEL {
+				column, value = "farm_lvl", gorm.Expr("GREATEST(farm_lvl, ?)", reward.Amount)
+			}
+			if err := db.Model(&dao.UserUpgrade{}).Where("user_id = ?", userId).Update(column, value).Error; err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown reward kind %q", reward.Kind)
+		}
+	}
+	return nil
+}
+
+// lockUserUpgrade locks the user row and creates the upgrade row of users who never opened their farm,
+// the lock keeps parallel grants from creating it twice
+func lockUserUpgrade(db *gorm.DB, userId uuid.UUID) error {
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userId).First(&dao.User{}).Error; err != nil {
+		return err
+	}
+	var userUpgrade dao.UserUpgrade
+	return db.Where(dao.UserUpgrade{UserID: userId}).Attrs(dao.UserUpgrade{FarmLvl: 1}).FirstOrCreate(&userUpgrade).Error
+}
+
+// WithTx returns a repository bound to an open transaction
+func (r *RewardRepositoryImpl) WithTx(tx *gorm.DB) RewardRepository {
+	return &RewardRepositoryImpl{db: tx}
+}
+
+func RewardRepositoryInit(db *gorm.DB) *RewardRepositoryImpl {
+	return &RewardRepositoryImpl{db: db}
+}
@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/domain/dao"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// Session states are cached for this long. Revocations evict them on every instance through NATS, but
+// core NATS drops messages while an instance is disconnected, so the ttl bounds how long a revoked
+// session can outlive a lost broadcast
+const sessionCacheTtl = 5 * time.Second
+
+// Past this many cached sessions the stale ones are dropped
+const sessionCacheSize = 10_000
+
+const sessionRevokedSubject = "auth.sessions.revoked"
+
+var (
+	ErrSessionNotFound    = errors.New("session not found or revoked")
+	ErrRefreshTokenReused = errors.New("refresh token was already used")
+)
+
+type SessionRepository interface {
+	CreateSession(session dao.Session) (dao.Session, error)
+	RotateSession(refreshHash, newRefreshHash string, expiresAt time.Time) (dao.Session, error)
+	IsSessionActive(sessionId uuid.UUID) (bool, error)
+	RevokeSession(sessionId uuid.UUID) error
+	RevokeUserSessions(userId uuid.UUID) error
+	RevokeAuthSessions(userId, userAuthId uuid.UUID) error
+	DeleteStaleSessions(before time.Time) (int64, error)
+}
+
+type cachedSession struct {
+	userID    uuid.UUID
+	expiresAt time.Time
+	revoked   bool
+	loadedAt  time.Time
+}
+
+// sessionRevocation is broadcast after a revoke, it names either one session or every session of a user
+type sessionRevocation struct {
+	SessionID *uuid.UUID `json:"sessionId,omitempty"`
+	UserID    *uuid.UUID `json:"userId,omitempty"`
+}
+
+type SessionRepositoryImpl struct {
+	db *gorm.DB
+	nc *nats.Conn
+
+	mu       sync.RWMutex
+	sessions map[uuid.UUID]cachedSession
+	// epoch grows with every eviction, a load that started before one must not fill the cache
+	epoch    uint64
+	cacheTtl time.Duration
+}
+
+func (s *SessionRepositoryImpl) CreateSession(session dao.Session) (dao.Session, error) {
+	if err := s.db.Create(&session).Error; err != nil {
+		log.Error("Error creating session: ", err)
+		return dao.Session{}, err
+	}
+	return session, nil
+}
+
+// RotateSession swaps the refresh token of the session holding refreshHash for newRefreshHash and
+// extends it until expiresAt. A token that was already rotated away revokes its session, as only a
+// leaked copy would be presented twice
+func (s *SessionRepositoryImpl) RotateSession(refreshHash, newRefreshHash string, expiresAt time.Time) (dao.Session, error) {
+	var session dao.Session
+	err := s.db.Where("refresh_hash = ?", refreshHash).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var reused dao.Session
+		if err := s.db.Where("previous_hash = ?", refreshHash).First(&reused).Error; err == nil {
+			log.Warnf("Refresh token of session %s reused, revoking it", reused.ID)
+			if err := s.RevokeSession(reused.ID); err != nil {
+				return dao.Session{}, err
+			}
+			return dao.Session{}, ErrRefreshTokenReused
+		}
+		return dao.Session{}, ErrSessionNotFound
+	}
+	if err != nil {
+		log.Error("Error getting session: ", err)
+		return dao.Session{}, err
+	}
+	now := time.Now()
+	if !session.IsActive(now) {
+		return dao.Session{}, ErrSessionNotFound
+	}
+
+	// Matching the old hash lets only one of parallel refreshes with the same token through
+	result := s.db.Model(&dao.Session{}).
+		Where("id = ? AND refresh_hash = ? AND revoked_at IS NULL", session.ID, refreshHash).
+		Updates(map[string]interface{}{
+			"refresh_hash":  newRefreshHash,
+			"previous_hash": refreshHash,
+			"expires_at":    expiresAt,
+			"last_used_at":  now,
+		})
+	if result.Error != nil {
+		log.Error("Error rotating session: ", result.Error)
+		return dao.Session{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return dao.Session{}, ErrSessionNotFound
+	}
+	session.RefreshHash, session.PreviousHash = newRefreshHash, &refreshHash
+	session.ExpiresAt, session.LastUsedAt = expiresAt, now
+	return session, nil
+}
+
+// IsSessionActive reports whether the session exists, is not revoked and has not expired
+func (s *SessionRepositoryImpl) IsSessionActive(sessionId uuid.UUID) (bool, error) {
+	now := time.Now()
+	s.mu.RLock()
+	cached, ok := s.sessions[sessionId]
+	epoch := s.epoch
+	s.mu.RUnlock()
+	if ok && now.Sub(cached.loadedAt) < s.cacheTtl {
+		return !cached.revoked && now.Before(cached.expiresAt), nil
+	}
+
+	var sessions []dao.Session
+	if err := s.db.Where("id = ?", sessionId).Limit(1).Find(&sessions).Error; err != nil {
+		log.Error("Error getting session: ", err)
+		return false, err
+	}
+	if len(sessions) == 0 {
+		return false, nil
+	}
+	session := sessions[0]
+
+	if s.cacheTtl > 0 {
+		s.mu.Lock()
+		if len(s.sessions) >= sessionCacheSize {
+			s.pruneLocked(now)
+		}
+		if s.epoch == epoch {
+			s.sessions[sessionId] = cachedSession{
+				userID:    session.UserID,
+				expiresAt: session.ExpiresAt,
+				revoked:   session.RevokedAt != nil,
+				loadedAt:  now,
+			}
+		}
+		s.mu.Unlock()
+	}
+	return session.IsActive(now), nil
+}
+
+func (s *SessionRepositoryImpl) pruneLocked(now time.Time) {
+	for id, cached := range s.sessions {
+		if now.Sub(cached.loadedAt) >= s.cacheTtl {
+			delete(s.sessions, id)
+		}
+	}
+	if len(s.sessions) >= sessionCacheSize {
+		s.sessions = make(map[uuid.UUID]cachedSession)
+	}
+}
+
+func (s *SessionRepositoryImpl) RevokeSession(sessionId uuid.UUID) error {
+	err := s.db.Model(&dao.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionId).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		log.Error("Error revoking session: ", err)
+		return err
+	}
+	s.broadcast(sessionRevocation{SessionID: &sessionId})
+	return nil
+}
+
+// RevokeUserSessions revokes every session of the user, whichever auth method opened it
+func (s *SessionRepositoryImpl) RevokeUserSessions(userId uuid.UUID) error {
+	err := s.db.Model(&dao.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		log.Error("Error revoking user sessions: ", err)
+		return err
+	}
+	s.broadcast(sessionRevocation{UserID: &userId})
+	return nil
+}
+
+// RevokeAuthSessions revokes the sessions of the user that signed in with one auth method
+func (s *SessionRepositoryImpl) RevokeAuthSessions(userId, userAuthId uuid.UUID) error {
+	err := s.db.Model(&dao.Session{}).
+		Where("user_id = ? AND user_auth_id = ? AND revoked_at IS NULL", userId, userAuthId).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		log.Error("Error revoking auth sessions: ", err)
+		return err
+	}
+	s.broadcast(sessionRevocation{UserID: &userId})
+	return nil
+}
+
+// DeleteStaleSessions removes sessions that expired or were revoked before the given time
+func (s *SessionRepositoryImpl) DeleteStaleSessions(before time.Time) (int64, error) {
+	result := s.db.Where("expires_at < ? OR revoked_at < ?", before, before).Delete(&dao.Session{})
+	if result.Error != nil {
+		log.Error("Error deleting stale sessions: ", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// broadcast evicts the revoked sessions here and tells the other instances to do the same
+func (s *SessionRepositoryImpl) broadcast(revocation sessionRevocation) {
+	s.evict(revocation)
+	if s.nc == nil {
+		return
+	}
+	data, err := json.Marshal(revocation)
+	if err != nil {
+		log.Error("Session revocation marshal: ", err)
+		return
+	}
+	if err := s.nc.Publish(sessionRevokedSubject, data); err != nil {
+		log.Error("Session revocation publish: ", err)
+	}
+}
+
+func (s *SessionRepositoryImpl) evict(revocation sessionRevocation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.epoch++
+	if revocation.SessionID != nil {
+		delete(s.sessions, *revocation.SessionID)
+	}
+	if revocation.UserID != nil {
+		for id, cached := range s.sessions {
+			if cached.userID == *revocation.UserID {
+				delete(s.sessions, id)
+			}
+		}
+	}
+}
+
+func (s *SessionRepositoryImpl) subscribe() error {
+	_, err := s.nc.Subscribe(sessionRevokedSubject, func(m *nats.Msg) {
+		var revocation sessionRevocation
+		if err := json.Unmarshal(m.Data, &revocation); err != nil {
+			log.Error("Session revocation unmarshal: ", err)
+			return
+		}
+		s.evict(revocation)
+	})
+	return err
+}
+
+func SessionRepositoryInit(db *gorm.DB, nc *nats.Conn) *SessionRepositoryImpl {
+	if err := db.AutoMigrate(&dao.Session{}); err != nil {
+		log.Error("Error during AutoMigrate: ", err)
+	}
+	repository := &SessionRepositoryImpl{
+		db:       db,
+		nc:       nc,
+		sessions: make(map[uuid.UUID]cachedSession),
+		cacheTtl: sessionCacheTtl,
+	}
+	// Without the broadcast another instance could keep serving a revoked session from its cache
+	if nc == nil {
+		log.Warn("NATS is not connected, session cache disabled")
+		repository.cacheTtl = 0
+	} else if err := repository.subscribe(); err != nil {
+		log.Error("Session revocation subscribe: ", err)
+		repository.cacheTtl = 0
+	}
+	return repository
+}
@@ -0,0 +1,327 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/pkg"
+	"errors"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	ErrOutOfStock         = errors.New("shop item out of stock")
+	ErrPurchaseLimit      = errors.New("shop item purchase limit reached")
+	ErrShopItemNotFound   = errors.New("shop item not found")
+	ErrInvalidPurchaseQty = errors.New("invalid purchase quantity")
+	ErrDailySellCap       = errors.New("daily sell cap reached")
+)
+
+// Catalog seeded into an empty shop_items table
+var defaultShopItems = []dao.ShopItem{
+	{Name: "Strawberry seed", Contents: []dao.ItemStack{{Plant: constant.STRAWBERRY, Amount: 1}}, PriceCoins: 10, SortOrder: 1},
+	{Name: "Rose seed", Contents: []dao.ItemStack{{Plant: constant.ROSE, Amount: 1}}, PriceCoins: 15, SortOrder: 2},
+	{Name: "Sunflower seed", Contents: []dao.ItemStack{{Plant: constant.SUNFLOWER, Amount: 1}}, PriceCoins: 20, SortOrder: 3},
+	{Name: "Christmas tree seed", Contents: []dao.ItemStack{{Plant: constant.CHRISTMAS_TREE, Amount: 1}}, PriceCoins: 50, SortOrder: 4},
+	{
+		Name: "Starter bundle",
+		Contents: []dao.ItemStack{
+			{Plant: constant.STRAWBERRY, Amount: 2},
+			{Plant: constant.ROSE, Amount: 2},
+			{Plant: constant.SUNFLOWER, Amount: 2},
+		},
+		PriceCoins:    60,
+		PurchaseLimit: 1,
+		SortOrder:     5,
+	},
+}
+
+type ShopRepository interface {
+	GetItems() ([]dao.ShopItem, error)
+	GetAllItems() ([]dao.ShopItem, error)
+	GetItem(itemId uuid.UUID) (dao.ShopItem, error)
+	CreateItem(item dao.ShopItem) (dao.ShopItem, error)
+	UpdateItem(item dao.ShopItem, columns ...string) (dao.ShopItem, error)
+	GetPurchasedCounts(userId uuid.UUID) (map[uuid.UUID]int, error)
+	Buy(userId uuid.UUID, itemId uuid.UUID, quantity int) (dao.ShopPurchase, dao.ShopItem, error)
+	GetSoldCoinsSince(userId uuid.UUID, since time.Time) (int64, error)
+	Sell(userId uuid.UUID, plant constant.Plant, quantity int, unitPrice int64, dailyCap int64) (dao.Sale, error)
+}
+
+type ShopRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (s *ShopRepositoryImpl) GetItems() ([]dao.ShopItem, error) {
+	var items []dao.ShopItem
+	if err := s.db.Where("enabled = ?", true).Order("sort_order").Find(&items).Error; err != nil {
+		log.Error("Error getting shop items: ", err)
+		return nil, err
+	}
+	return items, nil
+}
+
+func (s *ShopRepositoryImpl) GetItem(itemId uuid.UUID) (dao.ShopItem, error) {
+	var item dao.ShopItem
+	if err := s.db.Where("id = ? AND enabled = ?", itemId, true).First(&item).Error; err != nil {
+		return dao.ShopItem{}, err
+	}
+	return item, nil
+}
+
+// GetAllItems returns the whole catalog for the admin api, disabled items included
+func (s *ShopRepositoryImpl) GetAllItems() ([]dao.ShopItem, error) {
+	var items []dao.ShopItem
+	if err := s.db.Order("sort_order").Find(&items).Error; err != nil {
+		log.Error("Error getting shop items: ", err)
+		return nil, err
+	}
+	return items, nil
+}
+
+func (s *ShopRepositoryImpl) CreateItem(item dao.ShopItem) (dao.ShopItem, error) {
+	// Select all columns so an item created disabled is not overwritten by the column default.
+	// A selected zero id would bypass the database default as well, so it is generated here
+	if item.ID == uuid.Nil {
+		item.ID = uuid.New()
+	}
+	if err := s.db.Select("*").Create(&item).Error; err != nil {
+		log.Error("Error creating shop item: ", err)
+		return dao.ShopItem{}, err
+	}
+	return item, nil
+}
+
+// UpdateItem writes columns of item to the row with item.ID, updating through the struct keeps
+// the json serializers of Contents and PriceItems
+func (s *ShopRepositoryImpl) UpdateItem(item dao.ShopItem, columns ...string) (dao.ShopItem, error) {
+	result := s.db.Model(&dao.ShopItem{ID: item.ID}).Select(columns).Updates(&item)
+	if result.Error != nil {
+		log.Error("Error updating shop item: ", result.Error)
+		return dao.ShopItem{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return dao.ShopItem{}, ErrShopItemNotFound
+	}
+	var updated dao.ShopItem
+	if err := s.db.Where("id = ?", item.ID).First(&updated).Error; err != nil {
+		return dao.ShopItem{}, err
+	}
+	return updated, nil
+}
+
+func (s *ShopRepositoryImpl) GetPurchasedCounts(userId uuid.UUID) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		ShopItemID uuid.UUID
+		Total      int
+	}
+	err := s.db.Model(&dao.ShopPurchase{}).
+		Select("shop_item_id, SUM(quantity) AS total").
+		Where("user_id = ?", userId).
+		Group("shop_item_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		counts[row.ShopItemID] = row.Total
+	}
+	return counts, nil
+}
+
+// Buy charges the price and hands out the contents in one transaction. The user row is locked
+// so parallel purchases cannot both pass the per user limit, stock is taken with a conditional update
+func (s *ShopRepositoryImpl) Buy(userId uuid.UUID, itemId uuid.UUID, quantity int) (dao.ShopPurchase, dao.ShopItem, error) {
+	if quantity <= 0 {
+		return dao.ShopPurchase{}, dao.ShopItem{}, ErrInvalidPurchaseQty
+	}
+
+	var item dao.ShopItem
+	var purchase dao.ShopPurchase
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userId).First(&dao.User{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ? AND enabled = ?", itemId, true).First(&item).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrShopItemNotFound
+			}
+			return err
+		}
+
+		if item.PurchaseLimit > 0 {
+			var purchased int
+			err := tx.Model(&dao.ShopPurchase{}).
+				Select("COALESCE(SUM(quantity), 0)").
+				Where("user_id = ? AND shop_item_id = ?", userId, itemId).
+				Scan(&purchased).Error
+			if err != nil {
+				return err
+			}
+			if quantity > item.PurchaseLimit-purchased {
+				return ErrPurchaseLimit
+			}
+		}
+
+		if item.Stock != nil {
+			result := tx.Model(&dao.ShopItem{}).
+				Where("id = ? AND stock >= ?", itemId, quantity).
+				Update("stock", gorm.Expr("stock - ?", quantity))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrOutOfStock
+			}
+		}
+
+		// Every total is checked, a wrapped product would turn a charge into nothing or a credit
+		priceCoins, ok := pkg.CheckedMul(item.PriceCoins, int64(quantity))
+		if !ok || (item.PriceCoins > 0 && priceCoins <= 0) {
+			return ErrInvalidPurchaseQty
+		}
+		priceItems, ok := multiplyStacks(item.PriceItems, quantity)
+		if !ok {
+			return ErrInvalidPurchaseQty
+		}
+		contents, ok := multiplyStacks(item.Contents, quantity)
+		if !ok {
+			return ErrInvalidPurchaseQty
+		}
+
+		purchase = dao.ShopPurchase{
+			UserID:     userId,
+			ShopItemID: itemId,
+			Quantity:   quantity,
+			PriceCoins: priceCoins,
+		}
+		if err := tx.Create(&purchase).Error; err != nil {
+			return err
+		}
+		refID := purchase.ID.String()
+
+		if purchase.PriceCoins > 0 {
+			if _, err := adjustCoins(tx, userId, -purchase.PriceCoins, constant.WALLET_SHOP_PURCHASE, "shop:"+refID); err != nil {
+				return err
+			}
+		}
+		for _, price := range priceItems {
+			if price.Amount == 0 {
+				continue
+			}
+			if err := adjustItemQuantity(tx, userId, price.Plant, -price.Amount, constant.LEDGER_SHOP_PURCHASE, refID); err != nil {
+				return err
+			}
+		}
+		for _, content := range contents {
+			if content.Amount == 0 {
+				continue
+			}
+			if err := adjustItemQuantity(tx, userId, content.Plant, content.Amount, constant.LEDGER_SHOP_PURCHASE, refID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return dao.ShopPurchase{}, dao.ShopItem{}, err
+	}
+	return purchase, item, nil
+}
+
+// multiplyStacks scales item stacks by quantity, ok is false when an amount does not stay positive
+func multiplyStacks(stacks []dao.ItemStack, quantity int) ([]dao.ItemStack, bool) {
+	scaled := make([]dao.ItemStack, len(stacks))
+	for i, stack := range stacks {
+		amount, ok := pkg.CheckedMul(stack.Amount, quantity)
+		if !ok || amount < 0 {
+			return nil, false
+		}
+		scaled[i] = dao.ItemStack{Plant: stack.Plant, Amount: amount}
+	}
+	return scaled, true
+}
+
+func (s *ShopRepositoryImpl) GetSoldCoinsSince(userId uuid.UUID, since time.Time) (int64, error) {
+	return soldCoinsSince(s.db, userId, since)
+}
+
+func soldCoinsSince(db *gorm.DB, userId uuid.UUID, since time.Time) (int64, error) {
+	var sold int64
+	err := db.Model(&dao.Sale{}).
+		Select("COALESCE(SUM(coins), 0)").
+		Where("user_id = ? AND created_at >= ?", userId, since).
+		Scan(&sold).Error
+	return sold, err
+}
+
+// Sell converts produce into coins, dailyCap limits coins earned per UTC day and 0 means no cap.
+// The user row is locked so parallel sales cannot both slip under the cap
+func (s *ShopRepositoryImpl) Sell(userId uuid.UUID, plant constant.Plant, quantity int, unitPrice int64, dailyCap int64) (dao.Sale, error) {
+	if quantity <= 0 || unitPrice <= 0 {
+		return dao.Sale{}, ErrInvalidPurchaseQty
+	}
+	coins, ok := pkg.CheckedMul(unitPrice, int64(quantity))
+	if !ok {
+		return dao.Sale{}, ErrInvalidPurchaseQty
+	}
+
+	sale := dao.Sale{
+		UserID:    userId,
+		Plant:     plant,
+		Quantity:  quantity,
+		UnitPrice: unitPrice,
+		Coins:     coins,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userId).First(&dao.User{}).Error; err != nil {
+			return err
+		}
+		if dailyCap > 0 {
+			sold, err := soldCoinsSince(tx, userId, pkg.StartOfDay(time.Now()))
+			if err != nil {
+				return err
+			}
+			if sale.Coins > dailyCap-sold {
+				return ErrDailySellCap
+			}
+		}
+
+		if err := tx.Create(&sale).Error; err != nil {
+			return err
+		}
+		refID := sale.ID.String()
+		if err := adjustItemQuantity(tx, userId, plant, -quantity, constant.LEDGER_SELL, refID); err != nil {
+			return err
+		}
+		_, err := adjustCoins(tx, userId, sale.Coins, constant.WALLET_SELL, "sell:"+refID)
+		return err
+	})
+	if err != nil {
+		return dao.Sale{}, err
+	}
+	return sale, nil
+}
+
+func (s *ShopRepositoryImpl) seedShopItems() {
+	var count int64
+	if err := s.db.Model(&dao.ShopItem{}).Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	if err := s.db.Create(&defaultShopItems).Error; err != nil {
+		log.Error("Error seeding shop items: ", err)
+	}
+}
+
+func ShopRepositoryInit(db *gorm.DB) *ShopRepositoryImpl {
+	if err := db.AutoMigrate(&dao.ShopItem{}, &dao.ShopPurchase{}); err != nil {
+		log.Error("Error during AutoMigrate: ", err)
+	}
+	repository := &ShopRepositoryImpl{db: db}
+	repository.seedShopItems()
+	return repository
+}
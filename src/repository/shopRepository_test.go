@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/domain/dao"
+	"math"
+	"testing"
+)
+
+// TestUpdateShopItem checks an update writes only the selected columns and can clear the stock
+func TestUpdateShopItem(t *testing.T) {
+	db := testDB(t)
+	repository := ShopRepositoryInit(db)
+
+	stock := 5
+	item, err := repository.CreateItem(dao.ShopItem{
+		Name:       "Test bundle",
+		Contents:   []dao.ItemStack{{Plant: "ROSE", Amount: 2}},
+		PriceCoins: 10,
+		Stock:      &stock,
+		Enabled:    false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Delete(&dao.ShopItem{}, "id = ?", item.ID) })
+
+	updated, err := repository.UpdateItem(dao.ShopItem{ID: item.ID, PriceCoins: 25}, "price_coins", "stock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.PriceCoins != 25 || updated.Stock != nil {
+		t.Errorf("price %d stock %v, want 25 and unlimited", updated.PriceCoins, updated.Stock)
+	}
+	if updated.Name != item.Name || updated.Enabled || len(updated.Contents) != 1 || updated.Contents[0].Amount != 2 {
+		t.Errorf("unselected columns changed: %+v", updated)
+	}
+}
+
+func TestMultiplyStacks(t *testing.T) {
+	stacks := []dao.ItemStack{{Plant: "ROSE", Amount: 3}, {Plant: "TULIP", Amount: 0}}
+	tests := []struct {
+		name     string
+		stacks   []dao.ItemStack
+		quantity int
+		want     []int
+		ok       bool
+	}{
+		{"scaled", stacks, 4, []int{12, 0}, true},
+		{"wraps", stacks, math.MaxInt/2 + 1, nil, false},
+		{"negative amount", []dao.ItemStack{{Plant: "ROSE", Amount: -1}}, 2, nil, false},
+		{"empty", nil, 5, []int{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scaled, ok := multiplyStacks(tt.stacks, tt.quantity)
+			if ok != tt.ok {
+				t.Fatalf("ok %t, want %t", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if len(scaled) != len(tt.want) {
+				t.Fatalf("%d stacks, want %d", len(scaled), len(tt.want))
+			}
+			for i, stack := range scaled {
+				if stack.Amount != tt.want[i] || stack.Plant != tt.stacks[i].Plant {
+					t.Errorf("stack %d = %+v, want %s x%d", i, stack, tt.stacks[i].Plant, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -8,17 +8,32 @@ import (
 	"github.com/nats-io/nats.go"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type TaskRepository interface {
 	Save(task *dao.TaskComplete) (dao.TaskComplete, error)
 	Get(taskId uuid.UUID) (dao.Task, error)
 	GetAllTasks() ([]dao.Task, error)
-	GetStatus(userId uuid.UUID, taskId uuid.UUID) (dao.TaskComplete, error)
-	MarkDone(userId uuid.UUID, taskId uuid.UUID) (dao.TaskComplete, error)
-	MarkClaimed(userId uuid.UUID, taskId uuid.UUID) (dao.TaskComplete, error)
+	GetActiveTasks(now time.Time) ([]dao.Task, error)
+	GetClaimedTaskIds(userId uuid.UUID) (map[uuid.UUID]bool, error)
+	CreateTask(task dao.Task) (dao.Task, error)
+	UpdateTask(task dao.Task, columns ...string) (dao.Task, error)
+	ReorderTasks(taskIds []uuid.UUID) error
+	GetTaskStats() ([]dao.TaskStats, error)
+	GetStatus(userId uuid.UUID, taskId uuid.UUID, cycle string) (dao.TaskComplete, error)
+	GetStatuses(userId uuid.UUID, cycles map[uuid.UUID]string) (map[uuid.UUID]dao.TaskComplete, error)
+	MarkDone(userId uuid.UUID, taskId uuid.UUID, cycle string) (dao.TaskComplete, error)
+	MarkClaimed(userId uuid.UUID, taskId uuid.UUID, cycle string) (dao.TaskComplete, error)
+	WithTx(tx *gorm.DB) TaskRepository
 }
 
+var (
+	ErrTaskAlreadyClaimed = errors.New("task already claimed")
+	ErrTaskNotFound       = errors.New("task not found")
+)
+
 type TaskRepositoryImpl struct {
 	db *gorm.DB
 	nc *nats.Conn
@@ -47,64 +62,251 @@ func (r *TaskRepositoryImpl) Get(taskId uuid.UUID) (dao.Task, error) {
 
 func (r *TaskRepositoryImpl) GetAllTasks() ([]dao.Task, error) {
 	var tasks []dao.Task
-	if err := r.db.Find(&tasks).Error; err != nil {
+	if err := r.db.Order("sort_order, created_at, id").Find(&tasks).Error; err != nil {
 		r.logError("Error retrieving all tasks: ", err)
 		return nil, err
 	}
 	return tasks, nil
 }
 
-func (r *TaskRepositoryImpl) GetStatus(userId uuid.UUID, taskId uuid.UUID) (dao.TaskComplete, error) {
+// GetActiveTasks returns the enabled tasks whose scheduling window contains now
+func (r *TaskRepositoryImpl) GetActiveTasks(now time.Time) ([]dao.Task, error) {
+	var tasks []dao.Task
+	err := r.db.Where("enabled AND (starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)", now, now).
+		Order("sort_order, created_at, id").
+		Find(&tasks).Error
+	if err != nil {
+		r.logError("Error retrieving active tasks: ", err)
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (r *TaskRepositoryImpl) CreateTask(task dao.Task) (dao.Task, error) {
+	// Select all columns so a task created disabled is not overwritten by the column default.
+	// A selected zero id would bypass the database default as well, so it is generated here
+	if task.ID == uuid.Nil {
+		task.ID = uuid.New()
+	}
+	if err := r.db.Select("*").Create(&task).Error; err != nil {
+		r.logError("Error creating task: ", err)
+		return dao.Task{}, err
+	}
+	return task, nil
+}
+
+// UpdateTask writes columns of task to the row with task.ID, updating through the struct keeps
+// the json serializers of Data, Prerequisites and Audience
+func (r *TaskRepositoryImpl) UpdateTask(task dao.Task, columns ...string) (dao.Task, error) {
+	result := r.db.Model(&dao.Task{ID: task.ID}).Select(columns).Updates(&task)
+	if result.Error != nil {
+		r.logError("Error updating task: ", result.Error)
+		return dao.Task{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return dao.Task{}, ErrTaskNotFound
+	}
+	return r.Get(task.ID)
+}
+
+// ReorderTasks sets the sort order of the given tasks to their position in taskIds
+func (r *TaskRepositoryImpl) ReorderTasks(taskIds []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i, taskId := range taskIds {
+			result := tx.Model(&dao.Task{}).Where("id = ?", taskId).Update("sort_order", i)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrTaskNotFound
+			}
+		}
+		return nil
+	})
+}
+
+// GetTaskStats counts completions per task and sums the plants and coins paid. Ledger ref ids are the
+// task id optionally followed by @ and the cycle key, wallet tx ids prefix them with the reason and user
+func (r *TaskRepositoryImpl) GetTaskStats() ([]dao.TaskStats, error) {
+	var stats []dao.TaskStats
+	err := r.db.Raw(`
+		SELECT t.id AS task_id,
+		       COALESCE(c.done_users, 0) AS done_users,
+		       COALESCE(c.finished_users, 0) AS finished_users,
+		       COALESCE(c.claims, 0) AS claims,
+		       COALESCE(l.rewards_paid, 0) AS rewards_paid,
+		       COALESCE(w.coins_paid, 0) AS coins_paid
+		FROM tasks t
+		LEFT JOIN (
+			SELECT task_id,
+			       COUNT(DISTINCT user_id) FILTER (WHERE status = ?) AS done_users,
+			       COUNT(DISTINCT user_id) FILTER (WHERE status = ?) AS finished_users,
+			       COUNT(*) FILTER (WHERE status = ?) AS claims
+			FROM task_completes
+			GROUP BY task_id
+		) c ON c.task_id = t.id
+		LEFT JOIN (
+			SELECT split_part(ref_id, '@', 1) AS task_id, SUM(amount) AS rewards_paid
+			FROM inventory_ledgers
+			WHERE reason = ?
+			GROUP BY 1
+		) l ON l.task_id = t.id::text
+		LEFT JOIN (
+			SELECT split_part(split_part(tx_id, ':', 3), '@', 1) AS task_id, SUM(amount) AS coins_paid
+			FROM wallet_transactions
+			WHERE reason = ?
+			GROUP BY 1
+		) w ON w.task_id = t.id::text
+		ORDER BY t.sort_order, t.created_at, t.id`,
+		constant.TASK_COMPLETE_DONE, constant.TASK_COMPLETE_FINISHED, constant.TASK_COMPLETE_FINISHED,
+		constant.LEDGER_TASK_CLAIM, constant.WALLET_TASK_CLAIM).Scan(&stats).Error
+	if err != nil {
+		r.logError("Error retrieving task stats: ", err)
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetClaimedTaskIds returns the tasks the user claimed in at least one cycle
+func (r *TaskRepositoryImpl) GetClaimedTaskIds(userId uuid.UUID) (map[uuid.UUID]bool, error) {
+	var taskIds []uuid.UUID
+	err := r.db.Model(&dao.TaskComplete{}).
+		Where("user_id = ? AND status = ?", userId, constant.TASK_COMPLETE_FINISHED).
+		Distinct().
+		Pluck("task_id", &taskIds).Error
+	if err != nil {
+		r.logError("Error retrieving claimed tasks: ", err)
+		return nil, err
+	}
+	claimed := make(map[uuid.UUID]bool, len(taskIds))
+	for _, taskId := range taskIds {
+		claimed[taskId] = true
+	}
+	return claimed, nil
+}
+
+// GetStatus returns the status of the task in cycle, see dao.Task.Cycle
+func (r *TaskRepositoryImpl) GetStatus(userId uuid.UUID, taskId uuid.UUID, cycle string) (dao.TaskComplete, error) {
 	var taskComplete dao.TaskComplete
-	if err := r.db.Where("user_id = ? AND task_id = ?", userId, taskId).First(&taskComplete).Error; err != nil {
-		r.logError("Error retrieving task status: ", err)
+	if err := r.db.Where("user_id = ? AND task_id = ? AND cycle = ?", userId, taskId, cycle).First(&taskComplete).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			r.logError("Error retrieving task status: ", err)
+		}
 		return dao.TaskComplete{}, err
 	}
 	return taskComplete, nil
 }
 
-func (r *TaskRepositoryImpl) MarkDone(userId uuid.UUID, taskId uuid.UUID) (dao.TaskComplete, error) {
+// GetStatuses loads the statuses of many tasks in one query, cycles maps each task to its current cycle key.
+// Tasks the user has not started are absent from the result
+func (r *TaskRepositoryImpl) GetStatuses(userId uuid.UUID, cycles map[uuid.UUID]string) (map[uuid.UUID]dao.TaskComplete, error) {
+	statuses := make(map[uuid.UUID]dao.TaskComplete, len(cycles))
+	if len(cycles) == 0 {
+		return statuses, nil
+	}
+	pairs := make([][]interface{}, 0, len(cycles))
+	for taskId, cycle := range cycles {
+		pairs = append(pairs, []interface{}{taskId, cycle})
+	}
+	var taskCompletes []dao.TaskComplete
+	if err := r.db.Where("user_id = ? AND (task_id, cycle) IN ?", userId, pairs).Find(&taskCompletes).Error; err != nil {
+		r.logError("Error retrieving task statuses: ", err)
+		return nil, err
+	}
+	for _, taskComplete := range taskCompletes {
+		statuses[taskComplete.TaskID] = taskComplete
+	}
+	return statuses, nil
+}
+
+func (r *TaskRepositoryImpl) MarkDone(userId uuid.UUID, taskId uuid.UUID, cycle string) (dao.TaskComplete, error) {
 	taskComplete := &dao.TaskComplete{
 		UserID: userId,
 		TaskID: taskId,
+		Cycle:  cycle,
 		Status: constant.TASK_COMPLETE_DONE,
 	}
 	return r.Save(taskComplete)
 }
 
-func (r *TaskRepositoryImpl) MarkClaimed(userId uuid.UUID, taskId uuid.UUID) (dao.TaskComplete, error) {
-	var taskComplete dao.TaskComplete
-	err := r.db.Where("user_id = ? AND task_id = ?", userId, taskId).First(&taskComplete).Error
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		r.logError("Error retrieving claimed task: ", err)
-		return dao.TaskComplete{}, err
+// MarkClaimed moves the task to finished in cycle with a single upsert, a parallel claim gets ErrTaskAlreadyClaimed
+func (r *TaskRepositoryImpl) MarkClaimed(userId uuid.UUID, taskId uuid.UUID, cycle string) (dao.TaskComplete, error) {
+	now := time.Now()
+	taskComplete := dao.TaskComplete{
+		UserID:    userId,
+		TaskID:    taskId,
+		Cycle:     cycle,
+		Status:    constant.TASK_COMPLETE_FINISHED,
+		ClaimedAt: &now,
 	}
-
-	// If task not found, create a new one
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		taskComplete = dao.TaskComplete{
-			UserID: userId,
-			TaskID: taskId,
-			Status: constant.TASK_COMPLETE_FINISHED,
-		}
-		if err := r.db.Create(&taskComplete).Error; err != nil {
-			r.logError("Error creating claimed task: ", err)
-			return dao.TaskComplete{}, err
-		}
-	} else {
-		// If task already exists, update its status
-		taskComplete.Status = constant.TASK_COMPLETE_FINISHED
-		if err := r.db.Save(&taskComplete).Error; err != nil {
-			r.logError("Error updating claimed task: ", err)
-			return dao.TaskComplete{}, err
-		}
+	result := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "task_id"}, {Name: "cycle"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"status":     constant.TASK_COMPLETE_FINISHED,
+			"claimed_at": now,
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Neq{Column: clause.Column{Table: "task_completes", Name: "status"}, Value: constant.TASK_COMPLETE_FINISHED},
+		}},
+	}).Create(&taskComplete)
+	if result.Error != nil {
+		r.logError("Error updating claimed task: ", result.Error)
+		return dao.TaskComplete{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return dao.TaskComplete{}, ErrTaskAlreadyClaimed
 	}
 	return taskComplete, nil
 }
 
+// WithTx returns a repository bound to an open transaction
+func (r *TaskRepositoryImpl) WithTx(tx *gorm.DB) TaskRepository {
+	return &TaskRepositoryImpl{db: tx, nc: r.nc}
+}
+
+// dedupeTaskCompletes keeps one completion per user, task and cycle, the furthest along and then the
+// oldest. Concurrent claims before the unique index existed left duplicates the index can not be built over
+func dedupeTaskCompletes(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&dao.TaskComplete{}) ||
+		db.Migrator().HasIndex(&dao.TaskComplete{}, "idx_task_complete_user_task_cycle") {
+		return nil
+	}
+	// Tables from before recurring tasks get the cycle column when migrating, all their rows in cycle ''
+	cycle, sameCycle := "", ""
+	if db.Migrator().HasColumn(&dao.TaskComplete{}, "Cycle") {
+		cycle, sameCycle = ", cycle", " AND c.cycle = d.cycle"
+	}
+	result := db.Exec(`
+		DELETE FROM task_completes c
+		USING (
+			SELECT user_id, task_id`+cycle+`, (ARRAY_AGG(id ORDER BY
+				CASE status WHEN ? THEN 0 WHEN ? THEN 1 ELSE 2 END, created_at, id))[1] AS keep_id
+			FROM task_completes GROUP BY user_id, task_id`+cycle+` HAVING COUNT(*) > 1
+		) d
+		WHERE c.user_id = d.user_id AND c.task_id = d.task_id AND c.id <> d.keep_id`+sameCycle,
+		constant.TASK_COMPLETE_FINISHED, constant.TASK_COMPLETE_DONE)
+	if result.RowsAffected > 0 {
+		log.Warnf("Removed %d duplicate task completions", result.RowsAffected)
+	}
+	return result.Error
+}
+
 func TaskRepositoryInit(db *gorm.DB, nc *nats.Conn) *TaskRepositoryImpl {
+	if err := dedupeTaskCompletes(db); err != nil {
+		log.Fatal("Error removing duplicate task completions: ", err)
+	}
+	// MarkClaimed upserts on (user_id, task_id, cycle), without the unique index every claim fails
 	if err := db.AutoMigrate(&dao.Task{}, &dao.TaskComplete{}); err != nil {
-		log.Error("Error during AutoMigrate: ", err)
+		log.Fatal("Error during AutoMigrate: ", err)
+	}
+	if !db.Migrator().HasIndex(&dao.TaskComplete{}, "idx_task_complete_user_task_cycle") {
+		log.Fatal("Unique index idx_task_complete_user_task_cycle is missing")
+	}
+	// Completion is unique per cycle now, the old per task index would block the next cycle
+	if db.Migrator().HasIndex(&dao.TaskComplete{}, "idx_task_complete_user_task") {
+		if err := db.Migrator().DropIndex(&dao.TaskComplete{}, "idx_task_complete_user_task"); err != nil {
+			log.Error("Error dropping task completion index: ", err)
+		}
 	}
 	return &TaskRepositoryImpl{
 		db: db,
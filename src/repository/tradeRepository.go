@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/pkg"
+	"errors"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	ErrDailyGiftLimit     = errors.New("daily gift limit reached")
+	ErrTradeOfferNotFound = errors.New("trade offer not found")
+)
+
+type TradeRepository interface {
+	SendGift(gift dao.Gift, dailyLimit int) (dao.Gift, error)
+	GetGiftedSince(userId uuid.UUID, since time.Time) (int, error)
+	CreateOffer(offer dao.TradeOffer) (dao.TradeOffer, error)
+	GetPendingOffers(userId uuid.UUID) ([]dao.TradeOffer, error)
+	AcceptOffer(offerId uuid.UUID, userId uuid.UUID) (dao.TradeOffer, error)
+	RejectOffer(offerId uuid.UUID, userId uuid.UUID) (dao.TradeOffer, error)
+	CancelOffer(offerId uuid.UUID, userId uuid.UUID) (dao.TradeOffer, error)
+}
+
+type TradeRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (t *TradeRepositoryImpl) GetGiftedSince(userId uuid.UUID, since time.Time) (int, error) {
+	return giftedSince(t.db, userId, since)
+}
+
+func giftedSince(db *gorm.DB, userId uuid.UUID, since time.Time) (int, error) {
+	var gifted int
+	err := db.Model(&dao.Gift{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("from_user_id = ? AND created_at >= ?", userId, since).
+		Scan(&gifted).Error
+	return gifted, err
+}
+
+// SendGift moves items from sender to receiver, dailyLimit caps items sent per UTC day.
+// The sender row is locked so parallel gifts cannot both slip under the limit
+func (t *TradeRepositoryImpl) SendGift(gift dao.Gift, dailyLimit int) (dao.Gift, error) {
+	err := t.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", gift.FromUserID).First(&dao.User{}).Error; err != nil {
+			return err
+		}
+		gifted, err := giftedSince(tx, gift.FromUserID, pkg.StartOfDay(time.Now()))
+		if err != nil {
+			return err
+		}
+		if gift.Quantity > dailyLimit-gifted {
+			return ErrDailyGiftLimit
+		}
+
+		if err := tx.Create(&gift).Error; err != nil {
+			return err
+		}
+		refID := gift.ID.String()
+		if err := adjustItemQuantity(tx, gift.FromUserID, gift.Plant, -gift.Quantity, constant.LEDGER_GIFT, refID); err != nil {
+			return err
+		}
+		return adjustItemQuantity(tx, gift.ToUserID, gift.Plant, gift.Quantity, constant.LEDGER_GIFT, refID)
+	})
+	if err != nil {
+		return dao.Gift{}, err
+	}
+	return gift, nil
+}
+
+func (t *TradeRepositoryImpl) CreateOffer(offer dao.TradeOffer) (dao.TradeOffer, error) {
+	offer.Status = constant.TRADE_PENDING
+	if err := t.db.Create(&offer).Error; err != nil {
+		log.Error("Error creating trade offer: ", err)
+		return dao.TradeOffer{}, err
+	}
+	return offer, nil
+}
+
+func (t *TradeRepositoryImpl) GetPendingOffers(userId uuid.UUID) ([]dao.TradeOffer, error) {
+	var offers []dao.TradeOffer
+	err := t.db.Where("(from_user_id = ? OR to_user_id = ?) AND status = ?", userId, userId, constant.TRADE_PENDING).
+		Order("created_at DESC").
+		Find(&offers).Error
+	if err != nil {
+		log.Error("Error getting trade offers: ", err)
+		return nil, err
+	}
+	return offers, nil
+}
+
+// AcceptOffer settles a pending offer addressed to userId, both sides move in one transaction
+// and a missing item on either side rolls the whole settlement back
+func (t *TradeRepositoryImpl) AcceptOffer(offerId uuid.UUID, userId uuid.UUID) (dao.TradeOffer, error) {
+	var offer dao.TradeOffer
+	err := t.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		offer, err = resolveOffer(tx, offerId, "to_user_id", userId, constant.TRADE_ACCEPTED)
+		if err != nil {
+			return err
+		}
+		refID := offer.ID.String()
+		for _, stack := range offer.Give {
+			if err := moveItems(tx, offer.FromUserID, offer.ToUserID, stack, refID); err != nil {
+				return err
+			}
+		}
+		for _, stack := range offer.Want {
+			if err := moveItems(tx, offer.ToUserID, offer.FromUserID, stack, refID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return dao.TradeOffer{}, err
+	}
+	return offer, nil
+}
+
+func (t *TradeRepositoryImpl) RejectOffer(offerId uuid.UUID, userId uuid.UUID) (dao.TradeOffer, error) {
+	return resolveOffer(t.db, offerId, "to_user_id", userId, constant.TRADE_REJECTED)
+}
+
+func (t *TradeRepositoryImpl) CancelOffer(offerId uuid.UUID, userId uuid.UUID) (dao.TradeOffer, error) {
+	return resolveOffer(t.db, offerId, "from_user_id", userId, constant.TRADE_CANCELLED)
+}
+
+// resolveOffer moves a pending offer owned by userId through userColumn into status,
+// the status guard in the update makes concurrent resolutions of one offer exclusive
+func resolveOffer(db *gorm.DB, offerId uuid.UUID, userColumn string, userId uuid.UUID, status constant.TradeStatus) (dao.TradeOffer, error) {
+	now := time.Now()
+	var offers []dao.TradeOffer
+	err := db.Model(&offers).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND "+userColumn+" = ? AND status = ?", offerId, userId, constant.TRADE_PENDING).
+		Updates(map[string]interface{}{"status": status, "resolved_at": now}).Error
+	if err != nil {
+		return dao.TradeOffer{}, err
+	}
+	if len(offers) == 0 {
+		return dao.TradeOffer{}, ErrTradeOfferNotFound
+	}
+	return offers[0], nil
+}
+
+func moveItems(tx *gorm.DB, fromUserId, toUserId uuid.UUID, stack dao.ItemStack, refID string) error {
+	if err := adjustItemQuantity(tx, fromUserId, stack.Plant, -stack.Amount, constant.LEDGER_TRADE, refID); err != nil {
+		return err
+	}
+	return adjustItemQuantity(tx, toUserId, stack.Plant, stack.Amount, constant.LEDGER_TRADE, refID)
+}
+
+func TradeRepositoryInit(db *gorm.DB) *TradeRepositoryImpl {
+	if err := db.AutoMigrate(&dao.Gift{}, &dao.TradeOffer{}); err != nil {
+		log.Error("Error during AutoMigrate: ", err)
+	}
+	return &TradeRepositoryImpl{db: db}
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+)
+
+// TransactionRepository lets services run several repository calls atomically,
+// repositories join the transaction through their WithTx method
+type TransactionRepository interface {
+	Transaction(fn func(tx *gorm.DB) error) error
+}
+
+type TransactionRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (r *TransactionRepositoryImpl) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
+func TransactionRepositoryInit(db *gorm.DB) *TransactionRepositoryImpl {
+	return &TransactionRepositoryImpl{db: db}
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strconv"
+)
+
+// Levels seeded into an empty farm_levels table, matching the former hardcoded limits
+var defaultFarmLevels = []dao.FarmLevel{
+	{Lvl: 1, MaxFields: 4},
+	{Lvl: 2, MaxFields: 8, Cost: []dao.ItemStack{{Plant: constant.MONEY, Amount: 10}}},
+	{Lvl: 3, MaxFields: 16, Cost: []dao.ItemStack{{Plant: constant.MONEY, Amount: 30}}},
+}
+
+type UpgradeRepository interface {
+	GetFarmLevels() ([]dao.FarmLevel, error)
+	GetFarmLevel(lvl int) (dao.FarmLevel, error)
+	SaveFarmLevel(level dao.FarmLevel) (dao.FarmLevel, error)
+	BuyFarmLevel(userId uuid.UUID, level dao.FarmLevel) error
+}
+
+type UpgradeRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (u *UpgradeRepositoryImpl) GetFarmLevels() ([]dao.FarmLevel, error) {
+	var levels []dao.FarmLevel
+	if err := u.db.Order("lvl").Find(&levels).Error; err != nil {
+		log.Error("Error getting farm levels: ", err)
+		return nil, err
+	}
+	return levels, nil
+}
+
+func (u *UpgradeRepositoryImpl) GetFarmLevel(lvl int) (dao.FarmLevel, error) {
+	var level dao.FarmLevel
+	if err := u.db.Where("lvl = ?", lvl).First(&level).Error; err != nil {
+		return dao.FarmLevel{}, err
+	}
+	return level, nil
+}
+
+// SaveFarmLevel creates or replaces the level with level.Lvl, users already on it keep it after an edit
+func (u *UpgradeRepositoryImpl) SaveFarmLevel(level dao.FarmLevel) (dao.FarmLevel, error) {
+	err := u.db.Clauses(clause.OnConflict{UpdateAll: true}).Select("*").Create(&level).Error
+	if err != nil {
+		log.Error("Error saving farm level: ", err)
+		return dao.FarmLevel{}, err
+	}
+	return level, nil
+}
+
+// BuyFarmLevel charges the level item and coin cost and raises the user from level.Lvl-1 to level.Lvl
+func (u *UpgradeRepositoryImpl) BuyFarmLevel(userId uuid.UUID, level dao.FarmLevel) error {
+	return u.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&dao.UserUpgrade{}).
+			Where("user_id = ? AND farm_lvl = ?", userId, level.Lvl-1).
+			Update("farm_lvl", level.Lvl)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("farm level changed concurrently")
+		}
+		for _, cost := range level.Cost {
+			if cost.Amount == 0 {
+				continue
+			}
+			if err := adjustItemQuantity(tx, userId, cost.Plant, -cost.Amount, constant.LEDGER_FARM_UPGRADE, strconv.Itoa(level.Lvl)); err != nil {
+				return err
+			}
+		}
+		if level.CoinCost > 0 {
+			txID := fmt.Sprintf("farm_upgrade:%s:%d", userId, level.Lvl)
+			if _, err := adjustCoins(tx, userId, -level.CoinCost, constant.WALLET_FARM_UPGRADE, txID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (u *UpgradeRepositoryImpl) seedFarmLevels() {
+	var count int64
+	if err := u.db.Model(&dao.FarmLevel{}).Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	if err := u.db.Create(&defaultFarmLevels).Error; err != nil {
+		log.Error("Error seeding farm levels: ", err)
+	}
+}
+
+func UpgradeRepositoryInit(db *gorm.DB) *UpgradeRepositoryImpl {
+	if err := db.AutoMigrate(&dao.FarmLevel{}); err != nil {
+		log.Error("Error during AutoMigrate: ", err)
+	}
+	repository := &UpgradeRepositoryImpl{db: db}
+	repository.seedFarmLevels()
+	return repository
+}
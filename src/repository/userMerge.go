@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+// MergeUsers folds the duplicate account source into target in one transaction: inventory and coins are
+// moved through the ledgers, the farm keeps the higher level and the summed xp, fields move into free
+// slots of target and the seed of fields without a slot is refunded, referrals, auth methods and the
+// Telegram id are reassigned. Counters, task completions and trade history stay with source, which is
+// marked as merged and left out of the leaderboards. Accounts that both have an auth method of the same
+// kind are not merged, as target would end up with two
+func (u *UserRepositoryImpl) MergeUsers(sourceId, targetId uuid.UUID) error {
+	if sourceId == targetId {
+		return fmt.Errorf("can not merge an account into itself")
+	}
+	refID := "merge:" + sourceId.String()
+	return u.db.Transaction(func(tx *gorm.DB) error {
+		// Both rows locked in id order, so merges running the other way round can not deadlock
+		var users []dao.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uuid.UUID{sourceId, targetId}).Order("id").Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) != 2 {
+			return gorm.ErrRecordNotFound
+		}
+		var source dao.User
+		for _, user := range users {
+			if user.MergedIntoID != nil {
+				return ErrAlreadyMerged
+			}
+			if user.ID == sourceId {
+				source = user
+			}
+		}
+
+		var shared []string
+		err := tx.Model(&dao.UserAuth{}).Where("user_id IN ?", []uuid.UUID{sourceId, targetId}).
+			Group("auth_method").Having("COUNT(DISTINCT user_id) > 1").Pluck("auth_method", &shared).Error
+		if err != nil {
+			return err
+		}
+		if len(shared) > 0 {
+			return fmt.Errorf("%w: %s", ErrMergeMethodTaken, strings.Join(shared, ", "))
+		}
+
+		if err := mergeInventory(tx, sourceId, targetId, refID); err != nil {
+			return err
+		}
+		if source.Coins > 0 {
+			if _, err := adjustCoins(tx, sourceId, -source.Coins, constant.WALLET_ACCOUNT_MERGE, refID+":out"); err != nil {
+				return err
+			}
+			if _, err := adjustCoins(tx, targetId, source.Coins, constant.WALLET_ACCOUNT_MERGE, refID+":in"); err != nil {
+				return err
+			}
+		}
+		maxFields, err := mergeUpgrade(tx, sourceId, targetId)
+		if err != nil {
+			return err
+		}
+		if err := mergeFields(tx, sourceId, targetId, maxFields, refID); err != nil {
+			return err
+		}
+		if err := mergeReferrals(tx, sourceId, targetId); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&dao.UserAuth{}).Where("user_id = ?", sourceId).Update("user_id", targetId).Error; err != nil {
+			return err
+		}
+		// The Telegram id follows the telegram auth method, source had the only one
+		if source.TgId != 0 {
+			if err := tx.Model(&dao.User{}).Where("id = ?", targetId).Update("tg_id", source.TgId).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&dao.User{}).Where("id = ?", sourceId).
+			Updates(map[string]interface{}{"merged_into_id": targetId, "tg_id": 0}).Error
+	})
+}
+
+func mergeInventory(tx *gorm.DB, sourceId, targetId uuid.UUID, refID string) error {
+	var items []dao.InventoryItem
+	if err := tx.Where("user_id = ? AND quantity > 0", sourceId).Order("plant").Find(&items).Error; err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := adjustItemQuantity(tx, sourceId, item.Plant, -item.Quantity, constant.LEDGER_ACCOUNT_MERGE, refID); err != nil {
+			return err
+		}
+		if err := adjustItemQuantity(tx, targetId, item.Plant, item.Quantity, constant.LEDGER_ACCOUNT_MERGE, refID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeUpgrade gives target the higher farm level and the xp of both, it returns the field count of the level
+func mergeUpgrade(tx *gorm.DB, sourceId, targetId uuid.UUID) (int, error) {
+	if err := lockUserUpgrade(tx, targetId); err != nil {
+		return 0, err
+	}
+	var sourceUpgrades []dao.UserUpgrade
+	if err := tx.Where("user_id = ?", sourceId).Limit(1).Find(&sourceUpgrades).Error; err != nil {
+		return 0, err
+	}
+	if len(sourceUpgrades) > 0 {
+		source := sourceUpgrades[0]
+		err := tx.Model(&dao.UserUpgrade{}).Where("user_id = ?", targetId).Updates(map[string]interface{}{
+			"farm_lvl": gorm.Expr("GREATEST(farm_lvl, ?)", source.FarmLvl),
+			"farm_xp":  gorm.Expr("farm_xp + ?", source.FarmXp),
+		}).Error
+		if err != nil {
+			return 0, err
+		}
+		if err := tx.Model(&dao.UserUpgrade{}).Where("user_id = ?", sourceId).Update("farm_xp", 0).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	var target dao.UserUpgrade
+	if err := tx.Where("user_id = ?", targetId).First(&target).Error; err != nil {
+		return 0, err
+	}
+	var levels []dao.FarmLevel
+	if err := tx.Where("lvl = ?", target.FarmLvl).Limit(1).Find(&levels).Error; err != nil {
+		return 0, err
+	}
+	if len(levels) == 0 {
+		return 0, fmt.Errorf("farm level %d not found", target.FarmLvl)
+	}
+	return levels[0].MaxFields, nil
+}
+
+// mergeFields moves the planted fields of source into the free slots of target, keeping their planting
+// time. Fields left without a slot are cleared and their seed goes back to target's inventory
+func mergeFields(tx *gorm.DB, sourceId, targetId uuid.UUID, maxFields int, refID string) error {
+	var occupied []int
+	if err := tx.Model(&dao.UserField{}).Where("user_id = ?", targetId).Pluck("field_id", &occupied).Error; err != nil {
+		return err
+	}
+	taken := make(map[int]bool, len(occupied))
+	for _, fieldID := range occupied {
+		taken[fieldID] = true
+	}
+	var fields []dao.UserField
+	if err := tx.Where("user_id = ?", sourceId).Order("field_id").Find(&fields).Error; err != nil {
+		return err
+	}
+
+	slot := 0
+	for _, field := range fields {
+		for slot < maxFields && taken[slot] {
+			slot++
+		}
+		if slot < maxFields {
+			err := tx.Model(&dao.UserField{}).Where("id = ?", field.ID).
+				Updates(map[string]interface{}{"user_id": targetId, "field_id": slot}).Error
+			if err != nil {
+				return err
+			}
+			taken[slot] = true
+			continue
+		}
+		if err := tx.Where("id = ?", field.ID).Delete(&dao.UserField{}).Error; err != nil {
+			return err
+		}
+		if err := adjustItemQuantity(tx, targetId, field.Plant, 1, constant.LEDGER_ACCOUNT_MERGE, refID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeReferrals makes target the referrer of everyone source invited and keeps source's own referrer
+// when target has none. Links that would make target its own referral are dropped
+func mergeReferrals(tx *gorm.DB, sourceId, targetId uuid.UUID) error {
+	if err := tx.Where("(referrer_id = ? AND referral_id = ?) OR (referrer_id = ? AND referral_id = ?)",
+		sourceId, targetId, targetId, sourceId).Delete(&dao.UserReferral{}).Error; err != nil {
+		return err
+	}
+
+	moved := tx.Model(&dao.UserReferral{}).Where("referrer_id = ?", sourceId).Update("referrer_id", targetId)
+	if moved.Error != nil {
+		return moved.Error
+	}
+	if moved.RowsAffected > 0 {
+		if err := moveAllTimeScore(tx, sourceId, targetId, constant.LEADERBOARD_REFERRALS, moved.RowsAffected); err != nil {
+			return err
+		}
+	}
+	// Payouts between the two accounts stay as they were recorded
+	if err := tx.Model(&dao.ReferralPayout{}).Where("referrer_id = ? AND referral_id <> ?", sourceId, targetId).
+		Update("referrer_id", targetId).Error; err != nil {
+		return err
+	}
+
+	var targetReferrals int64
+	if err := tx.Model(&dao.UserReferral{}).Where("referral_id = ?", targetId).Count(&targetReferrals).Error; err != nil {
+		return err
+	}
+	if targetReferrals > 0 {
+		return tx.Where("referral_id = ?", sourceId).Delete(&dao.UserReferral{}).Error
+	}
+	if err := tx.Model(&dao.UserReferral{}).Where("referral_id = ?", sourceId).Update("referral_id", targetId).Error; err != nil {
+		return err
+	}
+	// Tiers already paid for source stay paid for target
+	return tx.Model(&dao.ReferralPayout{}).Where("referral_id = ? AND referrer_id <> ?", sourceId, targetId).
+		Update("referral_id", targetId).Error
+}
+
+// moveAllTimeScore shifts score between users on the all time board only, weekly boards keep what was
+// earned in their week
+func moveAllTimeScore(tx *gorm.DB, fromId, toId uuid.UUID, board constant.LeaderboardBoard, amount int64) error {
+	for _, move := range []struct {
+		userId uuid.UUID
+		amount int64
+	}{{fromId, -amount}, {toId, amount}} {
+		err := tx.Exec(`
+			INSERT INTO leaderboard_scores (board, period, user_id, score, updated_at)
+			VALUES (?, ?, ?, ?, NOW())
+			ON CONFLICT (board, period, user_id) DO UPDATE
+			SET score = leaderboard_scores.score + EXCLUDED.score, updated_at = EXCLUDED.updated_at`,
+			board, constant.LEADERBOARD_ALL_TIME, move.userId, move.amount).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"math/rand"
+	"testing"
+)
+
+// mergeTestDB migrates every table MergeUsers writes to
+func mergeTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := testDB(t)
+	WalletRepositoryInit(db)
+	UpgradeRepositoryInit(db)
+	ReferralRepositoryInit(db)
+	LeaderboardRepositoryInit(db)
+	return db
+}
+
+func TestMergeUsers(t *testing.T) {
+	db := mergeTestDB(t)
+	users := &UserRepositoryImpl{db: db}
+	inventory := &InventoryRepositoryImpl{db: db}
+	leaderboard := &LeaderboardRepositoryImpl{db: db}
+	plant := constant.Plant("TEST_PLANT")
+
+	source, target := testUser(t, db), testUser(t, db)
+	tgId := rand.Int63n(1<<40) + 1
+	if err := db.Model(&source).Update("tg_id", tgId).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.LinkAuth(source.ID, uuid.NewString(), constant.Telegram); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.LinkAuth(target.ID, uuid.NewString()+"@example.com", constant.Email); err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []dao.User{source, target} {
+		if err := inventory.AdjustItemQuantity(user.ID, plant, 3, constant.LEDGER_ADMIN_GRANT, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := adjustCoins(db, source.ID, 40, constant.WALLET_ACCOUNT_MERGE, "test:"+source.ID.String()); err != nil {
+		t.Fatal(err)
+	}
+	// High enough to top the board unless merged accounts are left out
+	if err := addLeaderboardScore(db, source.ID, constant.LEADERBOARD_HARVESTED, 1<<50); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := users.MergeUsers(source.ID, target.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if quantity, _ := inventory.GetItemQuantity(target.ID, plant); quantity != 6 {
+		t.Errorf("target holds %d, want 6", quantity)
+	}
+	if quantity, _ := inventory.GetItemQuantity(source.ID, plant); quantity != 0 {
+		t.Errorf("source holds %d, want 0", quantity)
+	}
+	var merged, kept dao.User
+	db.First(&merged, "id = ?", source.ID)
+	db.First(&kept, "id = ?", target.ID)
+	if merged.MergedIntoID == nil || *merged.MergedIntoID != target.ID || merged.Coins != 0 || merged.TgId != 0 {
+		t.Errorf("source after merge: merged into %v, %d coins, tg id %d", merged.MergedIntoID, merged.Coins, merged.TgId)
+	}
+	if kept.Coins != 40 || kept.TgId != tgId {
+		t.Errorf("target after merge: %d coins, tg id %d, want 40 and %d", kept.Coins, kept.TgId, tgId)
+	}
+	var methods []string
+	db.Model(&dao.UserAuth{}).Where("user_id = ?", target.ID).Order("auth_method").Pluck("auth_method", &methods)
+	if len(methods) != 2 || methods[0] != constant.Email || methods[1] != constant.Telegram {
+		t.Errorf("target auth methods %v, want email and telegram", methods)
+	}
+	top, err := leaderboard.GetTop(constant.LEADERBOARD_HARVESTED, constant.LEADERBOARD_ALL_TIME, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) > 0 && top[0].UserID == source.ID {
+		t.Error("merged account is still on the leaderboard")
+	}
+}
+
+func TestMergeUsersRefused(t *testing.T) {
+	db := mergeTestDB(t)
+	users := &UserRepositoryImpl{db: db}
+
+	tests := []struct {
+		name  string
+		setup func(t *testing.T) (uuid.UUID, uuid.UUID)
+		want  error
+	}{
+		{"both have the method", func(t *testing.T) (uuid.UUID, uuid.UUID) {
+			source, target := testUser(t, db), testUser(t, db)
+			for _, user := range []dao.User{source, target} {
+				if _, err := users.LinkAuth(user.ID, uuid.NewString(), constant.Telegram); err != nil {
+					t.Fatal(err)
+				}
+			}
+			return source.ID, target.ID
+		}, ErrMergeMethodTaken},
+		{"source already merged", func(t *testing.T) (uuid.UUID, uuid.UUID) {
+			source, target := testUser(t, db), testUser(t, db)
+			if err := db.Model(&source).Update("merged_into_id", target.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			return source.ID, target.ID
+		}, ErrAlreadyMerged},
+		{"target already merged", func(t *testing.T) (uuid.UUID, uuid.UUID) {
+			source, target, other := testUser(t, db), testUser(t, db), testUser(t, db)
+			if err := db.Model(&target).Update("merged_into_id", other.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			return source.ID, target.ID
+		}, ErrAlreadyMerged},
+		{"unknown target", func(t *testing.T) (uuid.UUID, uuid.UUID) {
+			return testUser(t, db).ID, uuid.New()
+		}, gorm.ErrRecordNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceId, targetId := tt.setup(t)
+			if err := users.MergeUsers(sourceId, targetId); !errors.Is(err, tt.want) {
+				t.Fatalf("error %v, want %v", err, tt.want)
+			}
+			var source dao.User
+			db.First(&source, "id = ?", sourceId)
+			if tt.want != ErrAlreadyMerged && source.MergedIntoID != nil {
+				t.Error("refused merge marked the source as merged")
+			}
+		})
+	}
+
+	t.Run("into itself", func(t *testing.T) {
+		user := testUser(t, db)
+		if err := users.MergeUsers(user.ID, user.ID); err == nil {
+			t.Fatal("merged an account into itself")
+		}
+	})
+}
+
+func TestUnlinkTelegramClearsTgId(t *testing.T) {
+	db := testDB(t)
+	users := &UserRepositoryImpl{db: db}
+	user := testUser(t, db)
+	if err := db.Model(&user).Update("tg_id", rand.Int63n(1<<40)+1).Error; err != nil {
+		t.Fatal(err)
+	}
+	telegram, err := users.LinkAuth(user.ID, uuid.NewString(), constant.Telegram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.LinkAuth(user.ID, uuid.NewString()+"@example.com", constant.Email); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := users.UnlinkAuth(user.ID, telegram.ID); err != nil {
+		t.Fatal(err)
+	}
+	var stored dao.User
+	db.First(&stored, "id = ?", user.ID)
+	if stored.TgId != 0 {
+		t.Errorf("tg id %d left after unlinking Telegram", stored.TgId)
+	}
+}
@@ -1,10 +1,14 @@
 package repository
 
 import (
+	"crazyfarmbackend/src/constant"
 	"crazyfarmbackend/src/domain/dao"
+	"errors"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type UserRepository interface {
@@ -19,9 +23,28 @@ type UserRepository interface {
 	UpdateUserFields(userId uuid.UUID, updates map[string]interface{}) (dao.User, error)
 	GetUserUpgrade(userId uuid.UUID) (dao.UserUpgrade, error)
 	GetMyReferrals(userId uuid.UUID) ([]dao.User, error)
-	SetReferrals(userId, referrerId uuid.UUID) (dao.UserReferral, error)
+	GetReferralTimesSince(userId uuid.UUID, since time.Time) ([]time.Time, error)
+	SetReferrals(userId, referrerId uuid.UUID, premium bool) (dao.UserReferral, error)
+	GetMyReferrer(userId uuid.UUID) (*dao.User, error)
+	IsFriend(userId, otherId uuid.UUID) (bool, error)
+	FindAuth(data, method string) (*dao.UserAuth, error)
+	GetAuthMethods(userId uuid.UUID) ([]dao.UserAuth, error)
+	LinkAuth(userId uuid.UUID, data, method string) (dao.UserAuth, error)
+	UnlinkAuth(userId, authId uuid.UUID) error
+	MergeUsers(sourceId, targetId uuid.UUID) error
+	SetRole(userId uuid.UUID, role constant.Role) (dao.User, error)
+	GetStaff() ([]dao.User, error)
 }
 
+var (
+	ErrAuthTaken        = errors.New("identity belongs to another account")
+	ErrAuthMethodLinked = errors.New("auth method is already linked")
+	ErrAuthNotFound     = errors.New("auth method not found")
+	ErrLastAuthMethod   = errors.New("last auth method can not be unlinked")
+	ErrAlreadyMerged    = errors.New("account was already merged")
+	ErrMergeMethodTaken = errors.New("both accounts have an auth method of the same kind")
+)
+
 type UserRepositoryImpl struct {
 	db *gorm.DB
 }
@@ -73,15 +96,24 @@ func (u *UserRepositoryImpl) GetByAuthId(ID uuid.UUID) (dao.UserAuth, error) {
 }
 
 func (u *UserRepositoryImpl) Create(data, method string) (dao.User, error) {
+	authMethod, err := u.createAuth(data, method)
+	if err != nil {
+		return dao.User{}, err
+	}
+	return authMethod.User, nil
+}
+
+// createAuth creates a user together with its first auth method
+func (u *UserRepositoryImpl) createAuth(data, method string) (dao.UserAuth, error) {
 	user := dao.User{}
 	if _, err := u.Save(&user); err != nil {
-		return dao.User{}, err
+		return dao.UserAuth{}, err
 	}
 	authMethod := dao.UserAuth{AuthData: data, AuthMethod: method, User: user}
 	if err := u.db.Save(&authMethod).Error; err != nil {
-		return dao.User{}, u.logAndReturnError("Error creating user: ", err)
+		return dao.UserAuth{}, u.logAndReturnError("Error creating user: ", err)
 	}
-	return user, nil
+	return authMethod, nil
 }
 
 func (u *UserRepositoryImpl) GetOrCreate(data, method string) (dao.User, error) {
@@ -99,11 +131,90 @@ func (u *UserRepositoryImpl) GetOrCreateAuth(data, method string) (dao.UserAuth,
 		return userAuth, false, nil
 	}
 	log.Infof("Creating user with auth method %s and data %s", method, data)
-	user, err := u.Create(data, method)
+	userAuth, err = u.createAuth(data, method)
 	if err != nil {
 		return dao.UserAuth{}, false, u.logAndReturnError("Error creating user: ", err)
 	}
-	return dao.UserAuth{User: user}, true, nil
+	return userAuth, true, nil
+}
+
+// FindAuth returns the auth method holding the identity, nil if nobody signed in with it yet
+func (u *UserRepositoryImpl) FindAuth(data, method string) (*dao.UserAuth, error) {
+	var userAuths []dao.UserAuth
+	if err := u.db.Where("auth_data = ? AND auth_method = ?", data, method).Limit(1).Find(&userAuths).Error; err != nil {
+		return nil, u.logAndReturnError("Error finding auth: ", err)
+	}
+	if len(userAuths) == 0 {
+		return nil, nil
+	}
+	return &userAuths[0], nil
+}
+
+func (u *UserRepositoryImpl) GetAuthMethods(userId uuid.UUID) ([]dao.UserAuth, error) {
+	var userAuths []dao.UserAuth
+	if err := u.db.Where("user_id = ?", userId).Order("created_at, id").Find(&userAuths).Error; err != nil {
+		return nil, u.logAndReturnError("Error getting auth methods: ", err)
+	}
+	return userAuths, nil
+}
+
+// LinkAuth attaches another auth method to the user, one per method. ErrAuthTaken means the identity
+// belongs to an account already
+func (u *UserRepositoryImpl) LinkAuth(userId uuid.UUID, data, method string) (dao.UserAuth, error) {
+	userAuth := dao.UserAuth{UserID: userId, AuthData: data, AuthMethod: method}
+	err := u.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userId).First(&dao.User{}).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&dao.UserAuth{}).Where("user_id = ? AND auth_method = ?", userId, method).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAuthMethodLinked
+		}
+		if err := tx.Omit("User").Create(&userAuth).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrAuthTaken
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return dao.UserAuth{}, err
+	}
+	return userAuth, nil
+}
+
+// UnlinkAuth removes one auth method of the user, the last one can not be removed. Removing Telegram
+// clears the Telegram id too
+func (u *UserRepositoryImpl) UnlinkAuth(userId, authId uuid.UUID) error {
+	return u.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userId).First(&dao.User{}).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&dao.UserAuth{}).Where("user_id = ?", userId).Count(&count).Error; err != nil {
+			return err
+		}
+		var removed []dao.UserAuth
+		result := tx.Clauses(clause.Returning{}).Where("id = ? AND user_id = ?", authId, userId).Delete(&removed)
+		if result.Error != nil {
+			return result.Error
+		}
+		if len(removed) == 0 {
+			return ErrAuthNotFound
+		}
+		if count <= 1 {
+			return ErrLastAuthMethod
+		}
+		// The Telegram id belongs to the identity, a user signing in with it elsewhere takes it along
+		if removed[0].AuthMethod == constant.Telegram {
+			return tx.Model(&dao.User{}).Where("id = ?", userId).Update("tg_id", 0).Error
+		}
+		return nil
+	})
 }
 
 func (u *UserRepositoryImpl) UpdateUserFields(userId uuid.UUID, updates map[string]interface{}) (dao.User, error) {
@@ -114,6 +225,26 @@ func (u *UserRepositoryImpl) UpdateUserFields(userId uuid.UUID, updates map[stri
 	return user, nil
 }
 
+func (u *UserRepositoryImpl) SetRole(userId uuid.UUID, role constant.Role) (dao.User, error) {
+	result := u.db.Model(&dao.User{}).Where("id = ?", userId).Update("role", role)
+	if result.Error != nil {
+		return dao.User{}, u.logAndReturnError("Error setting role: ", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return dao.User{}, gorm.ErrRecordNotFound
+	}
+	return u.Get(userId)
+}
+
+// GetStaff returns every user with a role above player
+func (u *UserRepositoryImpl) GetStaff() ([]dao.User, error) {
+	var users []dao.User
+	if err := u.db.Where("role <> ?", constant.ROLE_PLAYER).Order("created_at, id").Find(&users).Error; err != nil {
+		return nil, u.logAndReturnError("Error getting staff: ", err)
+	}
+	return users, nil
+}
+
 func (u *UserRepositoryImpl) GetUserUpgrade(userId uuid.UUID) (dao.UserUpgrade, error) {
 	var userUpgrade dao.UserUpgrade
 	if err := u.db.Where("user_id = ?", userId).First(&userUpgrade).Error; err == nil {
@@ -126,6 +257,19 @@ func (u *UserRepositoryImpl) GetUserUpgrade(userId uuid.UUID) (dao.UserUpgrade,
 	return userUpgrade, nil
 }
 
+// GetReferralTimesSince returns when the referrals of userId who signed up from since on created their account
+func (u *UserRepositoryImpl) GetReferralTimesSince(userId uuid.UUID, since time.Time) ([]time.Time, error) {
+	var times []time.Time
+	err := u.db.Model(&dao.UserReferral{}).
+		Joins("JOIN users ON users.id = user_referrals.referral_id").
+		Where("user_referrals.referrer_id = ? AND users.created_at >= ?", userId, since).
+		Pluck("users.created_at", &times).Error
+	if err != nil {
+		return nil, u.logAndReturnError("Error getting recent referrals: ", err)
+	}
+	return times, nil
+}
+
 func (u *UserRepositoryImpl) GetMyReferrals(userId uuid.UUID) ([]dao.User, error) {
 	var userReferrals []dao.UserReferral
 	if err := u.db.Where("referrer_id = ?", userId).Preload("Referral").Find(&userReferrals).Error; err != nil {
@@ -138,15 +282,22 @@ func (u *UserRepositoryImpl) GetMyReferrals(userId uuid.UUID) ([]dao.User, error
 	return referrals, nil
 }
 
-func (u *UserRepositoryImpl) SetReferrals(userId, referrerId uuid.UUID) (dao.UserReferral, error) {
+func (u *UserRepositoryImpl) SetReferrals(userId, referrerId uuid.UUID, premium bool) (dao.UserReferral, error) {
 	// Initialize the UserReferral struct with provided userId and referrerId
 	userReferral := dao.UserReferral{
 		ReferralId: userId,
 		ReferrerID: referrerId,
+		Premium:    premium,
 	}
 
-	// Save the userReferral to the database and handle any errors
-	if err := u.db.Save(&userReferral).Error; err != nil {
+	// Save the userReferral and count it on the referrer's leaderboard in one transaction
+	err := u.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&userReferral).Error; err != nil {
+			return err
+		}
+		return addLeaderboardScore(tx, referrerId, constant.LEADERBOARD_REFERRALS, 1)
+	})
+	if err != nil {
 		// Log the error and return it
 		return dao.UserReferral{}, u.logAndReturnError("Error creating user referral: ", err)
 	}
@@ -154,7 +305,59 @@ func (u *UserRepositoryImpl) SetReferrals(userId, referrerId uuid.UUID) (dao.Use
 	// Return the created userReferral
 	return userReferral, nil
 }
+func (u *UserRepositoryImpl) GetMyReferrer(userId uuid.UUID) (*dao.User, error) {
+	var userReferral dao.UserReferral
+	err := u.db.Where("referral_id = ?", userId).Preload("Referrer").First(&userReferral).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, u.logAndReturnError("Error getting user referrer: ", err)
+	}
+	return &userReferral.Referrer, nil
+}
+
+// IsFriend reports whether the two users are linked by a referral in either direction
+func (u *UserRepositoryImpl) IsFriend(userId, otherId uuid.UUID) (bool, error) {
+	var count int64
+	err := u.db.Model(&dao.UserReferral{}).
+		Where("(referrer_id = ? AND referral_id = ?) OR (referrer_id = ? AND referral_id = ?)", userId, otherId, otherId, userId).
+		Count(&count).Error
+	if err != nil {
+		return false, u.logAndReturnError("Error checking friendship: ", err)
+	}
+	return count > 0, nil
+}
+
+// dedupeUserFields keeps the first planting of fields planted twice by concurrent requests before the
+// unique index existed, the index can not be built over them
+func dedupeUserFields(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&dao.UserField{}) || db.Migrator().HasIndex(&dao.UserField{}, "idx_user_field") {
+		return nil
+	}
+	result := db.Exec(`
+		DELETE FROM user_fields f
+		USING (
+			SELECT user_id, field_id, (ARRAY_AGG(id ORDER BY created_at, id))[1] AS keep_id
+			FROM user_fields GROUP BY user_id, field_id HAVING COUNT(*) > 1
+		) d
+		WHERE f.user_id = d.user_id AND f.field_id = d.field_id AND f.id <> d.keep_id`)
+	if result.RowsAffected > 0 {
+		log.Warnf("Removed %d duplicate field plantings", result.RowsAffected)
+	}
+	return result.Error
+}
+
 func UserRepositoryInit(db *gorm.DB) *UserRepositoryImpl {
-	_ = db.AutoMigrate(&dao.User{}, &dao.UserAuth{}, &dao.UserUpgrade{}, &dao.UserField{}, &dao.UserReferral{})
+	if err := dedupeUserFields(db); err != nil {
+		log.Fatal("Error removing duplicate field plantings: ", err)
+	}
+	// PlantField relies on the unique index to refuse a second planting of the same field
+	if err := db.AutoMigrate(&dao.User{}, &dao.UserAuth{}, &dao.UserUpgrade{}, &dao.UserField{}, &dao.UserReferral{}); err != nil {
+		log.Fatal("Error during AutoMigrate: ", err)
+	}
+	if !db.Migrator().HasIndex(&dao.UserField{}, "idx_user_field") {
+		log.Fatal("Unique index idx_user_field is missing")
+	}
 	return &UserRepositoryImpl{db: db}
 }
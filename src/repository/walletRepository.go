@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotEnoughCoins       = errors.New("not enough coins")
+	ErrDuplicateTransaction = errors.New("duplicate wallet transaction")
+)
+
+type WalletRepository interface {
+	GetBalance(userId uuid.UUID) (int64, error)
+	AdjustCoins(userId uuid.UUID, amount int64, reason constant.WalletReason, txID string) (dao.WalletTransaction, error)
+	GetTransaction(txID string) (dao.WalletTransaction, error)
+	WithTx(tx *gorm.DB) WalletRepository
+}
+
+type WalletRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func (w *WalletRepositoryImpl) GetBalance(userId uuid.UUID) (int64, error) {
+	var user dao.User
+	if err := w.db.Select("coins").Where("id = ?", userId).First(&user).Error; err != nil {
+		return 0, err
+	}
+	return user.Coins, nil
+}
+
+// AdjustCoins applies a signed coin change exactly once per txID,
+// a repeated txID returns ErrDuplicateTransaction and leaves the balance untouched
+func (w *WalletRepositoryImpl) AdjustCoins(userId uuid.UUID, amount int64, reason constant.WalletReason, txID string) (dao.WalletTransaction, error) {
+	return adjustCoins(w.db, userId, amount, reason, txID)
+}
+
+func adjustCoins(db *gorm.DB, userId uuid.UUID, amount int64, reason constant.WalletReason, txID string) (dao.WalletTransaction, error) {
+	if amount == 0 {
+		return dao.WalletTransaction{}, fmt.Errorf("amount must not be zero")
+	}
+
+	transaction := dao.WalletTransaction{
+		UserID: userId,
+		TxID:   txID,
+		Amount: amount,
+		Reason: reason,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var updated []dao.User
+		err := tx.Raw(`
+			UPDATE users SET coins = coins + ?
+			WHERE id = ? AND coins + ? >= 0
+			RETURNING coins`, amount, userId, amount).Scan(&updated).Error
+		if err != nil {
+			return err
+		}
+		if len(updated) == 0 {
+			return ErrNotEnoughCoins
+		}
+
+		transaction.BalanceAfter = updated[0].Coins
+		if err := tx.Create(&transaction).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrDuplicateTransaction
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return dao.WalletTransaction{}, err
+	}
+	return transaction, nil
+}
+
+func (w *WalletRepositoryImpl) GetTransaction(txID string) (dao.WalletTransaction, error) {
+	var transaction dao.WalletTransaction
+	if err := w.db.Where("tx_id = ?", txID).First(&transaction).Error; err != nil {
+		return dao.WalletTransaction{}, err
+	}
+	return transaction, nil
+}
+
+// WithTx returns a repository bound to an open transaction
+func (w *WalletRepositoryImpl) WithTx(tx *gorm.DB) WalletRepository {
+	return &WalletRepositoryImpl{db: tx}
+}
+
+func WalletRepositoryInit(db *gorm.DB) *WalletRepositoryImpl {
+	if err := db.AutoMigrate(&dao.WalletTransaction{}); err != nil {
+		log.Error("Error during AutoMigrate: ", err)
+	}
+	return &WalletRepositoryImpl{db: db}
+}
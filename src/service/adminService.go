@@ -0,0 +1,139 @@
+package service
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/constructor"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/repository"
+	"errors"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type AdminService interface {
+	GetMyRole(c *gin.Context) dto.RolePermissions
+	GetRoles(c *gin.Context) []dto.RolePermissions
+	GetStaff(c *gin.Context) []dto.StaffUser
+	SetUserRole(c *gin.Context) dto.StaffUser
+	GrantItems(c *gin.Context) dto.ItemStack
+}
+
+type AdminServiceImpl struct {
+	userRepository      repository.UserRepository
+	inventoryRepository repository.InventoryRepository
+	plantRepository     repository.PlantRepository
+}
+
+func (a *AdminServiceImpl) GetMyRole(c *gin.Context) dto.RolePermissions {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	return constructor.ConstructRolePermissions(user.Role)
+}
+
+func (a *AdminServiceImpl) GetRoles(c *gin.Context) []dto.RolePermissions {
+	roles := make([]dto.RolePermissions, len(constant.Roles))
+	for i, role := range constant.Roles {
+		roles[i] = constructor.ConstructRolePermissions(role)
+	}
+	return roles
+}
+
+func (a *AdminServiceImpl) GetStaff(c *gin.Context) []dto.StaffUser {
+	users, err := a.userRepository.GetStaff()
+	if err != nil {
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	staff := make([]dto.StaffUser, len(users))
+	for i, user := range users {
+		staff[i] = constructor.ConstructStaffUserFromModel(user)
+	}
+	return staff
+}
+
+// SetUserRole grants a role to a user, PLAYER takes every role away. Nobody changes their own role, so
+// there is always an admin left to undo a mistake
+func (a *AdminServiceImpl) SetUserRole(c *gin.Context) dto.StaffUser {
+	admin, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "")
+	}
+	var request dto.SetRoleRequest
+	if err := pkg.UnmarshalAndValidate(body, &request); err != nil {
+		pkg.PanicException(constant.WrongDataBody, err.Error())
+	}
+	if !request.Role.Valid() {
+		pkg.PanicException(constant.WrongDataBody, "Unknown role")
+	}
+	if request.UserID == admin.ID {
+		pkg.PanicException(constant.InvalidRequest, "Own role can not be changed")
+	}
+
+	user, err := a.userRepository.SetRole(request.UserID, request.Role)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	case err != nil:
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	log.Infof("User %s set the role of %s to %s", admin.ID, user.ID, user.Role)
+	return constructor.ConstructStaffUserFromModel(user)
+}
+
+// GrantItems adjusts the inventory of a user for support cases and events, the ledger entry is booked
+// as an admin grant referencing the admin. It returns the quantity the user holds afterwards
+func (a *AdminServiceImpl) GrantItems(c *gin.Context) dto.ItemStack {
+	admin, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "")
+	}
+	var request dto.GrantItemsRequest
+	if err := pkg.UnmarshalAndValidate(body, &request); err != nil {
+		pkg.PanicException(constant.WrongDataBody, err.Error())
+	}
+	if !a.plantRepository.IsValidPlant(request.Plant) {
+		pkg.PanicException(constant.WrongDataBody, "Plant not found")
+	}
+	if _, err := a.userRepository.Get(request.UserID); err != nil {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+
+	err = a.inventoryRepository.AdjustItemQuantity(request.UserID, request.Plant, request.Amount, constant.LEDGER_ADMIN_GRANT, admin.ID.String())
+	switch {
+	case errors.Is(err, repository.ErrNotEnoughItems):
+		pkg.PanicException(constant.InvalidRequest, "User does not have that many items")
+	case err != nil:
+		log.Errorln(err)
+		pkg.PanicException(constant.UnknownError, "Items were not granted")
+	}
+	log.Infof("User %s granted %d %s to %s", admin.ID, request.Amount, request.Plant, request.UserID)
+
+	quantity, err := a.inventoryRepository.GetItemQuantity(request.UserID, request.Plant)
+	if err != nil {
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	return dto.ItemStack{Plant: request.Plant, Amount: quantity}
+}
+
+func AdminServiceInit(
+	userRepository repository.UserRepository,
+	inventoryRepository repository.InventoryRepository,
+	plantRepository repository.PlantRepository) *AdminServiceImpl {
+	return &AdminServiceImpl{
+		userRepository:      userRepository,
+		inventoryRepository: inventoryRepository,
+		plantRepository:     plantRepository,
+	}
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/constructor"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/repository"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	emailCodeTtl      = 10 * time.Minute
+	emailCodeCooldown = time.Minute
+	// Time a player has to confirm merging the account found by a link
+	mergeTokenTtl = 10 * time.Minute
+)
+
+// Codes mailed to one address, RATE_LIMIT_EMAIL_CODE_ADDRESS overrides it as "<burst>/<period>"
+var defaultEmailCodeLimit = pkg.RateLimit{Burst: 3, Period: 10 * time.Minute}
+
+type AuthService interface {
+	GetTonProofPayload(c *gin.Context) dto.TonProofPayload
+	SendEmailCode(c *gin.Context)
+	GetAuthMethods(c *gin.Context) []dto.AuthMethod
+	LinkAuthMethod(c *gin.Context) ([]dto.AuthMethod, *dto.AuthConflict)
+	UnlinkAuthMethod(c *gin.Context) []dto.AuthMethod
+	MergeAccount(c *gin.Context) []dto.AuthMethod
+}
+
+type AuthServiceImpl struct {
+	authRepository        repository.AuthRepository
+	userRepository        repository.UserRepository
+	sessionRepository     repository.SessionRepository
+	authenticatorRegistry AuthenticatorRegistry
+	mailSender            pkg.MailSender
+	rateLimiter           pkg.RateLimiter
+	// Per address on top of the per IP limit of the route, which a client spreading over addresses escapes
+	emailCodeLimit pkg.RateLimit
+	tonProofTtl    time.Duration
+}
+
+// GetTonProofPayload issues the challenge a wallet signs for the ton auth method
+func (a *AuthServiceImpl) GetTonProofPayload(c *gin.Context) dto.TonProofPayload {
+	if _, ok := a.authenticatorRegistry.Get(constant.Ton); !ok {
+		pkg.PanicException(constant.WrongMethod, "")
+	}
+	payload, expiresAt, err := pkg.NewTonProofPayload(a.tonProofTtl)
+	if err != nil {
+		log.Error("Creating ton proof payload failed: ", err)
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	if err := a.authRepository.SaveTonProofNonce(payload, expiresAt); err != nil {
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	return dto.TonProofPayload{Payload: payload, ExpiresAt: expiresAt.Unix()}
+}
+
+// SendEmailCode mails a one time code for the email auth method, a new code replaces the previous one
+func (a *AuthServiceImpl) SendEmailCode(c *gin.Context) {
+	if _, ok := a.authenticatorRegistry.Get(constant.Email); !ok {
+		pkg.PanicException(constant.WrongMethod, "")
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "")
+	}
+	var request dto.EmailCodeRequest
+	if err := pkg.UnmarshalAndValidate(body, &request); err != nil {
+		pkg.PanicException(constant.WrongDataBody, err.Error())
+	}
+	email, ok := normalizeEmail(request.Email)
+	if !ok {
+		pkg.PanicException(constant.WrongDataBody, "Invalid email")
+	}
+	a.limitEmailCode(c, email)
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		log.Error("Creating email code failed: ", err)
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+	now := time.Now()
+	err = a.authRepository.SaveEmailCode(dao.EmailCode{
+		Email:     email,
+		CodeHash:  hashEmailCode(email, code),
+		ExpiresAt: now.Add(emailCodeTtl),
+		SentAt:    now,
+	}, emailCodeCooldown)
+	switch {
+	case errors.Is(err, repository.ErrEmailCodeCooldown):
+		pkg.PanicException(constant.InvalidRequest, "Code was sent recently, try again later")
+	case errors.Is(err, repository.ErrEmailCodeLimit):
+		pkg.PanicException(constant.TooManyRequests, "Too many codes or attempts for this email, try again later")
+	case err != nil:
+		pkg.PanicException(constant.UnknownError, "")
+	}
+
+	message := fmt.Sprintf("Your sign in code is %s. It expires in %d minutes.", code, int(emailCodeTtl.Minutes()))
+	if err := a.mailSender.Send(email, "Your sign in code", message); err != nil {
+		log.Error("Sending email code failed: ", err)
+		pkg.PanicException(constant.UnknownError, "")
+	}
+}
+
+func (a *AuthServiceImpl) GetAuthMethods(c *gin.Context) []dto.AuthMethod {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	return a.authMethods(c, user.ID)
+}
+
+func (a *AuthServiceImpl) authMethods(c *gin.Context, userId uuid.UUID) []dto.AuthMethod {
+	userAuths, err := a.userRepository.GetAuthMethods(userId)
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	current, _ := c.MustGet("user_auth").(dao.UserAuth)
+	methods := make([]dto.AuthMethod, len(userAuths))
+	for i, userAuth := range userAuths {
+		methods[i] = constructor.ConstructAuthMethodFromModel(userAuth, userAuth.ID == current.ID)
+	}
+	return methods
+}
+
+// LinkAuthMethod signs in with another method and attaches it to the calling account. When the identity
+// already has an account of its own nothing is linked, the conflict carries a token to merge that account
+func (a *AuthServiceImpl) LinkAuthMethod(c *gin.Context) ([]dto.AuthMethod, *dto.AuthConflict) {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	var request dto.AuthRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.Method == "" || request.Data == "" {
+		pkg.PanicException(constant.WrongBody, "")
+	}
+	identity := a.authenticate(request)
+
+	existing, err := a.userRepository.FindAuth(identity.AuthData, request.Method)
+	if err != nil {
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	if existing != nil {
+		if existing.UserID == user.ID {
+			return a.authMethods(c, user.ID), nil
+		}
+		return nil, a.conflict(request.Method, existing.UserID, user.ID)
+	}
+
+	_, err = a.userRepository.LinkAuth(user.ID, identity.AuthData, request.Method)
+	switch {
+	case errors.Is(err, repository.ErrAuthTaken):
+		// Someone signed up with the identity since the lookup
+		existing, err := a.userRepository.FindAuth(identity.AuthData, request.Method)
+		if err != nil || existing == nil {
+			pkg.PanicException(constant.UnknownError, "")
+		}
+		return nil, a.conflict(request.Method, existing.UserID, user.ID)
+	case errors.Is(err, repository.ErrAuthMethodLinked):
+		pkg.PanicException(constant.InvalidRequest, "Method already linked, unlink it first")
+	case err != nil:
+		log.Error("Linking auth method: ", err)
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	if len(identity.Profile) > 0 {
+		if _, err := a.userRepository.UpdateUserFields(user.ID, identity.Profile); err != nil {
+			log.Error("Updating data: ", err)
+		}
+	}
+	return a.authMethods(c, user.ID), nil
+}
+
+func (a *AuthServiceImpl) authenticate(request dto.AuthRequest) AuthIdentity {
+	authenticator, ok := a.authenticatorRegistry.Get(request.Method)
+	if !ok {
+		pkg.PanicException(constant.WrongMethod, "")
+	}
+	identity, err := authenticator.Authenticate(request.Data)
+	if errors.Is(err, ErrAuthFailed) {
+		log.Info("Link rejected: ", err)
+		pkg.PanicException(constant.Unauthorized, "Authentication failed")
+	}
+	if err != nil {
+		log.Error("Authenticating: ", err)
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	return identity
+}
+
+func (a *AuthServiceImpl) conflict(method string, sourceId, targetId uuid.UUID) *dto.AuthConflict {
+	token, expiresAt, err := pkg.CreateMergeToken(sourceId, targetId, mergeTokenTtl)
+	if err != nil {
+		log.Error("Creating merge token failed: ", err)
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	return &dto.AuthConflict{Method: method, MergeToken: token, ExpiresAt: expiresAt.Unix()}
+}
+
+// UnlinkAuthMethod removes ?authId= from the calling account and signs out its sessions
+func (a *AuthServiceImpl) UnlinkAuthMethod(c *gin.Context) []dto.AuthMethod {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	authId, err := uuid.Parse(c.Query("authId"))
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "Invalid auth id")
+	}
+
+	err = a.userRepository.UnlinkAuth(user.ID, authId)
+	switch {
+	case errors.Is(err, repository.ErrAuthNotFound):
+		pkg.PanicException(constant.DataNotFound, "Auth method not found")
+	case errors.Is(err, repository.ErrLastAuthMethod):
+		pkg.PanicException(constant.InvalidRequest, "Last auth method can not be unlinked")
+	case err != nil:
+		log.Error("Unlinking auth method: ", err)
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	// The sessions went with the method, this evicts them from the caches
+	if err := a.sessionRepository.RevokeAuthSessions(user.ID, authId); err != nil {
+		log.Error("Revoking sessions of unlinked method: ", err)
+	}
+	return a.authMethods(c, user.ID)
+}
+
+// MergeAccount merges the account named by a merge token from LinkAuthMethod into the calling account,
+// moving its auth methods along and signing out its sessions
+func (a *AuthServiceImpl) MergeAccount(c *gin.Context) []dto.AuthMethod {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "")
+	}
+	var request dto.MergeRequest
+	if err := pkg.UnmarshalAndValidate(body, &request); err != nil {
+		pkg.PanicException(constant.WrongDataBody, err.Error())
+	}
+	sourceId, targetId, err := pkg.VerifyMergeToken(request.MergeToken)
+	if err != nil || targetId != user.ID {
+		pkg.PanicException(constant.Unauthorized, "Invalid merge token")
+	}
+
+	err = a.userRepository.MergeUsers(sourceId, targetId)
+	switch {
+	case errors.Is(err, repository.ErrAlreadyMerged):
+		pkg.PanicException(constant.InvalidRequest, "Account was already merged")
+	case errors.Is(err, repository.ErrMergeMethodTaken):
+		pkg.PanicException(constant.InvalidRequest, "Both accounts have a sign in of the same kind, unlink it from one of them first")
+	case err != nil:
+		log.Error("Merging accounts: ", err)
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	if err := a.sessionRepository.RevokeUserSessions(sourceId); err != nil {
+		log.Error("Revoking sessions of merged account: ", err)
+	}
+	return a.authMethods(c, user.ID)
+}
+
+// limitEmailCode takes a token from the bucket of the address, a limiter failure lets the request through
+// like RateLimitMiddleware does
+func (a *AuthServiceImpl) limitEmailCode(c *gin.Context, email string) {
+	result, err := a.rateLimiter.Take("email_code:email:"+email, a.emailCodeLimit)
+	if err != nil {
+		log.Error("Rate limit: ", err)
+		return
+	}
+	if !result.Allowed {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+		pkg.PanicException(constant.TooManyRequests, "Too many codes for this email, try again later")
+	}
+}
+
+func AuthServiceInit(
+	authRepository repository.AuthRepository,
+	userRepository repository.UserRepository,
+	sessionRepository repository.SessionRepository,
+	authenticatorRegistry AuthenticatorRegistry,
+	mailSender pkg.MailSender,
+	rateLimiter pkg.RateLimiter) *AuthServiceImpl {
+	emailCodeLimit := defaultEmailCodeLimit
+	if value := os.Getenv("RATE_LIMIT_EMAIL_CODE_ADDRESS"); value != "" {
+		limit, err := pkg.ParseRateLimit(value)
+		if err != nil {
+			log.Errorf("RATE_LIMIT_EMAIL_CODE_ADDRESS: %v, keeping %s", err, emailCodeLimit)
+		} else {
+			emailCodeLimit = limit
+		}
+	}
+	return &AuthServiceImpl{
+		authRepository:        authRepository,
+		userRepository:        userRepository,
+		sessionRepository:     sessionRepository,
+		authenticatorRegistry: authenticatorRegistry,
+		mailSender:            mailSender,
+		rateLimiter:           rateLimiter,
+		emailCodeLimit:        emailCodeLimit,
+		tonProofTtl:           envDuration("TON_PROOF_TTL", defaultTonProofTtl),
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/repository"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrAuthFailed marks credentials that were checked and rejected, as opposed to internal failures
+var ErrAuthFailed = errors.New("authentication failed")
+
+// AuthIdentity is what a successful sign in proves: the auth data stored in UserAuth for the method,
+// plus profile fields to refresh on the user and the start param for referrals
+type AuthIdentity struct {
+	AuthData   string
+	Profile    map[string]interface{}
+	StartParam string
+}
+
+// Authenticator checks the credentials of one auth method
+type Authenticator interface {
+	Authenticate(data string) (AuthIdentity, error)
+}
+
+type AuthenticatorRegistry interface {
+	Get(method string) (Authenticator, bool)
+	Methods() []string
+}
+
+type AuthenticatorRegistryImpl struct {
+	authenticators map[string]Authenticator
+	methods        []string
+}
+
+func (r *AuthenticatorRegistryImpl) Get(method string) (Authenticator, bool) {
+	authenticator, ok := r.authenticators[method]
+	return authenticator, ok
+}
+
+// Methods lists the enabled auth methods in registration order
+func (r *AuthenticatorRegistryImpl) Methods() []string {
+	return r.methods
+}
+
+func (r *AuthenticatorRegistryImpl) register(method string, authenticator Authenticator) {
+	r.authenticators[method] = authenticator
+	r.methods = append(r.methods, method)
+}
+
+type telegramAuthenticator struct{}
+
+// Authenticate validates Telegram Web App init data, the Telegram user id is the auth data
+func (telegramAuthenticator) Authenticate(data string) (AuthIdentity, error) {
+	telegramToken := os.Getenv("TELEGRAM_TOKEN")
+	telegramInitDataTtl, err := strconv.ParseFloat(os.Getenv("TELEGRAM_INIT_DATA_TTL"), 64)
+	if err != nil {
+		return AuthIdentity{}, fmt.Errorf("parsing TELEGRAM_INIT_DATA_TTL: %w", err)
+	}
+
+	telegramInitData, err := pkg.ParseTelegramData(data)
+	if err != nil {
+		return AuthIdentity{}, fmt.Errorf("%w: %v", ErrAuthFailed, err)
+	}
+	if err := pkg.ValidateTelegramData(data, telegramToken, time.Duration(telegramInitDataTtl*float64(time.Second))); err != nil {
+		return AuthIdentity{}, fmt.Errorf("%w: %v", ErrAuthFailed, err)
+	}
+
+	telegramUser := telegramInitData.TelegramUser
+	return AuthIdentity{
+		AuthData: strconv.FormatInt(telegramUser.ID, 10),
+		Profile: map[string]interface{}{
+			"tg_id":         telegramUser.ID,
+			"first_name":    pkg.GetNullableString(telegramUser.FirstName),
+			"last_name":     pkg.GetNullableString(telegramUser.LastName),
+			"username":      pkg.GetNullableString(telegramUser.Username),
+			"icon":          pkg.GetNullableString(telegramUser.PhotoURL),
+			"language_code": pkg.GetNullableString(telegramUser.LanguageCode),
+			"is_premium":    telegramUser.IsPremium,
+		},
+		StartParam: telegramInitData.StartParam,
+	}, nil
+}
+
+func AuthenticatorRegistryInit(authRepository repository.AuthRepository, mailSender pkg.MailSender) *AuthenticatorRegistryImpl {
+	registry := &AuthenticatorRegistryImpl{authenticators: make(map[string]Authenticator)}
+	registry.register(constant.Telegram, telegramAuthenticator{})
+
+	// TON Connect proofs name the domain of the app, without any allowed domain no proof can pass
+	if domains := splitList(os.Getenv("TON_PROOF_DOMAINS")); len(domains) > 0 {
+		registry.register(constant.Ton, &tonAuthenticator{
+			authRepository: authRepository,
+			domains:        domains,
+			network:        os.Getenv("TON_NETWORK"),
+			ttl:            envDuration("TON_PROOF_TTL", defaultTonProofTtl),
+		})
+	} else {
+		log.Warn("TON_PROOF_DOMAINS is not set, ton auth disabled")
+	}
+
+	// Codes that are never delivered would only lock players out
+	if mailSender != nil {
+		registry.register(constant.Email, &emailAuthenticator{authRepository: authRepository})
+	} else {
+		log.Warn("No mail sender configured, email auth disabled")
+	}
+	return registry
+}
+
+// splitList splits a comma separated env value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"crazyfarmbackend/src/domain/dto"
+	"crazyfarmbackend/src/repository"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+type emailAuthenticator struct {
+	authRepository repository.AuthRepository
+}
+
+// Authenticate consumes the one time code sent to the address, the normalized address is the auth data
+func (e *emailAuthenticator) Authenticate(data string) (AuthIdentity, error) {
+	var authData dto.EmailAuthData
+	if err := json.Unmarshal([]byte(data), &authData); err != nil {
+		return AuthIdentity{}, fmt.Errorf("%w: %v", ErrAuthFailed, err)
+	}
+	email, ok := normalizeEmail(authData.Email)
+	if !ok || authData.Code == "" {
+		return AuthIdentity{}, fmt.Errorf("%w: email and code are required", ErrAuthFailed)
+	}
+	err := e.authRepository.ConsumeEmailCode(email, hashEmailCode(email, authData.Code))
+	if errors.Is(err, repository.ErrEmailCodeInvalid) || errors.Is(err, repository.ErrEmailCodeLimit) {
+		return AuthIdentity{}, fmt.Errorf("%w: %v", ErrAuthFailed, err)
+	}
+	if err != nil {
+		return AuthIdentity{}, err
+	}
+	return AuthIdentity{AuthData: email}, nil
+}
+
+// normalizeEmail lowercases a bare address, display names and other RFC 5322 forms are rejected
+func normalizeEmail(email string) (string, bool) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", false
+	}
+	return email, true
+}
+
+func hashEmailCode(email, code string) string {
+	sum := sha256.Sum256([]byte(email + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// envDuration reads a duration like "15m", unset, invalid or negative values fall back to the default
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
+
+// envLimit reads a daily limit, unset, invalid or negative values fall back to the default. What 0 means
+// is up to the caller
+func envLimit(key string, fallback int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
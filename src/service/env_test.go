@@ -0,0 +1,22 @@
+package service
+
+import "testing"
+
+func TestEnvLimit(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"", 20},
+		{"abc", 20},
+		{"-1", 20},
+		{"0", 0},
+		{"5", 5},
+	}
+	for _, tt := range tests {
+		t.Setenv("TEST_DAILY_LIMIT", tt.value)
+		if got := envLimit("TEST_DAILY_LIMIT", 20); got != tt.want {
+			t.Errorf("envLimit with %q = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
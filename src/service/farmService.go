@@ -0,0 +1,229 @@
+package service
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/constructor"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/repository"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultStealSharePercent = 25
+	defaultStealWindow       = time.Hour
+	defaultThiefCooldown     = 30 * time.Minute
+	defaultVictimCooldown    = time.Hour
+	notificationsLimit       = 50
+)
+
+type FarmService interface {
+	Visit(c *gin.Context) dto.FarmVisit
+	Steal(c *gin.Context) dto.Theft
+	GetNotifications(c *gin.Context) []dto.Notification
+	ReadNotifications(c *gin.Context)
+}
+
+type FarmServiceImpl struct {
+	farmRepository      repository.FarmRepository
+	inventoryRepository repository.InventoryRepository
+	userRepository      repository.UserRepository
+	plantRepository     repository.PlantRepository
+	stealSharePercent   int
+	stealWindow         time.Duration
+	thiefCooldown       time.Duration
+	victimCooldown      time.Duration
+}
+
+// stealAmount is the share of reward a friend may take, the owner always keeps at least one
+func (f *FarmServiceImpl) stealAmount(reward int) int {
+	amount := max(reward*f.stealSharePercent/100, 1)
+	if reward-amount < 1 {
+		return 0
+	}
+	return amount
+}
+
+// stealableUntil returns the end of the steal window, zero if the crop can not be stolen now
+func (f *FarmServiceImpl) stealableUntil(field dao.UserField, plantInfo dao.PlantInfo, now time.Time) time.Time {
+	readyAt := field.CreatedAt.Add(plantInfo.GrowDuration())
+	until := readyAt.Add(f.stealWindow)
+	if field.Stolen > 0 || now.Before(readyAt) || !now.Before(until) || f.stealAmount(plantInfo.Reward) == 0 {
+		return time.Time{}
+	}
+	return until
+}
+
+func (f *FarmServiceImpl) cooldownLeft(column string, userId uuid.UUID, cooldown time.Duration) int64 {
+	last, err := f.farmRepository.GetLastTheftAt(column, userId)
+	if err != nil {
+		log.Errorln(err)
+		return 0
+	}
+	if last == nil {
+		return 0
+	}
+	return max(int64(time.Until(last.Add(cooldown)).Seconds()), 0)
+}
+
+func (f *FarmServiceImpl) Visit(c *gin.Context) dto.FarmVisit {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	ownerId, err := uuid.Parse(c.Query("userID"))
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "Invalid user id")
+	}
+	mustBeFriend(f.userRepository, user.ID, ownerId)
+
+	owner, err := f.userRepository.Get(ownerId)
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	userUpgrade, err := f.userRepository.GetUserUpgrade(ownerId)
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	fields, err := f.inventoryRepository.GetMyFields(ownerId)
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+
+	now := time.Now()
+	farmFields := make([]dto.FarmField, 0, len(fields))
+	for _, field := range fields {
+		plantInfo, ok := f.plantRepository.GetPlant(field.Plant)
+		if !ok {
+			continue
+		}
+		farmField := dto.FarmField{
+			FieldID: field.FieldID,
+			Plant:   field.Plant,
+			ReadyAt: field.CreatedAt.Add(plantInfo.GrowDuration()).Unix(),
+			Stolen:  field.Stolen,
+		}
+		if until := f.stealableUntil(field, plantInfo, now); !until.IsZero() {
+			farmField.Stealable = true
+			farmField.StealableUntil = until.Unix()
+		}
+		farmFields = append(farmFields, farmField)
+	}
+
+	return dto.FarmVisit{
+		Owner:          constructor.ConstructUserReferralFromModel(owner),
+		FarmLvl:        userUpgrade.FarmLvl,
+		Fields:         farmFields,
+		StealCooldown:  f.cooldownLeft("thief_id", user.ID, f.thiefCooldown),
+		VictimCooldown: f.cooldownLeft("victim_id", ownerId, f.victimCooldown),
+	}
+}
+
+func (f *FarmServiceImpl) Steal(c *gin.Context) dto.Theft {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	victimId, err := uuid.Parse(c.Query("userID"))
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "Invalid user id")
+	}
+	fieldID, err := strconv.Atoi(c.Query("fieldID"))
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "Invalid field id")
+	}
+	mustBeFriend(f.userRepository, user.ID, victimId)
+
+	field, err := f.inventoryRepository.GetMyField(victimId, fieldID)
+	if err != nil {
+		log.Errorln(err)
+		pkg.PanicException(constant.DataNotFound, "Error to access field")
+	}
+	if field == nil {
+		pkg.PanicException(constant.InvalidRequest, "Nothing planted")
+	}
+	plantInfo, ok := f.plantRepository.GetPlant(field.Plant)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "Plant not found")
+	}
+	if f.stealableUntil(*field, plantInfo, time.Now()).IsZero() {
+		pkg.PanicException(constant.InvalidRequest, "Crop can not be stolen now")
+	}
+
+	theft, err := f.farmRepository.Steal(*field, dao.Theft{
+		ThiefID:  user.ID,
+		VictimID: victimId,
+		FieldID:  field.FieldID,
+		Plant:    field.Plant,
+		Amount:   f.stealAmount(plantInfo.Reward),
+	}, f.thiefCooldown, f.victimCooldown)
+	switch {
+	case errors.Is(err, repository.ErrThiefCooldown):
+		pkg.PanicException(constant.InvalidRequest, "You stole recently, wait for the cooldown")
+	case errors.Is(err, repository.ErrVictimCooldown):
+		pkg.PanicException(constant.InvalidRequest, "This farm was robbed recently")
+	case errors.Is(err, repository.ErrAlreadyStolen):
+		pkg.PanicException(constant.InvalidRequest, "Crop can not be stolen now")
+	case err != nil:
+		log.Errorln(err)
+		pkg.PanicException(constant.UnknownError, "Steal failed")
+	}
+	return constructor.ConstructTheftByModel(theft)
+}
+
+func (f *FarmServiceImpl) GetNotifications(c *gin.Context) []dto.Notification {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	notifications, err := f.farmRepository.GetNotifications(user.ID, notificationsLimit)
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	notificationDTOs := make([]dto.Notification, len(notifications))
+	for i, notification := range notifications {
+		notificationDTOs[i] = constructor.ConstructNotificationByModel(notification)
+	}
+	return notificationDTOs
+}
+
+func (f *FarmServiceImpl) ReadNotifications(c *gin.Context) {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	if err := f.farmRepository.MarkNotificationsRead(user.ID); err != nil {
+		log.Errorln(err)
+		pkg.PanicException(constant.UnknownError, "")
+	}
+}
+
+func FarmServiceInit(
+	farmRepository repository.FarmRepository,
+	inventoryRepository repository.InventoryRepository,
+	userRepository repository.UserRepository,
+	plantRepository repository.PlantRepository) *FarmServiceImpl {
+	// Percent of a ripe crop's reward a friend takes, the owner keeps at least one item
+	stealSharePercent, err := strconv.Atoi(os.Getenv("STEAL_SHARE_PERCENT"))
+	if err != nil || stealSharePercent <= 0 || stealSharePercent > 100 {
+		stealSharePercent = defaultStealSharePercent
+	}
+	return &FarmServiceImpl{
+		farmRepository:      farmRepository,
+		inventoryRepository: inventoryRepository,
+		userRepository:      userRepository,
+		plantRepository:     plantRepository,
+		stealSharePercent:   stealSharePercent,
+		// Durations use Go syntax, e.g. 45m or 2h
+		stealWindow:    envDuration("STEAL_WINDOW", defaultStealWindow),
+		thiefCooldown:  envDuration("STEAL_THIEF_COOLDOWN", defaultThiefCooldown),
+		victimCooldown: envDuration("STEAL_VICTIM_COOLDOWN", defaultVictimCooldown),
+	}
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/repository"
+	"github.com/google/uuid"
+)
+
+// mustBeFriend stops interactions outside the referral graph
+func mustBeFriend(userRepository repository.UserRepository, userId, otherId uuid.UUID) {
+	if userId == otherId {
+		pkg.PanicException(constant.InvalidRequest, "Can not do this with yourself")
+	}
+	isFriend, err := userRepository.IsFriend(userId, otherId)
+	if err != nil {
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	if !isFriend {
+		pkg.PanicException(constant.InvalidRequest, "User is not your friend")
+	}
+}
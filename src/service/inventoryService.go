@@ -7,20 +7,30 @@ import (
 	"crazyfarmbackend/src/domain/dto"
 	"crazyfarmbackend/src/pkg"
 	"crazyfarmbackend/src/repository"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	"strconv"
+	"time"
 )
 
 type InventoryService interface {
 	GetAllItems(c *gin.Context) (dto.GetAllItemsResponse, error)
 	GetMyFields(c *gin.Context) []dto.UserField
 	PlantField(c *gin.Context) dto.UserField
+	HarvestField(c *gin.Context) dto.HarvestResult
+	HarvestAll(c *gin.Context) []dto.HarvestResult
+	GetHistory(c *gin.Context) dto.InventoryHistoryResponse
 }
 
 type InventoryServiceImpl struct {
 	inventoryRepository repository.InventoryRepository
+	userRepository      repository.UserRepository
+	upgradeRepository   repository.UpgradeRepository
+	plantRepository     repository.PlantRepository
+	referralService     ReferralService
 }
 
 func (u *InventoryServiceImpl) GetAllItems(c *gin.Context) (dto.GetAllItemsResponse, error) {
@@ -28,12 +38,16 @@ func (u *InventoryServiceImpl) GetAllItems(c *gin.Context) (dto.GetAllItemsRespo
 	if !ok {
 		return dto.GetAllItemsResponse{}, fmt.Errorf("failed to get user from context")
 	}
-	items, err := u.inventoryRepository.GetAllInventoryItems(user.ID)
+	items, err := u.inventoryRepository.GetAllInventoryItems(user.ID, u.plantRepository.GetPlantNames())
 	if err != nil {
 		return dto.GetAllItemsResponse{}, err
 	}
 	var dtoItems []dto.InventoryItem
 	for _, item := range items {
+		// A disabled plant can no longer be planted or bought, it is only listed while still held
+		if plant, ok := u.plantRepository.GetPlant(item.Plant); ok && !plant.Enabled && item.Quantity <= 0 {
+			continue
+		}
 		dtoItems = append(dtoItems, constructor.ConstructInventoryItemByModel(item))
 	}
 	response := dto.GetAllItemsResponse{
@@ -56,12 +70,17 @@ func (u *InventoryServiceImpl) GetMyFields(c *gin.Context) []dto.UserField {
 
 	userFieldDTOs := make([]dto.UserField, len(userFields))
 	for i, field := range userFields {
-		userFieldDTOs[i] = constructor.ConstructUserFieldFromModel(field)
+		userFieldDTOs[i] = u.constructUserField(field)
 	}
 
 	return userFieldDTOs
 }
 
+func (u *InventoryServiceImpl) constructUserField(field dao.UserField) dto.UserField {
+	plantInfo, _ := u.plantRepository.GetPlant(field.Plant)
+	return constructor.ConstructUserFieldFromModel(field, plantInfo)
+}
+
 func (u *InventoryServiceImpl) PlantField(c *gin.Context) dto.UserField {
 	user, ok := c.MustGet("user").(dao.User)
 	fieldIDStr := c.Query("fieldID")
@@ -71,49 +90,179 @@ func (u *InventoryServiceImpl) PlantField(c *gin.Context) dto.UserField {
 	}
 	plantStr := c.Query("plant")
 	plant := constant.Plant(plantStr)
-	if !constant.IsValidPlant(plant) {
+	plantInfo, plantFound := u.plantRepository.GetPlant(plant)
+	if !plantFound || !plantInfo.IsAvailable(time.Now()) {
 		pkg.PanicException(constant.DataNotFound, "Plant not found")
 	}
 	if !ok {
 		pkg.PanicException(constant.DataNotFound, "User not found")
 	}
+	if !u.isFieldUnlocked(user.ID, fieldID) {
+		pkg.PanicException(constant.InvalidRequest, "Field is locked")
+	}
+
+	userFieldUpdated, err := u.inventoryRepository.PlantField(user.ID, fieldID, plant)
+	switch {
+	case errors.Is(err, repository.ErrFieldOccupied):
+		pkg.PanicException(constant.InvalidRequest, "Already planted")
+	case errors.Is(err, repository.ErrNotEnoughItems):
+		pkg.PanicException(constant.InvalidRequest, "Not enough item to plant")
+	case err != nil:
+		log.Errorln(err)
+		pkg.PanicException(constant.DataNotFound, "Failed to plant field")
+	}
+
+	return constructor.ConstructUserFieldFromModel(userFieldUpdated, plantInfo)
+}
+
+// Field ids are zero based, a farm level unlocks ids [0, MaxFields)
+func (u *InventoryServiceImpl) isFieldUnlocked(userId uuid.UUID, fieldID int) bool {
+	userUpgrade, err := u.userRepository.GetUserUpgrade(userId)
+	if err != nil {
+		return false
+	}
+	level, err := u.upgradeRepository.GetFarmLevel(userUpgrade.FarmLvl)
+	if err != nil {
+		log.Errorln(err)
+		return false
+	}
+	return fieldID >= 0 && fieldID < level.MaxFields
+}
+
+func (u *InventoryServiceImpl) HarvestField(c *gin.Context) dto.HarvestResult {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	fieldID, err := strconv.Atoi(c.Query("fieldID"))
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "Invalid field id")
+	}
 
 	userField, err := u.inventoryRepository.GetMyField(user.ID, fieldID)
 	if err != nil {
 		log.Errorln(err)
 		pkg.PanicException(constant.DataNotFound, "Error to access field")
 	}
-	if userField != nil {
-		pkg.PanicException(constant.InvalidRequest, "Already planted")
+	if userField == nil {
+		pkg.PanicException(constant.InvalidRequest, "Nothing planted")
 	}
 
-	plantQuantity, err := u.inventoryRepository.GetItemQuantity(user.ID, plant)
+	plantInfo, ok := u.plantRepository.GetPlant(userField.Plant)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "Plant not found")
+	}
+	if time.Now().Before(userField.CreatedAt.Add(plantInfo.GrowDuration())) {
+		pkg.PanicException(constant.InvalidRequest, "Not ready to harvest")
+	}
 
+	amount, err := u.inventoryRepository.HarvestField(*userField, plantInfo.Reward)
 	if err != nil {
 		log.Errorln(err)
-		pkg.PanicException(constant.InvalidRequest, "Access inventory error")
+		pkg.PanicException(constant.InvalidRequest, "Failed to harvest field")
 	}
-	if !(plantQuantity > 0) {
-		pkg.PanicException(constant.InvalidRequest, "Not enough item to plant")
+	u.referralService.CheckMilestones(user.ID)
+
+	return dto.HarvestResult{
+		FieldID: userField.FieldID,
+		Plant:   userField.Plant,
+		Amount:  amount,
+	}
+}
+
+func (u *InventoryServiceImpl) HarvestAll(c *gin.Context) []dto.HarvestResult {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
 	}
 
-	err = u.inventoryRepository.AdjustItemQuantity(user.ID, plant, -1)
+	userFields, err := u.inventoryRepository.GetMyFields(user.ID)
 	if err != nil {
-		log.Errorln(err)
-		pkg.PanicException(constant.InvalidRequest, "Cant decrease")
+		pkg.PanicException(constant.DataNotFound, "")
 	}
 
-	userFieldUpdated, err := u.inventoryRepository.PlantField(user.ID, fieldID, plant)
+	harvested := make([]dto.HarvestResult, 0, len(userFields))
+	for _, field := range userFields {
+		plantInfo, ok := u.plantRepository.GetPlant(field.Plant)
+		if !ok || time.Now().Before(field.CreatedAt.Add(plantInfo.GrowDuration())) {
+			continue
+		}
+		amount, err := u.inventoryRepository.HarvestField(field, plantInfo.Reward)
+		if err != nil {
+			log.Errorln(err)
+			continue
+		}
+		harvested = append(harvested, dto.HarvestResult{
+			FieldID: field.FieldID,
+			Plant:   field.Plant,
+			Amount:  amount,
+		})
+	}
+	if len(harvested) > 0 {
+		u.referralService.CheckMilestones(user.ID)
+	}
+
+	return harvested
+}
+
+const (
+	historyDefaultLimit = 50
+	historyMaxLimit     = 100
+)
+
+func (u *InventoryServiceImpl) GetHistory(c *gin.Context) dto.InventoryHistoryResponse {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+
+	var cursor uint64
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		parsed, err := strconv.ParseUint(cursorStr, 10, 64)
+		if err != nil {
+			pkg.PanicException(constant.WrongBody, "Invalid cursor")
+		}
+		cursor = parsed
+	}
+	limit := historyDefaultLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			pkg.PanicException(constant.WrongBody, "Invalid limit")
+		}
+		limit = min(parsed, historyMaxLimit)
+	}
+
+	entries, err := u.inventoryRepository.GetHistory(user.ID, cursor, limit)
 	if err != nil {
 		log.Errorln(err)
-		pkg.PanicException(constant.DataNotFound, "Failed to plant field")
+		pkg.PanicException(constant.DataNotFound, "")
 	}
 
-	return constructor.ConstructUserFieldFromModel(userFieldUpdated)
+	response := dto.InventoryHistoryResponse{
+		Items: make([]dto.LedgerEntry, len(entries)),
+	}
+	for i, entry := range entries {
+		response.Items[i] = constructor.ConstructLedgerEntryByModel(entry)
+	}
+	if len(entries) == limit {
+		nextCursor := strconv.FormatUint(entries[len(entries)-1].ID, 10)
+		response.NextCursor = &nextCursor
+	}
+	return response
 }
 
-func InventoryServiceInit(inventoryRepository repository.InventoryRepository) *InventoryServiceImpl {
+func InventoryServiceInit(
+	inventoryRepository repository.InventoryRepository,
+	userRepository repository.UserRepository,
+	upgradeRepository repository.UpgradeRepository,
+	plantRepository repository.PlantRepository,
+	referralService ReferralService) *InventoryServiceImpl {
 	return &InventoryServiceImpl{
 		inventoryRepository: inventoryRepository,
+		userRepository:      userRepository,
+		upgradeRepository:   upgradeRepository,
+		plantRepository:     plantRepository,
+		referralService:     referralService,
 	}
 }
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/constructor"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	leaderboardDefaultLimit = 50
+	leaderboardMaxLimit     = 100
+
+	leaderboardScopeGlobal  = "global"
+	leaderboardScopeFriends = "friends"
+	leaderboardPeriodWeek   = "week"
+
+	defaultLeaderboardRefresh = 5 * time.Minute
+)
+
+type LeaderboardService interface {
+	GetLeaderboard(c *gin.Context) dto.Leaderboard
+	StartRefresh()
+}
+
+type LeaderboardServiceImpl struct {
+	leaderboardRepository repository.LeaderboardRepository
+	userRepository        repository.UserRepository
+}
+
+func (l *LeaderboardServiceImpl) GetLeaderboard(c *gin.Context) dto.Leaderboard {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	board := constant.LeaderboardBoard(c.Query("board"))
+	if !constant.IsValidLeaderboard(board) {
+		pkg.PanicException(constant.WrongBody, "Invalid board")
+	}
+	scope := c.DefaultQuery("scope", leaderboardScopeGlobal)
+	if scope != leaderboardScopeGlobal && scope != leaderboardScopeFriends {
+		pkg.PanicException(constant.WrongBody, "Invalid scope")
+	}
+	period := constant.LEADERBOARD_ALL_TIME
+	switch c.DefaultQuery("period", constant.LEADERBOARD_ALL_TIME) {
+	case constant.LEADERBOARD_ALL_TIME:
+	case leaderboardPeriodWeek:
+		// Inventory worth is a snapshot, only accumulated boards have weekly standings
+		if board == constant.LEADERBOARD_INVENTORY_WORTH {
+			pkg.PanicException(constant.InvalidRequest, "Inventory worth has no weekly leaderboard")
+		}
+		period = pkg.WeekKey(time.Now())
+	default:
+		pkg.PanicException(constant.WrongBody, "Invalid period")
+	}
+	limit := leaderboardDefaultLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			pkg.PanicException(constant.WrongBody, "Invalid limit")
+		}
+		limit = min(parsed, leaderboardMaxLimit)
+	}
+
+	leaderboard := dto.Leaderboard{
+		Board:  board,
+		Scope:  scope,
+		Period: period,
+	}
+	if scope == leaderboardScopeFriends {
+		leaderboard.Entries, leaderboard.Me = l.friendsStandings(user, board, period, limit)
+	} else {
+		leaderboard.Entries, leaderboard.Me = l.globalStandings(user, board, period, limit)
+	}
+	return leaderboard
+}
+
+func (l *LeaderboardServiceImpl) globalStandings(user dao.User, board constant.LeaderboardBoard, period string, limit int) ([]dto.LeaderboardEntry, dto.LeaderboardEntry) {
+	scores, err := l.leaderboardRepository.GetTop(board, period, limit)
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	entries := make([]dto.LeaderboardEntry, len(scores))
+	for i, score := range scores {
+		entries[i] = dto.LeaderboardEntry{
+			Rank:  competitionRank(entries, i, score.Score),
+			User:  constructor.ConstructUserReferralFromModel(score.User),
+			Score: score.Score,
+		}
+	}
+
+	score, rank, err := l.leaderboardRepository.GetRank(board, period, user.ID)
+	if err != nil {
+		log.Errorln(err)
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	return entries, dto.LeaderboardEntry{
+		Rank:  rank,
+		User:  constructor.ConstructUserReferralFromModel(user),
+		Score: score,
+	}
+}
+
+// friendsStandings ranks the user among their referrer and referrals, friends without a score rank with 0
+func (l *LeaderboardServiceImpl) friendsStandings(user dao.User, board constant.LeaderboardBoard, period string, limit int) ([]dto.LeaderboardEntry, dto.LeaderboardEntry) {
+	friends, err := l.userRepository.GetMyReferrals(user.ID)
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	referrer, err := l.userRepository.GetMyReferrer(user.ID)
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	if referrer != nil {
+		friends = append(friends, *referrer)
+	}
+	friends = append(friends, user)
+
+	userIds := make([]uuid.UUID, len(friends))
+	for i, friend := range friends {
+		userIds[i] = friend.ID
+	}
+	scores, err := l.leaderboardRepository.GetScores(board, period, userIds)
+	if err != nil {
+		log.Errorln(err)
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	sort.SliceStable(friends, func(i, j int) bool {
+		return scores[friends[i].ID] > scores[friends[j].ID]
+	})
+
+	var me dto.LeaderboardEntry
+	entries := make([]dto.LeaderboardEntry, len(friends))
+	for i, friend := range friends {
+		entries[i] = dto.LeaderboardEntry{
+			Rank:  competitionRank(entries, i, scores[friend.ID]),
+			User:  constructor.ConstructUserReferralFromModel(friend),
+			Score: scores[friend.ID],
+		}
+		if friend.ID == user.ID {
+			me = entries[i]
+		}
+	}
+	return entries[:min(limit, len(entries))], me
+}
+
+// competitionRank gives position i the rank of the previous entry when their scores tie
+func competitionRank(entries []dto.LeaderboardEntry, i int, score int64) int64 {
+	if i > 0 && entries[i-1].Score == score {
+		return entries[i-1].Rank
+	}
+	return int64(i + 1)
+}
+
+// StartRefresh keeps the inventory worth standings current while serving http. Every instance ticks,
+// the repository lets one of them rebuild per interval and the others skip
+func (l *LeaderboardServiceImpl) StartRefresh() {
+	interval := envDuration("LEADERBOARD_REFRESH_INTERVAL", defaultLeaderboardRefresh)
+	if interval <= 0 {
+		return
+	}
+	go l.refreshInventoryWorth(interval)
+}
+
+func (l *LeaderboardServiceImpl) refreshInventoryWorth(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		// A little under the interval so the instance whose tick comes first is not skipped next time
+		if _, err := l.leaderboardRepository.RefreshInventoryWorth(interval * 9 / 10); err != nil {
+			log.Error("Error refreshing inventory worth: ", err)
+		}
+	}
+}
+
+func LeaderboardServiceInit(
+	leaderboardRepository repository.LeaderboardRepository,
+	userRepository repository.UserRepository) *LeaderboardServiceImpl {
+	return &LeaderboardServiceImpl{
+		leaderboardRepository: leaderboardRepository,
+		userRepository:        userRepository,
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/constructor"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/repository"
+	"errors"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"time"
+)
+
+type PlantService interface {
+	GetPlants(c *gin.Context) []dto.Plant
+	GetAllPlants(c *gin.Context) []dto.Plant
+	CreatePlant(c *gin.Context) dto.Plant
+	UpdatePlant(c *gin.Context) dto.Plant
+	DeletePlant(c *gin.Context)
+}
+
+type PlantServiceImpl struct {
+	plantRepository repository.PlantRepository
+}
+
+// GetPlants lists the plants players can currently plant
+func (p *PlantServiceImpl) GetPlants(c *gin.Context) []dto.Plant {
+	plants, err := p.plantRepository.GetPlants()
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	now := time.Now()
+	plantDTOs := make([]dto.Plant, 0, len(plants))
+	for _, plant := range plants {
+		if plant.IsAvailable(now) {
+			plantDTOs = append(plantDTOs, constructor.ConstructPlantByModel(plant))
+		}
+	}
+	return plantDTOs
+}
+
+func (p *PlantServiceImpl) GetAllPlants(c *gin.Context) []dto.Plant {
+	plants, err := p.plantRepository.GetPlants()
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	plantDTOs := make([]dto.Plant, len(plants))
+	for i, plant := range plants {
+		plantDTOs[i] = constructor.ConstructPlantByModel(plant)
+	}
+	return plantDTOs
+}
+
+func (p *PlantServiceImpl) readPlantRequest(c *gin.Context) dto.PlantRequest {
+	body, err := c.GetRawData()
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "")
+	}
+	var request dto.PlantRequest
+	if err := pkg.UnmarshalAndValidate(body, &request); err != nil {
+		pkg.PanicException(constant.WrongDataBody, err.Error())
+	}
+	return request
+}
+
+func mustBeValidAvailability(plant dao.PlantInfo) {
+	if plant.AvailableFrom != nil && plant.AvailableTo != nil && plant.AvailableTo.Before(*plant.AvailableFrom) {
+		pkg.PanicException(constant.WrongDataBody, "AvailableTo is before AvailableFrom")
+	}
+}
+
+func (p *PlantServiceImpl) CreatePlant(c *gin.Context) dto.Plant {
+	request := p.readPlantRequest(c)
+	switch {
+	case request.Name == "":
+		pkg.PanicException(constant.WrongDataBody, "field Name is required")
+	case request.GrowTime == nil:
+		pkg.PanicException(constant.WrongDataBody, "field GrowTime is required")
+	case request.Reward == nil:
+		pkg.PanicException(constant.WrongDataBody, "field Reward is required")
+	}
+	plant := constructor.ConstructPlantFromRequest(request)
+	mustBeValidAvailability(plant)
+
+	plant, err := p.plantRepository.CreatePlant(plant)
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		pkg.PanicException(constant.InvalidRequest, "Plant already exists")
+	case err != nil:
+		pkg.PanicException(constant.UnknownError, "Plant was not created")
+	}
+	return constructor.ConstructPlantByModel(plant)
+}
+
+// UpdatePlant changes the fields of ?name= the body sets, the others keep their value
+func (p *PlantServiceImpl) UpdatePlant(c *gin.Context) dto.Plant {
+	name := constant.Plant(c.Query("name"))
+	request := p.readPlantRequest(c)
+	updates := constructor.ConstructPlantUpdatesFromRequest(request)
+	if len(updates) == 0 {
+		pkg.PanicException(constant.WrongDataBody, "Nothing to update")
+	}
+	// The window is checked as it ends up, a body may move only one of its bounds
+	current, ok := p.plantRepository.GetPlant(name)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "Plant not found")
+	}
+	update := constructor.ConstructPlantFromRequest(request)
+	if request.AvailableFrom != nil {
+		current.AvailableFrom = update.AvailableFrom
+	}
+	if request.AvailableTo != nil {
+		current.AvailableTo = update.AvailableTo
+	}
+	mustBeValidAvailability(current)
+
+	plant, err := p.plantRepository.UpdatePlant(name, updates)
+	switch {
+	case errors.Is(err, repository.ErrPlantNotFound):
+		pkg.PanicException(constant.DataNotFound, "Plant not found")
+	case err != nil:
+		log.Errorln(err)
+		pkg.PanicException(constant.UnknownError, "Plant was not updated")
+	}
+	return constructor.ConstructPlantByModel(plant)
+}
+
+func (p *PlantServiceImpl) DeletePlant(c *gin.Context) {
+	err := p.plantRepository.DeletePlant(constant.Plant(c.Query("name")))
+	switch {
+	case errors.Is(err, repository.ErrPlantNotFound):
+		pkg.PanicException(constant.DataNotFound, "Plant not found")
+	case errors.Is(err, repository.ErrPlantInUse):
+		pkg.PanicException(constant.InvalidRequest, "Plant is in use, disable it instead")
+	case err != nil:
+		pkg.PanicException(constant.UnknownError, "Plant was not deleted")
+	}
+}
+
+func PlantServiceInit(plantRepository repository.PlantRepository) *PlantServiceImpl {
+	return &PlantServiceImpl{
+		plantRepository: plantRepository,
+	}
+}
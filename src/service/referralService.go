@@ -0,0 +1,215 @@
+package service
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/constructor"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/repository"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const referralPayoutsLimit = 100
+
+type ReferralService interface {
+	GetMyReferralProgram(c *gin.Context) dto.ReferralProgram
+	GetReferralRewards(c *gin.Context) []dto.ReferralReward
+	SaveReferralReward(c *gin.Context) dto.ReferralReward
+	CheckMilestones(referralId uuid.UUID)
+}
+
+type ReferralServiceImpl struct {
+	referralRepository repository.ReferralRepository
+	userRepository     repository.UserRepository
+	counterRepository  repository.CounterRepository
+	plantRepository    repository.PlantRepository
+	upgradeRepository  repository.UpgradeRepository
+}
+
+// CheckMilestones pays the referrer of referralId every enabled tier the referral has reached and
+// that was not paid yet. Services call it after actions that can reach a milestone, failures are
+// only logged so they never break the action itself
+func (r *ReferralServiceImpl) CheckMilestones(referralId uuid.UUID) {
+	referral, err := r.referralRepository.GetReferral(referralId)
+	if err != nil {
+		log.Errorln(err)
+		return
+	}
+	if referral == nil {
+		return
+	}
+	tiers, err := r.referralRepository.GetReferralRewards()
+	if err != nil {
+		return
+	}
+	paid, err := r.referralRepository.GetPaidRewardIds(referralId)
+	if err != nil {
+		log.Errorln(err)
+		return
+	}
+
+	progress := referralProgress{referralId: referralId, counterRepository: r.counterRepository, userRepository: r.userRepository}
+	for _, tier := range tiers {
+		if !tier.Enabled || paid[tier.ID] {
+			continue
+		}
+		reached, err := progress.reached(tier)
+		if err != nil {
+			log.Errorln(err)
+			return
+		}
+		if !reached {
+			continue
+		}
+		rewards := tier.RewardsFor(referral.Premium)
+		if err := validateRewards(rewards, r.plantRepository, r.upgradeRepository); err != nil {
+			log.Errorf("referral reward %s: %v", tier.ID, err)
+			continue
+		}
+		_, err = r.referralRepository.PayReferralReward(dao.ReferralPayout{
+			ReferrerID: referral.ReferrerID,
+			ReferralID: referralId,
+			RewardID:   tier.ID,
+			Premium:    referral.Premium,
+			Rewards:    rewards,
+		})
+		if err != nil && !errors.Is(err, repository.ErrReferralRewardPaid) {
+			log.Errorf("referral reward %s for %s: %v", tier.ID, referralId, err)
+		}
+	}
+}
+
+// referralProgress looks up the referral's harvest count and farm level once, and only when a tier needs them
+type referralProgress struct {
+	referralId        uuid.UUID
+	counterRepository repository.CounterRepository
+	userRepository    repository.UserRepository
+
+	harvested *int64
+	farmLvl   *int
+}
+
+func (p *referralProgress) reached(tier dao.ReferralReward) (bool, error) {
+	switch tier.Milestone {
+	case constant.REFERRAL_SIGNUP:
+		return true, nil
+	case constant.REFERRAL_HARVEST:
+		if p.harvested == nil {
+			harvested, err := p.counterRepository.GetCounter(p.referralId, constant.COUNTER_HARVESTED, constant.COUNTER_KEY_ANY)
+			if err != nil {
+				return false, err
+			}
+			p.harvested = &harvested
+		}
+		return *p.harvested >= int64(tier.Threshold), nil
+	case constant.REFERRAL_FARM_LEVEL:
+		if p.farmLvl == nil {
+			userUpgrade, err := p.userRepository.GetUserUpgrade(p.referralId)
+			if err != nil {
+				return false, err
+			}
+			p.farmLvl = &userUpgrade.FarmLvl
+		}
+		return *p.farmLvl >= tier.Threshold, nil
+	}
+	return false, fmt.Errorf("referral reward %s: unknown milestone %q", tier.ID, tier.Milestone)
+}
+
+// GetMyReferralProgram lists the enabled tiers and the payouts the user received as a referrer
+func (r *ReferralServiceImpl) GetMyReferralProgram(c *gin.Context) dto.ReferralProgram {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	tiers, err := r.referralRepository.GetReferralRewards()
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	payouts, err := r.referralRepository.GetPayouts(user.ID, referralPayoutsLimit)
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+
+	program := dto.ReferralProgram{
+		Tiers:   make([]dto.ReferralReward, 0, len(tiers)),
+		Payouts: make([]dto.ReferralPayout, len(payouts)),
+	}
+	for _, tier := range tiers {
+		if tier.Enabled {
+			program.Tiers = append(program.Tiers, constructor.ConstructReferralRewardByModel(tier))
+		}
+	}
+	for i, payout := range payouts {
+		program.Payouts[i] = constructor.ConstructReferralPayoutByModel(payout)
+	}
+	return program
+}
+
+func (r *ReferralServiceImpl) GetReferralRewards(c *gin.Context) []dto.ReferralReward {
+	tiers, err := r.referralRepository.GetReferralRewards()
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	tierDTOs := make([]dto.ReferralReward, len(tiers))
+	for i, tier := range tiers {
+		tierDTOs[i] = constructor.ConstructReferralRewardByModel(tier)
+	}
+	return tierDTOs
+}
+
+// SaveReferralReward creates or replaces a tier, tiers already paid are not paid again after an edit
+func (r *ReferralServiceImpl) SaveReferralReward(c *gin.Context) dto.ReferralReward {
+	body, err := c.GetRawData()
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "")
+	}
+	var request dto.ReferralRewardRequest
+	if err := pkg.UnmarshalAndValidate(body, &request); err != nil {
+		pkg.PanicException(constant.WrongDataBody, err.Error())
+	}
+	if len(request.Rewards) == 0 {
+		pkg.PanicException(constant.WrongDataBody, "Rewards must not be empty")
+	}
+	tier := constructor.ConstructReferralRewardFromRequest(request)
+
+	switch tier.Milestone {
+	case constant.REFERRAL_SIGNUP:
+	case constant.REFERRAL_HARVEST, constant.REFERRAL_FARM_LEVEL:
+		if tier.Threshold <= 0 {
+			pkg.PanicException(constant.WrongDataBody, "Threshold must be positive")
+		}
+	default:
+		pkg.PanicException(constant.WrongDataBody, "Unknown milestone")
+	}
+	for _, rewards := range [][]dao.RewardLine{tier.Rewards, tier.PremiumRewards} {
+		if err := validateRewards(rewards, r.plantRepository, r.upgradeRepository); err != nil {
+			pkg.PanicException(constant.WrongDataBody, err.Error())
+		}
+	}
+
+	tier, err = r.referralRepository.SaveReferralReward(tier)
+	if err != nil {
+		pkg.PanicException(constant.UnknownError, "Referral reward was not saved")
+	}
+	return constructor.ConstructReferralRewardByModel(tier)
+}
+
+func ReferralServiceInit(
+	referralRepository repository.ReferralRepository,
+	userRepository repository.UserRepository,
+	counterRepository repository.CounterRepository,
+	plantRepository repository.PlantRepository,
+	upgradeRepository repository.UpgradeRepository) *ReferralServiceImpl {
+	return &ReferralServiceImpl{
+		referralRepository: referralRepository,
+		userRepository:     userRepository,
+		counterRepository:  counterRepository,
+		plantRepository:    plantRepository,
+		upgradeRepository:  upgradeRepository,
+	}
+}
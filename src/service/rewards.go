@@ -0,0 +1,52 @@
+package service
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/repository"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidReward = errors.New("invalid reward")
+
+// validateRewards checks a reward bundle before it is stored or paid out: plants must be in the catalog,
+// unlocked farm levels must exist and every kind or plant may appear once, so a bundle books one
+// wallet transaction at most
+func validateRewards(rewards []dao.RewardLine, plantRepository repository.PlantRepository, upgradeRepository repository.UpgradeRepository) error {
+	type lineKey struct {
+		kind  constant.RewardKind
+		plant constant.Plant
+	}
+	seen := make(map[lineKey]bool, len(rewards))
+	for _, reward := range rewards {
+		if reward.Amount <= 0 {
+			return fmt.Errorf("%w: %s amount must be positive", ErrInvalidReward, reward.Kind)
+		}
+		switch reward.Kind {
+		case constant.REWARD_PLANT:
+			if !plantRepository.IsValidPlant(reward.Plant) {
+				return fmt.Errorf("%w: plant %q is not in the plant catalog", ErrInvalidReward, reward.Plant)
+			}
+		case constant.REWARD_COINS, constant.REWARD_FARM_XP:
+		case constant.REWARD_FARM_LEVEL:
+			if _, err := upgradeRepository.GetFarmLevel(int(reward.Amount)); err != nil {
+				return fmt.Errorf("%w: farm level %d does not exist", ErrInvalidReward, reward.Amount)
+			}
+		default:
+			return fmt.Errorf("%w: unknown kind %q", ErrInvalidReward, reward.Kind)
+		}
+		if reward.Kind != constant.REWARD_PLANT && reward.Plant != "" {
+			return fmt.Errorf("%w: plant is only allowed on %s rewards", ErrInvalidReward, constant.REWARD_PLANT)
+		}
+		key := lineKey{reward.Kind, reward.Plant}
+		if reward.Kind == constant.REWARD_FARM_LEVEL {
+			key.plant = ""
+		}
+		if seen[key] {
+			return fmt.Errorf("%w: %s %s is listed twice", ErrInvalidReward, reward.Kind, reward.Plant)
+		}
+		seen[key] = true
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/repository"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	// A session not refreshed for this long has to sign in again
+	defaultRefreshTokenTtl = 30 * 24 * time.Hour
+	// Expired and revoked sessions are deleted this long after they ended
+	defaultSessionRetention     = 7 * 24 * time.Hour
+	defaultSessionPruneInterval = time.Hour
+)
+
+type SessionService interface {
+	StartSession(c *gin.Context, userAuth dao.UserAuth) dto.SessionTokens
+	Refresh(c *gin.Context) dto.SessionTokens
+	Logout(c *gin.Context)
+	LogoutEverywhere(c *gin.Context)
+	StartPruning()
+}
+
+type SessionServiceImpl struct {
+	sessionRepository repository.SessionRepository
+	refreshTokenTtl   time.Duration
+	retention         time.Duration
+}
+
+// StartSession opens a session for a successful sign in and issues its first token pair
+func (s *SessionServiceImpl) StartSession(c *gin.Context, userAuth dao.UserAuth) dto.SessionTokens {
+	refreshToken, err := pkg.NewRefreshToken()
+	if err != nil {
+		log.Error("Creating refresh token failed: ", err)
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	now := time.Now()
+	session, err := s.sessionRepository.CreateSession(dao.Session{
+		UserID:      userAuth.UserID,
+		UserAuthID:  userAuth.ID,
+		RefreshHash: pkg.HashRefreshToken(refreshToken),
+		UserAgent:   c.Request.UserAgent(),
+		IP:          c.ClientIP(),
+		ExpiresAt:   now.Add(s.refreshTokenTtl),
+		LastUsedAt:  now,
+	})
+	if err != nil {
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	return s.issueTokens(session, refreshToken)
+}
+
+// Refresh trades a refresh token for a new token pair, the old refresh token stops working
+func (s *SessionServiceImpl) Refresh(c *gin.Context) dto.SessionTokens {
+	body, err := c.GetRawData()
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "")
+	}
+	var request dto.RefreshRequest
+	if err := pkg.UnmarshalAndValidate(body, &request); err != nil {
+		pkg.PanicException(constant.WrongDataBody, err.Error())
+	}
+
+	refreshToken, err := pkg.NewRefreshToken()
+	if err != nil {
+		log.Error("Creating refresh token failed: ", err)
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	session, err := s.sessionRepository.RotateSession(pkg.HashRefreshToken(request.RefreshToken),
+		pkg.HashRefreshToken(refreshToken), time.Now().Add(s.refreshTokenTtl))
+	switch {
+	case errors.Is(err, repository.ErrSessionNotFound), errors.Is(err, repository.ErrRefreshTokenReused):
+		pkg.PanicException(constant.Unauthorized, "Invalid refresh token")
+	case err != nil:
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	return s.issueTokens(session, refreshToken)
+}
+
+func (s *SessionServiceImpl) issueTokens(session dao.Session, refreshToken string) dto.SessionTokens {
+	token, expiresAt, err := pkg.CreateJwtToken(session.UserAuthID, session.ID)
+	if err != nil {
+		log.Error("Creating JWT token failed: ", err)
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	return dto.SessionTokens{
+		Token:        token,
+		ExpiresAt:    expiresAt.Unix(),
+		RefreshToken: refreshToken,
+	}
+}
+
+// Logout revokes the session of the calling access token
+func (s *SessionServiceImpl) Logout(c *gin.Context) {
+	sessionId, ok := c.MustGet("session_id").(uuid.UUID)
+	if !ok {
+		pkg.PanicException(constant.Unauthorized, "")
+	}
+	if err := s.sessionRepository.RevokeSession(sessionId); err != nil {
+		pkg.PanicException(constant.UnknownError, "")
+	}
+}
+
+// LogoutEverywhere revokes every session of the user, including the calling one
+func (s *SessionServiceImpl) LogoutEverywhere(c *gin.Context) {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	if err := s.sessionRepository.RevokeUserSessions(user.ID); err != nil {
+		pkg.PanicException(constant.UnknownError, "")
+	}
+}
+
+// StartPruning deletes ended sessions periodically while serving http, the delete is idempotent so
+// every instance runs it
+func (s *SessionServiceImpl) StartPruning() {
+	interval := envDuration("SESSION_PRUNE_INTERVAL", defaultSessionPruneInterval)
+	if interval <= 0 {
+		return
+	}
+	go s.pruneSessions(interval)
+}
+
+func (s *SessionServiceImpl) pruneSessions(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		deleted, err := s.sessionRepository.DeleteStaleSessions(time.Now().Add(-s.retention))
+		if err != nil {
+			continue
+		}
+		if deleted > 0 {
+			log.Infof("Deleted %d stale sessions", deleted)
+		}
+	}
+}
+
+func SessionServiceInit(sessionRepository repository.SessionRepository) *SessionServiceImpl {
+	return &SessionServiceImpl{
+		sessionRepository: sessionRepository,
+		refreshTokenTtl:   envDuration("REFRESH_TOKEN_TTL", defaultRefreshTokenTtl),
+		retention:         envDuration("SESSION_RETENTION", defaultSessionRetention),
+	}
+}
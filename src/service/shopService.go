@@ -0,0 +1,350 @@
+package service
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/constructor"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/repository"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+// Largest quantity bought or sold in one request
+const maxShopQuantity = 1000
+
+type ShopService interface {
+	GetItems(c *gin.Context) []dto.ShopItem
+	Buy(c *gin.Context) dto.ShopPurchase
+	GetSellPrices(c *gin.Context) dto.SellPricesResponse
+	Sell(c *gin.Context) dto.Sale
+	GetAllItems(c *gin.Context) []dto.ShopItemDefinition
+	CreateItem(c *gin.Context) dto.ShopItemDefinition
+	UpdateItem(c *gin.Context) dto.ShopItemDefinition
+	SaveFarmLevel(c *gin.Context) dto.FarmLevel
+}
+
+type ShopServiceImpl struct {
+	shopRepository    repository.ShopRepository
+	plantRepository   repository.PlantRepository
+	upgradeRepository repository.UpgradeRepository
+	sellDailyCap      int64
+}
+
+func (s *ShopServiceImpl) GetItems(c *gin.Context) []dto.ShopItem {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+
+	items, err := s.shopRepository.GetItems()
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	purchased, err := s.shopRepository.GetPurchasedCounts(user.ID)
+	if err != nil {
+		log.Errorln(err)
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+
+	itemDTOs := make([]dto.ShopItem, len(items))
+	for i, item := range items {
+		itemDTOs[i] = constructor.ConstructShopItemByModel(item, purchased[item.ID])
+	}
+	return itemDTOs
+}
+
+func (s *ShopServiceImpl) Buy(c *gin.Context) dto.ShopPurchase {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	itemId, err := uuid.Parse(c.Query("itemID"))
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "Invalid item id")
+	}
+	quantity := 1
+	if quantityStr := c.Query("quantity"); quantityStr != "" {
+		quantity, err = strconv.Atoi(quantityStr)
+		if err != nil || quantity <= 0 || quantity > maxShopQuantity {
+			pkg.PanicException(constant.WrongBody, "Invalid quantity")
+		}
+	}
+
+	purchase, item, err := s.shopRepository.Buy(user.ID, itemId, quantity)
+	switch {
+	case errors.Is(err, repository.ErrShopItemNotFound):
+		pkg.PanicException(constant.DataNotFound, "Shop item not found")
+	case errors.Is(err, repository.ErrOutOfStock):
+		pkg.PanicException(constant.InvalidRequest, "Out of stock")
+	case errors.Is(err, repository.ErrPurchaseLimit):
+		pkg.PanicException(constant.InvalidRequest, "Purchase limit reached")
+	case errors.Is(err, repository.ErrInvalidPurchaseQty):
+		pkg.PanicException(constant.WrongBody, "Invalid quantity")
+	case errors.Is(err, repository.ErrNotEnoughCoins), errors.Is(err, repository.ErrNotEnoughItems):
+		pkg.PanicException(constant.InvalidRequest, "Not enough resources")
+	case err != nil:
+		log.Errorln(err)
+		pkg.PanicException(constant.UnknownError, "Purchase failed")
+	}
+
+	return constructor.ConstructShopPurchaseByModel(purchase, item)
+}
+
+func (s *ShopServiceImpl) GetSellPrices(c *gin.Context) dto.SellPricesResponse {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+
+	plants, err := s.plantRepository.GetPlants()
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	var prices []dto.SellPrice
+	for _, plantInfo := range plants {
+		if plantInfo.Enabled && plantInfo.SellPrice > 0 {
+			prices = append(prices, dto.SellPrice{Plant: plantInfo.Name, UnitPrice: plantInfo.SellPrice})
+		}
+	}
+
+	soldToday, err := s.shopRepository.GetSoldCoinsSince(user.ID, pkg.StartOfDay(time.Now()))
+	if err != nil {
+		log.Errorln(err)
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	response := dto.SellPricesResponse{
+		Prices:    prices,
+		DailyCap:  s.sellDailyCap,
+		SoldToday: soldToday,
+	}
+	if s.sellDailyCap > 0 {
+		remaining := max(s.sellDailyCap-soldToday, 0)
+		response.RemainingToday = &remaining
+	}
+	return response
+}
+
+func (s *ShopServiceImpl) Sell(c *gin.Context) dto.Sale {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	plantInfo, ok := s.plantRepository.GetPlant(constant.Plant(c.Query("plant")))
+	if !ok || !plantInfo.Enabled {
+		pkg.PanicException(constant.DataNotFound, "Plant not found")
+	}
+	if plantInfo.SellPrice <= 0 {
+		pkg.PanicException(constant.InvalidRequest, "Plant can not be sold")
+	}
+	quantity := 1
+	if quantityStr := c.Query("quantity"); quantityStr != "" {
+		var err error
+		quantity, err = strconv.Atoi(quantityStr)
+		if err != nil || quantity <= 0 || quantity > maxShopQuantity {
+			pkg.PanicException(constant.WrongBody, "Invalid quantity")
+		}
+	}
+
+	sale, err := s.shopRepository.Sell(user.ID, plantInfo.Name, quantity, plantInfo.SellPrice, s.sellDailyCap)
+	switch {
+	case errors.Is(err, repository.ErrDailySellCap):
+		pkg.PanicException(constant.InvalidRequest, "Daily sell limit reached")
+	case errors.Is(err, repository.ErrNotEnoughItems):
+		pkg.PanicException(constant.InvalidRequest, "Not enough items to sell")
+	case errors.Is(err, repository.ErrInvalidPurchaseQty):
+		pkg.PanicException(constant.WrongBody, "Invalid quantity")
+	case err != nil:
+		log.Errorln(err)
+		pkg.PanicException(constant.UnknownError, "Sale failed")
+	}
+
+	return constructor.ConstructSaleByModel(sale)
+}
+
+func (s *ShopServiceImpl) GetAllItems(c *gin.Context) []dto.ShopItemDefinition {
+	items, err := s.shopRepository.GetAllItems()
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	definitions := make([]dto.ShopItemDefinition, len(items))
+	for i, item := range items {
+		definitions[i] = constructor.ConstructShopItemDefinitionByModel(item)
+	}
+	return definitions
+}
+
+func (s *ShopServiceImpl) readShopItemRequest(c *gin.Context) dto.ShopItemRequest {
+	body, err := c.GetRawData()
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "")
+	}
+	var request dto.ShopItemRequest
+	if err := pkg.UnmarshalAndValidate(body, &request); err != nil {
+		pkg.PanicException(constant.WrongDataBody, err.Error())
+	}
+	return request
+}
+
+func (s *ShopServiceImpl) validateItemStacks(field string, stacks []dto.ItemStack) []dao.ItemStack {
+	items := make([]dao.ItemStack, len(stacks))
+	for i, stack := range stacks {
+		if !s.plantRepository.IsValidPlant(stack.Plant) {
+			pkg.PanicException(constant.WrongDataBody, field+": plant "+string(stack.Plant)+" not found")
+		}
+		if stack.Amount <= 0 {
+			pkg.PanicException(constant.WrongDataBody, field+": amount must be positive")
+		}
+		items[i] = dao.ItemStack{Plant: stack.Plant, Amount: stack.Amount}
+	}
+	return items
+}
+
+// shopItemFromRequest maps the request onto an item and returns the columns of the fields it sets
+func (s *ShopServiceImpl) shopItemFromRequest(request dto.ShopItemRequest) (dao.ShopItem, []string) {
+	item := dao.ShopItem{Enabled: true}
+	var columns []string
+	if request.Name != nil {
+		item.Name = *request.Name
+		columns = append(columns, "name")
+	}
+	if request.Icon != nil {
+		if *request.Icon != "" {
+			item.Icon = request.Icon
+		}
+		columns = append(columns, "icon")
+	}
+	if request.Contents != nil {
+		if len(request.Contents) == 0 {
+			pkg.PanicException(constant.WrongDataBody, "Contents must not be empty")
+		}
+		item.Contents = s.validateItemStacks("Contents", request.Contents)
+		columns = append(columns, "contents")
+	}
+	if request.PriceCoins != nil {
+		item.PriceCoins = *request.PriceCoins
+		columns = append(columns, "price_coins")
+	}
+	if request.PriceItems != nil {
+		item.PriceItems = s.validateItemStacks("PriceItems", request.PriceItems)
+		columns = append(columns, "price_items")
+	}
+	if request.PurchaseLimit != nil {
+		item.PurchaseLimit = *request.PurchaseLimit
+		columns = append(columns, "purchase_limit")
+	}
+	if request.Stock != nil {
+		if *request.Stock >= 0 {
+			item.Stock = request.Stock
+		}
+		columns = append(columns, "stock")
+	}
+	if request.Enabled != nil {
+		item.Enabled = *request.Enabled
+		columns = append(columns, "enabled")
+	}
+	if request.SortOrder != nil {
+		item.SortOrder = *request.SortOrder
+		columns = append(columns, "sort_order")
+	}
+	return item, columns
+}
+
+func (s *ShopServiceImpl) CreateItem(c *gin.Context) dto.ShopItemDefinition {
+	request := s.readShopItemRequest(c)
+	switch {
+	case request.Name == nil:
+		pkg.PanicException(constant.WrongDataBody, "field Name is required")
+	case request.Contents == nil:
+		pkg.PanicException(constant.WrongDataBody, "field Contents is required")
+	}
+	item, _ := s.shopItemFromRequest(request)
+
+	item, err := s.shopRepository.CreateItem(item)
+	if err != nil {
+		pkg.PanicException(constant.UnknownError, "Shop item was not created")
+	}
+	return constructor.ConstructShopItemDefinitionByModel(item)
+}
+
+// UpdateItem changes the fields of ?itemID= the body sets, the others keep their value. Items are
+// disabled rather than deleted, purchases keep pointing at them
+func (s *ShopServiceImpl) UpdateItem(c *gin.Context) dto.ShopItemDefinition {
+	itemId, err := uuid.Parse(c.Query("itemID"))
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "Invalid item id")
+	}
+	request := s.readShopItemRequest(c)
+	item, columns := s.shopItemFromRequest(request)
+	if len(columns) == 0 {
+		pkg.PanicException(constant.WrongDataBody, "Nothing to update")
+	}
+	item.ID = itemId
+
+	item, err = s.shopRepository.UpdateItem(item, columns...)
+	switch {
+	case errors.Is(err, repository.ErrShopItemNotFound):
+		pkg.PanicException(constant.DataNotFound, "Shop item not found")
+	case err != nil:
+		log.Errorln(err)
+		pkg.PanicException(constant.UnknownError, "Shop item was not updated")
+	}
+	return constructor.ConstructShopItemDefinitionByModel(item)
+}
+
+// SaveFarmLevel creates or replaces a farm level. Levels stay consecutive and their field limits
+// never shrink from one level to the next, so a level is only added right after the highest one
+func (s *ShopServiceImpl) SaveFarmLevel(c *gin.Context) dto.FarmLevel {
+	body, err := c.GetRawData()
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "")
+	}
+	var request dto.FarmLevelRequest
+	if err := pkg.UnmarshalAndValidate(body, &request); err != nil {
+		pkg.PanicException(constant.WrongDataBody, err.Error())
+	}
+	level := dao.FarmLevel{
+		Lvl:       request.Lvl,
+		MaxFields: request.MaxFields,
+		Cost:      s.validateItemStacks("Cost", request.Cost),
+		CoinCost:  request.CoinCost,
+	}
+
+	if level.Lvl > 1 {
+		previous, err := s.upgradeRepository.GetFarmLevel(level.Lvl - 1)
+		if err != nil {
+			pkg.PanicException(constant.WrongDataBody, "Previous farm level does not exist")
+		}
+		if level.MaxFields < previous.MaxFields {
+			pkg.PanicException(constant.WrongDataBody, "MaxFields is below the previous level")
+		}
+	}
+	if next, err := s.upgradeRepository.GetFarmLevel(level.Lvl + 1); err == nil && level.MaxFields > next.MaxFields {
+		pkg.PanicException(constant.WrongDataBody, "MaxFields is above the next level")
+	}
+
+	level, err = s.upgradeRepository.SaveFarmLevel(level)
+	if err != nil {
+		pkg.PanicException(constant.UnknownError, "Farm level was not saved")
+	}
+	return constructor.ConstructFarmLevelFromModel(level)
+}
+
+func ShopServiceInit(
+	shopRepository repository.ShopRepository,
+	plantRepository repository.PlantRepository,
+	upgradeRepository repository.UpgradeRepository) *ShopServiceImpl {
+	// Coins a user may earn from selling per UTC day, unset or 0 means no cap
+	sellDailyCap := envLimit("SELL_DAILY_CAP", 0)
+	return &ShopServiceImpl{
+		shopRepository:    shopRepository,
+		plantRepository:   plantRepository,
+		upgradeRepository: upgradeRepository,
+		sellDailyCap:      sellDailyCap,
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/repository"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+var ErrTaskUnavailable = errors.New("task is not available")
+
+// taskViewer holds what decides which tasks one user sees. The claimed set is loaded up front,
+// farm level and referral are only looked up once a task targets them
+type taskViewer struct {
+	user           dao.User
+	now            time.Time
+	claimed        map[uuid.UUID]bool
+	userRepository repository.UserRepository
+
+	farmLvl  *int
+	referred *bool
+}
+
+func (s *TaskServiceImpl) newTaskViewer(user dao.User) (*taskViewer, error) {
+	claimed, err := s.taskRepository.GetClaimedTaskIds(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &taskViewer{
+		user:           user,
+		now:            time.Now(),
+		claimed:        claimed,
+		userRepository: s.userRepository,
+	}, nil
+}
+
+func (v *taskViewer) getFarmLvl() (int, error) {
+	if v.farmLvl == nil {
+		userUpgrade, err := v.userRepository.GetUserUpgrade(v.user.ID)
+		if err != nil {
+			return 0, err
+		}
+		v.farmLvl = &userUpgrade.FarmLvl
+	}
+	return *v.farmLvl, nil
+}
+
+func (v *taskViewer) isReferred() (bool, error) {
+	if v.referred == nil {
+		referrer, err := v.userRepository.GetMyReferrer(v.user.ID)
+		if err != nil {
+			return false, err
+		}
+		referred := referrer != nil
+		v.referred = &referred
+	}
+	return *v.referred, nil
+}
+
+// canSee reports whether the task is enabled and in its window, every prerequisite is claimed and the user is in its audience
+func (v *taskViewer) canSee(task dao.Task) (bool, error) {
+	if !task.Enabled || !task.IsActive(v.now) {
+		return false, nil
+	}
+	for _, prerequisite := range task.Prerequisites {
+		if !v.claimed[prerequisite] {
+			return false, nil
+		}
+	}
+
+	audience := task.Audience
+	if len(audience.LanguageCodes) > 0 && !matchesLanguage(v.user.LanguageCode, audience.LanguageCodes) {
+		return false, nil
+	}
+	if audience.MinFarmLvl > 0 || audience.MaxFarmLvl > 0 {
+		farmLvl, err := v.getFarmLvl()
+		if err != nil {
+			return false, err
+		}
+		if farmLvl < audience.MinFarmLvl || (audience.MaxFarmLvl > 0 && farmLvl > audience.MaxFarmLvl) {
+			return false, nil
+		}
+	}
+	if audience.Referred != nil {
+		referred, err := v.isReferred()
+		if err != nil {
+			return false, err
+		}
+		if referred != *audience.Referred {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchesLanguage compares primary subtags, so "en" in the audience matches a user on "en-GB"
+func matchesLanguage(languageCode *string, codes []string) bool {
+	if languageCode == nil {
+		return false
+	}
+	primary := pkg.PrimaryLanguage(*languageCode)
+	for _, code := range codes {
+		if pkg.PrimaryLanguage(code) == primary {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTargeting rejects windows that end before they start, self prerequisites and inverted farm level ranges
+func validateTargeting(task dao.Task) error {
+	if task.StartsAt != nil && task.EndsAt != nil && !task.EndsAt.After(*task.StartsAt) {
+		return fmt.Errorf("%w: EndsAt must be after StartsAt", ErrInvalidTaskData)
+	}
+	for _, prerequisite := range task.Prerequisites {
+		if prerequisite == task.ID {
+			return fmt.Errorf("%w: task can not be its own prerequisite", ErrInvalidTaskData)
+		}
+	}
+	audience := task.Audience
+	if audience.MinFarmLvl < 0 || audience.MaxFarmLvl < 0 {
+		return fmt.Errorf("%w: farm levels must not be negative", ErrInvalidTaskData)
+	}
+	if audience.MaxFarmLvl > 0 && audience.MaxFarmLvl < audience.MinFarmLvl {
+		return fmt.Errorf("%w: MaxFarmLvl must not be below MinFarmLvl", ErrInvalidTaskData)
+	}
+	for _, code := range audience.LanguageCodes {
+		if pkg.PrimaryLanguage(code) == "" {
+			return fmt.Errorf("%w: empty language code", ErrInvalidTaskData)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,646 @@
+package service
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/repository"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrUnknownTaskType  = errors.New("unknown task type")
+	ErrInvalidTaskData  = errors.New("invalid task data")
+	ErrTaskNotRecurring = errors.New("task type can not recur")
+)
+
+// TaskChecker decides whether a user completed a task of one type.
+// Validate runs when a task is created and rejects Data that does not match the checker's schema
+type TaskChecker interface {
+	Validate(task dao.Task) error
+	Check(task dao.Task, user dao.User) (bool, error)
+}
+
+// TaskProgressChecker is implemented by checkers that can measure partial completion,
+// their tasks are done once the progress reaches NeedDoneTimes
+type TaskProgressChecker interface {
+	TaskChecker
+	Progress(task dao.Task, user dao.User) (int, error)
+}
+
+// TaskBatchChecker measures all tasks of its type for one user with a fixed number of queries,
+// the task list uses it so its cost does not grow with the number of tasks
+type TaskBatchChecker interface {
+	TaskProgressChecker
+	ProgressAll(tasks []dao.Task, user dao.User) (map[uuid.UUID]int, error)
+}
+
+// TaskDataSchema is implemented by checkers that read Data, DataSchema returns an example of their typed schema
+type TaskDataSchema interface {
+	DataSchema() interface{}
+}
+
+// TaskResult is the outcome of evaluating a task, Progress is nil for all or nothing task types
+type TaskResult struct {
+	Done     bool
+	Progress *int
+}
+
+type TaskCheckerRegistry interface {
+	Register(taskType constant.Task, checker TaskChecker)
+	Validate(task dao.Task) error
+	Evaluate(task dao.Task, user dao.User) (TaskResult, error)
+	EvaluateAll(tasks []dao.Task, user dao.User) map[uuid.UUID]TaskResult
+	Measurable(task dao.Task) bool
+	Recurring(taskType constant.Task) bool
+	Types() []constant.Task
+	DataSchema(taskType constant.Task) interface{}
+}
+
+type TaskCheckerRegistryImpl struct {
+	checkers map[constant.Task]TaskChecker
+}
+
+func (r *TaskCheckerRegistryImpl) Register(taskType constant.Task, checker TaskChecker) {
+	r.checkers[taskType] = checker
+}
+
+func (r *TaskCheckerRegistryImpl) get(task dao.Task) (TaskChecker, error) {
+	checker, ok := r.checkers[task.Type]
+	if !ok {
+		return nil, fmt.Errorf("task %s: %w %q", task.ID, ErrUnknownTaskType, task.Type)
+	}
+	return checker, nil
+}
+
+func (r *TaskCheckerRegistryImpl) Validate(task dao.Task) error {
+	checker, err := r.get(task)
+	if err != nil {
+		return err
+	}
+	if err := validateRecurrence(task); err != nil {
+		return fmt.Errorf("task %s: %w", task.ID, err)
+	}
+	if err := validateTargeting(task); err != nil {
+		return fmt.Errorf("task %s: %w", task.ID, err)
+	}
+	if err := checker.Validate(task); err != nil {
+		return fmt.Errorf("task %s: %w", task.ID, err)
+	}
+	return nil
+}
+
+// Evaluate validates the task before checking it, so a malformed row fails loudly instead of never completing
+func (r *TaskCheckerRegistryImpl) Evaluate(task dao.Task, user dao.User) (TaskResult, error) {
+	if err := r.Validate(task); err != nil {
+		return TaskResult{}, err
+	}
+	checker, _ := r.get(task)
+	if progressChecker, ok := checker.(TaskProgressChecker); ok {
+		progress, err := progressChecker.Progress(task, user)
+		if err != nil {
+			return TaskResult{}, err
+		}
+		progress = min(progress, task.NeedDoneTimes)
+		return TaskResult{Done: progress >= task.NeedDoneTimes, Progress: &progress}, nil
+	}
+	done, err := checker.Check(task, user)
+	return TaskResult{Done: done}, err
+}
+
+// EvaluateAll measures the progress of measurable tasks grouped by type. Tasks that fail validation
+// or measuring are logged and left out of the result, all or nothing tasks are skipped
+func (r *TaskCheckerRegistryImpl) EvaluateAll(tasks []dao.Task, user dao.User) map[uuid.UUID]TaskResult {
+	byType := make(map[constant.Task][]dao.Task)
+	for _, task := range tasks {
+		if !r.Measurable(task) {
+			continue
+		}
+		if err := r.Validate(task); err != nil {
+			log.Errorln(err)
+			continue
+		}
+		byType[task.Type] = append(byType[task.Type], task)
+	}
+
+	results := make(map[uuid.UUID]TaskResult, len(tasks))
+	for taskType, group := range byType {
+		progress, err := measureAll(r.checkers[taskType].(TaskProgressChecker), group, user)
+		if err != nil {
+			log.Warnln(err)
+			continue
+		}
+		for _, task := range group {
+			value, ok := progress[task.ID]
+			if !ok {
+				continue
+			}
+			value = min(value, task.NeedDoneTimes)
+			results[task.ID] = TaskResult{Done: value >= task.NeedDoneTimes, Progress: &value}
+		}
+	}
+	return results
+}
+
+// measureAll uses the checker's batch method when it has one and falls back to one Progress call per task
+func measureAll(checker TaskProgressChecker, tasks []dao.Task, user dao.User) (map[uuid.UUID]int, error) {
+	if batchChecker, ok := checker.(TaskBatchChecker); ok {
+		return batchChecker.ProgressAll(tasks, user)
+	}
+	progress := make(map[uuid.UUID]int, len(tasks))
+	for _, task := range tasks {
+		value, err := checker.Progress(task, user)
+		if err != nil {
+			return nil, err
+		}
+		progress[task.ID] = value
+	}
+	return progress, nil
+}
+
+// Measurable reports whether the task type exposes progress
+func (r *TaskCheckerRegistryImpl) Measurable(task dao.Task) bool {
+	checker, ok := r.checkers[task.Type]
+	if !ok {
+		return false
+	}
+	_, ok = checker.(TaskProgressChecker)
+	return ok
+}
+
+// Recurring reports whether tasks of the type may have a recurrence
+func (r *TaskCheckerRegistryImpl) Recurring(taskType constant.Task) bool {
+	_, registered := r.checkers[taskType]
+	_, oneShot := oneShotTaskTypes[taskType]
+	return registered && !oneShot
+}
+
+// Types lists the registered task types in name order
+func (r *TaskCheckerRegistryImpl) Types() []constant.Task {
+	types := make([]constant.Task, 0, len(r.checkers))
+	for taskType := range r.checkers {
+		types = append(types, taskType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// DataSchema returns an example Data value of the task type, nil when the type takes no Data
+func (r *TaskCheckerRegistryImpl) DataSchema(taskType constant.Task) interface{} {
+	if schema, ok := r.checkers[taskType].(TaskDataSchema); ok {
+		return schema.DataSchema()
+	}
+	return nil
+}
+
+// decodeTaskData converts the json Data column into the checker's typed schema and validates it
+func decodeTaskData[T any](data map[string]interface{}) (T, error) {
+	var typed T
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return typed, fmt.Errorf("%w: %v", ErrInvalidTaskData, err)
+	}
+	if err := pkg.UnmarshalAndValidate(raw, &typed); err != nil {
+		return typed, fmt.Errorf("%w: %v", ErrInvalidTaskData, err)
+	}
+	return typed, nil
+}
+
+// sameProgressAll serves checkers whose progress depends on the user only, it measures once for all tasks
+func sameProgressAll(checker TaskProgressChecker, tasks []dao.Task, user dao.User) (map[uuid.UUID]int, error) {
+	progress := make(map[uuid.UUID]int, len(tasks))
+	if len(tasks) == 0 {
+		return progress, nil
+	}
+	value, err := checker.Progress(tasks[0], user)
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		progress[task.ID] = value
+	}
+	return progress, nil
+}
+
+func checkProgress(checker TaskProgressChecker, task dao.Task, user dao.User) (bool, error) {
+	progress, err := checker.Progress(task, user)
+	if err != nil {
+		return false, err
+	}
+	return progress >= task.NeedDoneTimes, nil
+}
+
+// Longest N hour cycle, progress of recurring tasks is summed from counter buckets kept about a month
+const maxRecurrenceHours = 24 * 28
+
+// Types whose tasks complete once and for all, with the reason. Counter and login tasks measure the
+// current cycle of a recurring task, friends tasks count the friends who joined in it and subscribe and
+// inventory tasks check a state that can be claimed again every cycle
+var oneShotTaskTypes = map[constant.Task]string{
+	constant.FARM_LEVEL: "a farm level is reached only once",
+}
+
+func validateRecurrence(task dao.Task) error {
+	if reason, oneShot := oneShotTaskTypes[task.Type]; oneShot && task.Recurrence != constant.TASK_RECURRENCE_NONE {
+		return fmt.Errorf("%w: %s tasks, %s", ErrTaskNotRecurring, task.Type, reason)
+	}
+	switch task.Recurrence {
+	case constant.TASK_RECURRENCE_NONE, constant.TASK_RECURRENCE_DAILY, constant.TASK_RECURRENCE_WEEKLY:
+		return nil
+	case constant.TASK_RECURRENCE_HOURS:
+		if task.RecurrenceHours <= 0 || task.RecurrenceHours > maxRecurrenceHours {
+			return fmt.Errorf("%w: RecurrenceHours must be between 1 and %d", ErrInvalidTaskData, maxRecurrenceHours)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: unknown recurrence %q", ErrInvalidTaskData, task.Recurrence)
+}
+
+func requireNeedDoneTimes(task dao.Task) error {
+	if task.NeedDoneTimes <= 0 {
+		return fmt.Errorf("%w: NeedDoneTimes must be positive", ErrInvalidTaskData)
+	}
+	return nil
+}
+
+type SubscribeTaskData struct {
+	ChannelID string `json:"id" validate:"required"`
+}
+
+// subscribeChecker asks the telegram bot over nats whether the user joined the channel
+type subscribeChecker struct {
+	nc *nats.Conn
+}
+
+func (s *subscribeChecker) Validate(task dao.Task) error {
+	_, err := decodeTaskData[SubscribeTaskData](task.Data)
+	return err
+}
+
+func (s *subscribeChecker) DataSchema() interface{} {
+	return SubscribeTaskData{ChannelID: "@channel"}
+}
+
+func (s *subscribeChecker) Check(task dao.Task, user dao.User) (bool, error) {
+	data, err := decodeTaskData[SubscribeTaskData](task.Data)
+	if err != nil {
+		return false, err
+	}
+	requestData := strconv.Itoa(int(user.TgId)) + "," + data.ChannelID
+	msg, err := s.nc.Request("check_subscribe", []byte(requestData), 10*time.Second)
+	if err != nil {
+		return false, err
+	}
+	return string(msg.Data) == "1", nil
+}
+
+// friendsChecker counts referrals against NeedDoneTimes, recurring tasks only the friends who joined in
+// the current cycle. It takes no Data
+type friendsChecker struct {
+	userRepository repository.UserRepository
+}
+
+func (f *friendsChecker) Validate(task dao.Task) error {
+	return requireNeedDoneTimes(task)
+}
+
+func (f *friendsChecker) Check(task dao.Task, user dao.User) (bool, error) {
+	return checkProgress(f, task, user)
+}
+
+func (f *friendsChecker) Progress(task dao.Task, user dao.User) (int, error) {
+	progress, err := f.ProgressAll([]dao.Task{task}, user)
+	return progress[task.ID], err
+}
+
+// ProgressAll counts all referrals once for one-shot tasks and reads the sign up times since the earliest
+// cycle start once for recurring ones
+func (f *friendsChecker) ProgressAll(tasks []dao.Task, user dao.User) (map[uuid.UUID]int, error) {
+	cycles, since := currentCycles(tasks, time.Now())
+	var total int
+	if len(cycles) < len(tasks) {
+		referrals, err := f.userRepository.GetMyReferrals(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		total = len(referrals)
+	}
+	var joined []time.Time
+	if len(cycles) > 0 {
+		var err error
+		if joined, err = f.userRepository.GetReferralTimesSince(user.ID, since); err != nil {
+			return nil, err
+		}
+	}
+
+	progress := make(map[uuid.UUID]int, len(tasks))
+	for _, task := range tasks {
+		cycle, recurring := cycles[task.ID]
+		if !recurring {
+			progress[task.ID] = total
+			continue
+		}
+		for _, at := range joined {
+			if !at.Before(cycle.Start) {
+				progress[task.ID]++
+			}
+		}
+	}
+	return progress, nil
+}
+
+// currentCycles returns the cycles recurring tasks are in at now and the earliest of their starts
+func currentCycles(tasks []dao.Task, now time.Time) (map[uuid.UUID]dao.TaskCycle, time.Time) {
+	cycles := make(map[uuid.UUID]dao.TaskCycle, len(tasks))
+	var since time.Time
+	for _, task := range tasks {
+		if cycle := task.Cycle(now); cycle.Key != "" {
+			cycles[task.ID] = cycle
+			if since.IsZero() || cycle.Start.Before(since) {
+				since = cycle.Start
+			}
+		}
+	}
+	return cycles, since
+}
+
+type InventoryTaskData struct {
+	Item constant.Plant `json:"item" validate:"required"`
+}
+
+// inventoryChecker compares the held quantity of Data.item against NeedDoneTimes
+type inventoryChecker struct {
+	inventoryRepository repository.InventoryRepository
+	plantRepository     repository.PlantRepository
+}
+
+func (i *inventoryChecker) Validate(task dao.Task) error {
+	data, err := decodeTaskData[InventoryTaskData](task.Data)
+	if err != nil {
+		return err
+	}
+	if _, ok := i.plantRepository.GetPlant(data.Item); !ok {
+		return fmt.Errorf("%w: item %s is not in the plant catalog", ErrInvalidTaskData, data.Item)
+	}
+	return requireNeedDoneTimes(task)
+}
+
+func (i *inventoryChecker) DataSchema() interface{} {
+	return InventoryTaskData{Item: constant.ROSE}
+}
+
+func (i *inventoryChecker) Check(task dao.Task, user dao.User) (bool, error) {
+	return checkProgress(i, task, user)
+}
+
+func (i *inventoryChecker) Progress(task dao.Task, user dao.User) (int, error) {
+	data, err := decodeTaskData[InventoryTaskData](task.Data)
+	if err != nil {
+		return 0, err
+	}
+	quantity, err := i.inventoryRepository.GetItemQuantity(user.ID, data.Item)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return quantity, err
+}
+
+func (i *inventoryChecker) ProgressAll(tasks []dao.Task, user dao.User) (map[uuid.UUID]int, error) {
+	items := make(map[uuid.UUID]constant.Plant, len(tasks))
+	plants := make([]constant.Plant, 0, len(tasks))
+	for _, task := range tasks {
+		data, err := decodeTaskData[InventoryTaskData](task.Data)
+		if err != nil {
+			return nil, err
+		}
+		items[task.ID] = data.Item
+		plants = append(plants, data.Item)
+	}
+	quantities, err := i.inventoryRepository.GetItemQuantities(user.ID, plants)
+	if err != nil {
+		return nil, err
+	}
+	progress := make(map[uuid.UUID]int, len(tasks))
+	for taskId, item := range items {
+		progress[taskId] = quantities[item]
+	}
+	return progress, nil
+}
+
+type CounterTaskData struct {
+	Item constant.Plant `json:"item"`
+}
+
+// counterChecker reads a gameplay event counter, Data.item limits it to one plant and is optional
+type counterChecker struct {
+	counter           constant.Counter
+	counterRepository repository.CounterRepository
+	plantRepository   repository.PlantRepository
+}
+
+func (k *counterChecker) Validate(task dao.Task) error {
+	data, err := decodeTaskData[CounterTaskData](task.Data)
+	if err != nil {
+		return err
+	}
+	if data.Item != "" {
+		if _, ok := k.plantRepository.GetPlant(data.Item); !ok {
+			return fmt.Errorf("%w: item %s is not in the plant catalog", ErrInvalidTaskData, data.Item)
+		}
+	}
+	return requireNeedDoneTimes(task)
+}
+
+// DataSchema shows the optional item, an empty Data counts every plant
+func (k *counterChecker) DataSchema() interface{} {
+	return CounterTaskData{Item: constant.ROSE}
+}
+
+func (k *counterChecker) Check(task dao.Task, user dao.User) (bool, error) {
+	return checkProgress(k, task, user)
+}
+
+func (k *counterChecker) Progress(task dao.Task, user dao.User) (int, error) {
+	data, err := decodeTaskData[CounterTaskData](task.Data)
+	if err != nil {
+		return 0, err
+	}
+	// Recurring tasks only count events of the current cycle
+	cycle := task.Cycle(time.Now())
+	if cycle.Key == "" {
+		value, err := k.counterRepository.GetCounter(user.ID, k.counter, string(data.Item))
+		return int(value), err
+	}
+	value, err := k.counterRepository.GetCounterSince(user.ID, k.counter, string(data.Item), cycle.Start)
+	return int(value), err
+}
+
+// ProgressAll reads the lifetime counters once and the hourly buckets since the earliest cycle start once,
+// then sums each recurring task's own cycle in memory
+func (k *counterChecker) ProgressAll(tasks []dao.Task, user dao.User) (map[uuid.UUID]int, error) {
+	keys := make(map[uuid.UUID]string, len(tasks))
+	for _, task := range tasks {
+		data, err := decodeTaskData[CounterTaskData](task.Data)
+		if err != nil {
+			return nil, err
+		}
+		keys[task.ID] = string(data.Item)
+	}
+	cycles, since := currentCycles(tasks, time.Now())
+
+	var lifetime map[string]int64
+	if len(cycles) < len(tasks) {
+		var err error
+		if lifetime, err = k.counterRepository.GetCounters(user.ID, k.counter); err != nil {
+			return nil, err
+		}
+	}
+	var buckets []dao.UserCounterBucket
+	if len(cycles) > 0 {
+		var err error
+		if buckets, err = k.counterRepository.GetCounterBuckets(user.ID, k.counter, since); err != nil {
+			return nil, err
+		}
+	}
+
+	progress := make(map[uuid.UUID]int, len(tasks))
+	for taskId, key := range keys {
+		cycle, recurring := cycles[taskId]
+		if !recurring {
+			progress[taskId] = int(lifetime[key])
+			continue
+		}
+		start := cycle.Start.UTC().Truncate(time.Hour)
+		var value int64
+		for _, bucket := range buckets {
+			if bucket.Key == key && !bucket.Hour.Before(start) {
+				value += bucket.Value
+			}
+		}
+		progress[taskId] = int(value)
+	}
+	return progress, nil
+}
+
+// loginStreakChecker compares the current run of consecutive UTC login days with NeedDoneTimes, recurring
+// tasks compare the UTC days with a sign in during the current cycle, a daily check-in needs 1
+type loginStreakChecker struct {
+	counterRepository repository.CounterRepository
+}
+
+func (l *loginStreakChecker) Validate(task dao.Task) error {
+	return requireNeedDoneTimes(task)
+}
+
+func (l *loginStreakChecker) Check(task dao.Task, user dao.User) (bool, error) {
+	return checkProgress(l, task, user)
+}
+
+func (l *loginStreakChecker) Progress(task dao.Task, user dao.User) (int, error) {
+	progress, err := l.ProgressAll([]dao.Task{task}, user)
+	return progress[task.ID], err
+}
+
+func (l *loginStreakChecker) ProgressAll(tasks []dao.Task, user dao.User) (map[uuid.UUID]int, error) {
+	cycles, since := currentCycles(tasks, time.Now())
+	var streak int64
+	if len(cycles) < len(tasks) {
+		var err error
+		if streak, err = l.counterRepository.GetLoginStreak(user.ID); err != nil {
+			return nil, err
+		}
+	}
+	var buckets []dao.UserCounterBucket
+	if len(cycles) > 0 {
+		var err error
+		if buckets, err = l.counterRepository.GetCounterBuckets(user.ID, constant.COUNTER_LOGIN, since); err != nil {
+			return nil, err
+		}
+	}
+
+	progress := make(map[uuid.UUID]int, len(tasks))
+	for _, task := range tasks {
+		cycle, recurring := cycles[task.ID]
+		if !recurring {
+			progress[task.ID] = int(streak)
+			continue
+		}
+		progress[task.ID] = loginDays(buckets, cycle.Start)
+	}
+	return progress, nil
+}
+
+// loginDays counts the UTC days with a sign in bucket from the hour since falls in
+func loginDays(buckets []dao.UserCounterBucket, since time.Time) int {
+	start := since.UTC().Truncate(time.Hour)
+	days := make(map[time.Time]bool)
+	for _, bucket := range buckets {
+		if !bucket.Hour.Before(start) {
+			days[pkg.StartOfDay(bucket.Hour)] = true
+		}
+	}
+	return len(days)
+}
+
+// farmLevelChecker compares the farm level with NeedDoneTimes
+type farmLevelChecker struct {
+	userRepository repository.UserRepository
+}
+
+func (f *farmLevelChecker) Validate(task dao.Task) error {
+	return requireNeedDoneTimes(task)
+}
+
+func (f *farmLevelChecker) Check(task dao.Task, user dao.User) (bool, error) {
+	return checkProgress(f, task, user)
+}
+
+func (f *farmLevelChecker) Progress(task dao.Task, user dao.User) (int, error) {
+	userUpgrade, err := f.userRepository.GetUserUpgrade(user.ID)
+	if err != nil {
+		return 0, err
+	}
+	return userUpgrade.FarmLvl, nil
+}
+
+func (f *farmLevelChecker) ProgressAll(tasks []dao.Task, user dao.User) (map[uuid.UUID]int, error) {
+	return sameProgressAll(f, tasks, user)
+}
+
+// TaskCheckerRegistryInit registers the built in task types, new types only need a checker here
+func TaskCheckerRegistryInit(
+	nc *nats.Conn,
+	userRepository repository.UserRepository,
+	inventoryRepository repository.InventoryRepository,
+	plantRepository repository.PlantRepository,
+	counterRepository repository.CounterRepository) *TaskCheckerRegistryImpl {
+	registry := &TaskCheckerRegistryImpl{checkers: make(map[constant.Task]TaskChecker)}
+	registry.Register(constant.SUBSCRIBE, &subscribeChecker{nc: nc})
+	registry.Register(constant.FRIENDS, &friendsChecker{userRepository: userRepository})
+	registry.Register(constant.INVENTORY, &inventoryChecker{
+		inventoryRepository: inventoryRepository,
+		plantRepository:     plantRepository,
+	})
+	registry.Register(constant.PLANT, &counterChecker{
+		counter:           constant.COUNTER_PLANTED,
+		counterRepository: counterRepository,
+		plantRepository:   plantRepository,
+	})
+	registry.Register(constant.HARVEST, &counterChecker{
+		counter:           constant.COUNTER_HARVESTED,
+		counterRepository: counterRepository,
+		plantRepository:   plantRepository,
+	})
+	registry.Register(constant.LOGIN_STREAK, &loginStreakChecker{counterRepository: counterRepository})
+	registry.Register(constant.FARM_LEVEL, &farmLevelChecker{userRepository: userRepository})
+	return registry
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateRecurrence(t *testing.T) {
+	tests := []struct {
+		name  string
+		task  dao.Task
+		valid bool
+	}{
+		{"one shot subscribe", dao.Task{Type: constant.SUBSCRIBE}, true},
+		{"daily harvest", dao.Task{Type: constant.HARVEST, Recurrence: constant.TASK_RECURRENCE_DAILY}, true},
+		{"weekly plant", dao.Task{Type: constant.PLANT, Recurrence: constant.TASK_RECURRENCE_WEEKLY}, true},
+		{"hourly plant", dao.Task{Type: constant.PLANT, Recurrence: constant.TASK_RECURRENCE_HOURS, RecurrenceHours: 6}, true},
+		{"hours missing", dao.Task{Type: constant.PLANT, Recurrence: constant.TASK_RECURRENCE_HOURS}, false},
+		{"hours too long", dao.Task{Type: constant.PLANT, Recurrence: constant.TASK_RECURRENCE_HOURS, RecurrenceHours: maxRecurrenceHours + 1}, false},
+		{"unknown recurrence", dao.Task{Type: constant.HARVEST, Recurrence: "MONTHLY"}, false},
+		{"daily subscribe", dao.Task{Type: constant.SUBSCRIBE, Recurrence: constant.TASK_RECURRENCE_DAILY}, true},
+		{"daily friends", dao.Task{Type: constant.FRIENDS, Recurrence: constant.TASK_RECURRENCE_DAILY}, true},
+		{"weekly inventory", dao.Task{Type: constant.INVENTORY, Recurrence: constant.TASK_RECURRENCE_WEEKLY}, true},
+		{"daily login streak", dao.Task{Type: constant.LOGIN_STREAK, Recurrence: constant.TASK_RECURRENCE_DAILY}, true},
+		{"one shot farm level", dao.Task{Type: constant.FARM_LEVEL}, true},
+		{"hourly farm level", dao.Task{Type: constant.FARM_LEVEL, Recurrence: constant.TASK_RECURRENCE_HOURS, RecurrenceHours: 6}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRecurrence(tt.task)
+			if tt.valid && err != nil {
+				t.Fatalf("rejected: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidTaskData) && !errors.Is(err, ErrTaskNotRecurring) {
+				t.Fatalf("error %v, want ErrInvalidTaskData or ErrTaskNotRecurring", err)
+			}
+		})
+	}
+}
+
+func TestLoginDays(t *testing.T) {
+	at := func(value string) dao.UserCounterBucket {
+		hour, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dao.UserCounterBucket{Hour: hour}
+	}
+	buckets := []dao.UserCounterBucket{
+		at("2026-03-01T23:00:00Z"),
+		at("2026-03-02T08:00:00Z"),
+		at("2026-03-02T09:00:00Z"),
+		at("2026-03-04T00:00:00Z"),
+	}
+	tests := []struct {
+		since string
+		want  int
+	}{
+		{"2026-03-01T00:00:00Z", 3},
+		{"2026-03-01T23:30:00Z", 3},
+		{"2026-03-02T00:00:00Z", 2},
+		{"2026-03-02T09:00:00Z", 2},
+		{"2026-03-02T10:00:00Z", 1},
+		{"2026-03-05T00:00:00Z", 0},
+	}
+	for _, tt := range tests {
+		since, _ := time.Parse(time.RFC3339, tt.since)
+		if got := loginDays(buckets, since); got != tt.want {
+			t.Errorf("login days since %s = %d, want %d", tt.since, got, tt.want)
+		}
+	}
+}
@@ -7,25 +7,42 @@ import (
 	"crazyfarmbackend/src/domain/dto"
 	"crazyfarmbackend/src/pkg"
 	"crazyfarmbackend/src/repository"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/nats-io/nats.go"
-	"strconv"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"os"
+	"sort"
 	"time"
 )
 
+const defaultTaskLanguage = "en"
+
 type TaskService interface {
 	GetAllTasks(c *gin.Context) ([]dto.Task, error)
 	Check(c *gin.Context) (dto.Task, error)
 	Claim(c *gin.Context) (dto.Task, error)
+	GetTaskDefinitions(c *gin.Context) []dto.TaskDefinition
+	GetTaskTypes(c *gin.Context) []dto.TaskType
+	CreateTask(c *gin.Context) dto.TaskDefinition
+	UpdateTask(c *gin.Context) dto.TaskDefinition
+	SetTaskEnabled(c *gin.Context, enabled bool) dto.TaskDefinition
+	ReorderTasks(c *gin.Context)
+	GetTaskStats(c *gin.Context) []dto.TaskStats
 }
 
 type TaskServiceImpl struct {
-	taskRepository      repository.TaskRepository
-	inventoryRepository repository.InventoryRepository
-	userRepository      repository.UserRepository
-	nc                  *nats.Conn
+	transactionRepository repository.TransactionRepository
+	taskRepository        repository.TaskRepository
+	rewardRepository      repository.RewardRepository
+	upgradeRepository     repository.UpgradeRepository
+	userRepository        repository.UserRepository
+	plantRepository       repository.PlantRepository
+	taskCheckerRegistry   TaskCheckerRegistry
+	referralService       ReferralService
+	defaultLanguage       string
 }
 
 // Helper function to extract user from context
@@ -45,49 +62,68 @@ func statusToString(status dao.TaskComplete, err error) constant.TaskCompleteSta
 	return status.Status
 }
 
-func (s *TaskServiceImpl) checkTask(task dao.Task, user dao.User) (bool, error) {
-	switch task.Type {
-	case constant.SUBSCRIBE:
-		channelId, _ := task.Data["id"].(string)
-		return s.checkTaskSubscribe(strconv.Itoa(int(user.TgId)), channelId)
-	case constant.FRIENDS:
-		return s.checkTaskFriend(user, task.NeedDoneTimes)
-	case constant.INVENTORY:
-		plant, _ := task.Data["item"].(string)
-		return s.checkTaskInventory(user, task.NeedDoneTimes, constant.Plant(plant))
-	default:
-		return false, nil
+// languages lists the languages to show task texts in by preference: an explicit Accept-Language header,
+// the language telegram reported for the user and finally the default language
+func (s *TaskServiceImpl) languages(c *gin.Context, user dao.User) []string {
+	languages := pkg.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+	if user.LanguageCode != nil {
+		if language := pkg.PrimaryLanguage(*user.LanguageCode); language != "" {
+			languages = append(languages, language)
+		}
 	}
+	return append(languages, s.defaultLanguage)
 }
 
-func (s *TaskServiceImpl) checkTaskSubscribe(userId string, channelId string) (bool, error) {
-	requestData := userId + "," + channelId
-	msg, err := s.nc.Request("check_subscribe", []byte(requestData), 10*time.Second)
+// evaluateTask runs the registered checker, malformed task rows surface as errors while
+// transient check failures only leave the task incomplete
+func (s *TaskServiceImpl) evaluateTask(task dao.Task, user dao.User) (TaskResult, error) {
+	result, err := s.taskCheckerRegistry.Evaluate(task, user)
+	if errors.Is(err, ErrInvalidTaskData) || errors.Is(err, ErrUnknownTaskType) {
+		log.Errorln(err)
+		return TaskResult{}, err
+	}
 	if err != nil {
-		return false, err
+		log.Warnln(err)
+		return TaskResult{}, nil
 	}
-	return string(msg.Data) == "1", nil
+	return result, nil
 }
 
-func (s *TaskServiceImpl) checkTaskFriend(user dao.User, friendsRequired int) (bool, error) {
-	userReferrals, err := s.userRepository.GetMyReferrals(user.ID)
-	if err != nil {
-		return false, err
+// claimRefID ties the reward ledger rows to the task and, for recurring tasks, the claimed cycle
+func claimRefID(task dao.Task, cycle dao.TaskCycle) string {
+	if cycle.Key == "" {
+		return task.ID.String()
 	}
-	if len(userReferrals) >= friendsRequired {
-		return true, nil
+	return task.ID.String() + "@" + cycle.Key
+}
+
+// completedProgress is the progress shown for tasks already done or claimed
+func (s *TaskServiceImpl) completedProgress(task dao.Task) *int {
+	if !s.taskCheckerRegistry.Measurable(task) {
+		return nil
 	}
-	return false, nil
+	progress := task.NeedDoneTimes
+	return &progress
 }
-func (s *TaskServiceImpl) checkTaskInventory(user dao.User, itemsRequired int, itemName constant.Plant) (bool, error) {
-	quantity, err := s.inventoryRepository.GetItemQuantity(user.ID, itemName)
+
+// getVisibleTask loads the task, tasks hidden from the user are reported as ErrTaskUnavailable
+func (s *TaskServiceImpl) getVisibleTask(taskId uuid.UUID, user dao.User) (dao.Task, error) {
+	task, err := s.taskRepository.Get(taskId)
+	if err != nil {
+		return dao.Task{}, fmt.Errorf("failed to get task: %v", err)
+	}
+	viewer, err := s.newTaskViewer(user)
+	if err != nil {
+		return dao.Task{}, err
+	}
+	visible, err := viewer.canSee(task)
 	if err != nil {
-		return false, err
+		return dao.Task{}, err
 	}
-	if quantity >= itemsRequired {
-		return true, nil
+	if !visible {
+		return dao.Task{}, ErrTaskUnavailable
 	}
-	return false, nil
+	return task, nil
 }
 
 func (s *TaskServiceImpl) Check(c *gin.Context) (dto.Task, error) {
@@ -104,21 +140,26 @@ func (s *TaskServiceImpl) Check(c *gin.Context) (dto.Task, error) {
 	if err != nil {
 		return dto.Task{}, fmt.Errorf("invalid task ID format: %v", err)
 	}
-	task, err := s.taskRepository.Get(taskIdUUid)
+	task, err := s.getVisibleTask(taskIdUUid, user)
 	if err != nil {
-		return dto.Task{}, fmt.Errorf("failed to get task: %v", err)
+		return dto.Task{}, err
 	}
-	status, statusErr := s.taskRepository.GetStatus(user.ID, task.ID)
+	text := task.Localize(s.languages(c, user))
+	cycle := task.Cycle(time.Now())
+	status, statusErr := s.taskRepository.GetStatus(user.ID, task.ID, cycle.Key)
 	if statusErr == nil {
-		return constructor.ConstructTaskByModel(task, statusToString(status, nil)), nil
+		return constructor.ConstructTaskByModel(task, text, statusToString(status, nil), s.completedProgress(task)), nil
+	}
+	result, err := s.evaluateTask(task, user)
+	if err != nil {
+		return dto.Task{}, err
 	}
-	checked, err := s.checkTask(task, user)
-	if err != nil || !checked {
-		return constructor.ConstructTaskByModel(task, statusToString(status, statusErr)), nil
+	if !result.Done {
+		return constructor.ConstructTaskByModel(task, text, statusToString(status, statusErr), result.Progress), nil
 	}
 
-	status, statusErr = s.taskRepository.MarkDone(user.ID, taskIdUUid)
-	return constructor.ConstructTaskByModel(task, statusToString(status, statusErr)), nil
+	status, statusErr = s.taskRepository.MarkDone(user.ID, taskIdUUid, cycle.Key)
+	return constructor.ConstructTaskByModel(task, text, statusToString(status, statusErr), result.Progress), nil
 }
 
 func (s *TaskServiceImpl) Claim(c *gin.Context) (dto.Task, error) {
@@ -137,63 +178,395 @@ func (s *TaskServiceImpl) Claim(c *gin.Context) (dto.Task, error) {
 		return dto.Task{}, fmt.Errorf("invalid task ID format: %v", err)
 	}
 
-	task, err := s.taskRepository.Get(taskIdUUid)
+	task, err := s.getVisibleTask(taskIdUUid, user)
 	if err != nil {
-		return dto.Task{}, fmt.Errorf("failed to get task: %v", err)
+		return dto.Task{}, err
 	}
+	text := task.Localize(s.languages(c, user))
 
-	status, statusErr := s.taskRepository.GetStatus(user.ID, task.ID)
-	if status.Status == constant.TASK_COMPLETE_FINISHED {
-		return constructor.ConstructTaskByModel(task, statusToString(status, statusErr)), nil
+	rewards := task.RewardBundle()
+	if err := validateRewards(rewards, s.plantRepository, s.upgradeRepository); err != nil {
+		return dto.Task{}, fmt.Errorf("task %s: %w", task.ID, err)
 	}
 
-	checked, err := s.checkTask(task, user)
-	if err != nil || !checked {
-		return constructor.ConstructTaskByModel(task, statusToString(status, statusErr)), nil
+	cycle := task.Cycle(time.Now())
+	status, statusErr := s.taskRepository.GetStatus(user.ID, task.ID, cycle.Key)
+	if status.Status == constant.TASK_COMPLETE_FINISHED {
+		return constructor.ConstructTaskByModel(task, text, statusToString(status, statusErr), s.completedProgress(task)), nil
 	}
 
-	status, statusErr = s.taskRepository.MarkClaimed(user.ID, taskIdUUid)
-	if statusErr != nil {
-		return dto.Task{}, fmt.Errorf("failed to mark task as claimed: %v", statusErr)
+	result, err := s.evaluateTask(task, user)
+	if err != nil {
+		return dto.Task{}, err
+	}
+	if !result.Done {
+		return constructor.ConstructTaskByModel(task, text, statusToString(status, statusErr), result.Progress), nil
 	}
 
-	err = s.inventoryRepository.AdjustItemQuantity(user.ID, task.Reward, task.RewardAmount)
+	err = s.transactionRepository.Transaction(func(tx *gorm.DB) error {
+		status, statusErr = s.taskRepository.WithTx(tx).MarkClaimed(user.ID, taskIdUUid, cycle.Key)
+		if statusErr != nil {
+			return fmt.Errorf("failed to mark task as claimed: %w", statusErr)
+		}
+		err := s.rewardRepository.WithTx(tx).GrantRewards(user.ID, rewards,
+			constant.LEDGER_TASK_CLAIM, constant.WALLET_TASK_CLAIM, claimRefID(task, cycle))
+		if err != nil {
+			return fmt.Errorf("failed to give reward for task: %w", err)
+		}
+		return nil
+	})
+	if errors.Is(err, repository.ErrTaskAlreadyClaimed) {
+		return constructor.ConstructTaskByModel(task, text, constant.TASK_COMPLETE_FINISHED, s.completedProgress(task)), nil
+	}
 	if err != nil {
-		return dto.Task{}, fmt.Errorf("failed to give reward for task: %v", err)
+		return dto.Task{}, err
+	}
+	if grantsFarmLevel(rewards) {
+		s.referralService.CheckMilestones(user.ID)
 	}
 
-	return constructor.ConstructTaskByModel(task, statusToString(status, statusErr)), nil
+	return constructor.ConstructTaskByModel(task, text, statusToString(status, statusErr), result.Progress), nil
+}
+
+// grantsFarmLevel reports whether a bundle can raise the farm level and so reach a referral milestone
+func grantsFarmLevel(rewards []dao.RewardLine) bool {
+	for _, reward := range rewards {
+		if reward.Kind == constant.REWARD_FARM_LEVEL {
+			return true
+		}
+	}
+	return false
 }
 
+// GetAllTasks lists the tasks visible to the user, grouped by category in order of each category's first
+// task and by sort order inside a category. Statuses and progress are loaded in batches, so the number
+// of queries does not depend on the number of tasks. ?category= limits the list to one category
 func (s *TaskServiceImpl) GetAllTasks(c *gin.Context) ([]dto.Task, error) {
 	user, err := s.getUserFromContext(c)
 	if err != nil {
 		return nil, err
 	}
 
-	items, err := s.taskRepository.GetAllTasks()
+	viewer, err := s.newTaskViewer(user)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.taskRepository.GetActiveTasks(viewer.now)
 	if err != nil {
 		return nil, err
 	}
 
-	var dtoItems []dto.Task
+	languages := s.languages(c, user)
+	category, filterCategory := c.GetQuery("category")
+	var visibleItems []dao.Task
+	cycles := make(map[uuid.UUID]string)
 	for _, item := range items {
-		status, err := s.taskRepository.GetStatus(user.ID, item.ID)
-		dtoItems = append(dtoItems, constructor.ConstructTaskByModel(item, statusToString(status, err)))
+		if filterCategory && item.Category != category {
+			continue
+		}
+		visible, err := viewer.canSee(item)
+		if err != nil {
+			return nil, err
+		}
+		if !visible {
+			continue
+		}
+		visibleItems = append(visibleItems, item)
+		cycles[item.ID] = item.Cycle(viewer.now).Key
+	}
+	groupTasksByCategory(visibleItems)
+
+	statuses, err := s.taskRepository.GetStatuses(user.ID, cycles)
+	if err != nil {
+		return nil, err
+	}
+	// Only measurable open tasks are evaluated, all or nothing checks stay behind the check endpoint
+	var openItems []dao.Task
+	for _, item := range visibleItems {
+		if _, started := statuses[item.ID]; !started {
+			openItems = append(openItems, item)
+		}
+	}
+	results := s.taskCheckerRegistry.EvaluateAll(openItems, user)
+
+	dtoItems := make([]dto.Task, 0, len(visibleItems))
+	for _, item := range visibleItems {
+		status, started := statuses[item.ID]
+		if !started {
+			status.Status = constant.TASK_COMPLETE_NULL
+		}
+		progress := s.completedProgress(item)
+		if !started {
+			progress = results[item.ID].Progress
+		}
+		dtoItems = append(dtoItems, constructor.ConstructTaskByModel(item, item.Localize(languages), status.Status, progress))
 	}
 
 	return dtoItems, nil
 }
 
+// groupTasksByCategory moves tasks of one category together, categories keep the position of their first task
+func groupTasksByCategory(tasks []dao.Task) {
+	position := make(map[string]int)
+	for _, task := range tasks {
+		if _, ok := position[task.Category]; !ok {
+			position[task.Category] = len(position)
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return position[tasks[i].Category] < position[tasks[j].Category]
+	})
+}
+
+func (s *TaskServiceImpl) GetTaskDefinitions(c *gin.Context) []dto.TaskDefinition {
+	tasks, err := s.taskRepository.GetAllTasks()
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	definitions := make([]dto.TaskDefinition, len(tasks))
+	for i, task := range tasks {
+		definitions[i] = constructor.ConstructTaskDefinitionByModel(task)
+	}
+	return definitions
+}
+
+// GetTaskTypes documents the registered task types and the Data each of them expects
+func (s *TaskServiceImpl) GetTaskTypes(c *gin.Context) []dto.TaskType {
+	types := s.taskCheckerRegistry.Types()
+	taskTypes := make([]dto.TaskType, len(types))
+	for i, taskType := range types {
+		taskTypes[i] = dto.TaskType{
+			Type:       taskType,
+			Measurable: s.taskCheckerRegistry.Measurable(dao.Task{Type: taskType}),
+			Recurring:  s.taskCheckerRegistry.Recurring(taskType),
+			Data:       s.taskCheckerRegistry.DataSchema(taskType),
+		}
+	}
+	return taskTypes
+}
+
+func (s *TaskServiceImpl) readTaskRequest(c *gin.Context) dto.TaskRequest {
+	body, err := c.GetRawData()
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "")
+	}
+	var request dto.TaskRequest
+	if err := pkg.UnmarshalAndValidate(body, &request); err != nil {
+		pkg.PanicException(constant.WrongDataBody, err.Error())
+	}
+	return request
+}
+
+// mustBeValidTask checks the definition against its checker, its reward bundle and the other tasks,
+// prerequisites must exist and must not lead back to the task, a cycle would hide its tasks forever
+func (s *TaskServiceImpl) mustBeValidTask(task dao.Task) {
+	if err := s.taskCheckerRegistry.Validate(task); err != nil {
+		pkg.PanicException(constant.WrongDataBody, err.Error())
+	}
+	for language, text := range task.Translations {
+		if language == "" {
+			pkg.PanicException(constant.WrongDataBody, "Translation without a language code")
+		}
+		if text == (dao.TaskText{}) {
+			pkg.PanicException(constant.WrongDataBody, fmt.Sprintf("Translation %s is empty", language))
+		}
+	}
+	rewards := task.RewardBundle()
+	if len(rewards) == 0 {
+		pkg.PanicException(constant.WrongDataBody, "Task must pay a reward")
+	}
+	if err := validateRewards(rewards, s.plantRepository, s.upgradeRepository); err != nil {
+		pkg.PanicException(constant.WrongDataBody, err.Error())
+	}
+	if len(task.Prerequisites) == 0 {
+		return
+	}
+
+	tasks, err := s.taskRepository.GetAllTasks()
+	if err != nil {
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	byId := make(map[uuid.UUID]dao.Task, len(tasks)+1)
+	for _, other := range tasks {
+		byId[other.ID] = other
+	}
+	byId[task.ID] = task
+	for _, prerequisite := range task.Prerequisites {
+		if _, ok := byId[prerequisite]; !ok {
+			pkg.PanicException(constant.WrongDataBody, fmt.Sprintf("Prerequisite %s does not exist", prerequisite))
+		}
+	}
+	if requiresItself(byId, task.ID) {
+		pkg.PanicException(constant.WrongDataBody, "Prerequisites form a cycle")
+	}
+}
+
+// requiresItself walks the prerequisites of taskId and reports whether they lead back to it
+func requiresItself(tasks map[uuid.UUID]dao.Task, taskId uuid.UUID) bool {
+	visited := make(map[uuid.UUID]bool)
+	stack := append([]uuid.UUID(nil), tasks[taskId].Prerequisites...)
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if current == taskId {
+			return true
+		}
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+		stack = append(stack, tasks[current].Prerequisites...)
+	}
+	return false
+}
+
+func (s *TaskServiceImpl) CreateTask(c *gin.Context) dto.TaskDefinition {
+	task := constructor.ConstructTaskFromRequest(s.readTaskRequest(c))
+	task.ID = uuid.New()
+	s.mustBeValidTask(task)
+
+	task, err := s.taskRepository.CreateTask(task)
+	if err != nil {
+		pkg.PanicException(constant.UnknownError, "Task was not created")
+	}
+	return constructor.ConstructTaskDefinitionByModel(task)
+}
+
+// UpdateTask replaces the definition of ?taskId=, completions of earlier cycles are kept
+func (s *TaskServiceImpl) UpdateTask(c *gin.Context) dto.TaskDefinition {
+	taskId, err := uuid.Parse(c.Query("taskId"))
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "Invalid task id")
+	}
+	request := s.readTaskRequest(c)
+	task := constructor.ConstructTaskFromRequest(request)
+	task.ID = taskId
+	s.mustBeValidTask(task)
+
+	columns := []string{"name", "description", "button_label", "translations", "icon", "reward", "reward_amount",
+		"rewards", "need_done_times", "type", "data", "recurrence", "recurrence_hours", "starts_at", "ends_at",
+		"prerequisites", "audience", "category", "sort_order"}
+	// Enabling is left to SetTaskEnabled unless the body sets it, an edit must not hide a live task
+	if request.Enabled != nil {
+		columns = append(columns, "enabled")
+	}
+	task, err = s.taskRepository.UpdateTask(task, columns...)
+	switch {
+	case errors.Is(err, repository.ErrTaskNotFound):
+		pkg.PanicException(constant.DataNotFound, "Task not found")
+	case err != nil:
+		pkg.PanicException(constant.UnknownError, "Task was not updated")
+	}
+	return constructor.ConstructTaskDefinitionByModel(task)
+}
+
+// SetTaskEnabled hides or shows ?taskId= without touching its definition, tasks are never deleted
+// because completions and ledger rows refer to them
+func (s *TaskServiceImpl) SetTaskEnabled(c *gin.Context, enabled bool) dto.TaskDefinition {
+	taskId, err := uuid.Parse(c.Query("taskId"))
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "Invalid task id")
+	}
+	task, err := s.taskRepository.UpdateTask(dao.Task{ID: taskId, Enabled: enabled}, "enabled")
+	switch {
+	case errors.Is(err, repository.ErrTaskNotFound):
+		pkg.PanicException(constant.DataNotFound, "Task not found")
+	case err != nil:
+		pkg.PanicException(constant.UnknownError, "Task was not updated")
+	}
+	return constructor.ConstructTaskDefinitionByModel(task)
+}
+
+// ReorderTasks sets the sort order from the position of each id in the body, unlisted tasks keep theirs
+func (s *TaskServiceImpl) ReorderTasks(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "")
+	}
+	var request dto.TaskReorderRequest
+	if err := pkg.UnmarshalAndValidate(body, &request); err != nil {
+		pkg.PanicException(constant.WrongDataBody, err.Error())
+	}
+	err = s.taskRepository.ReorderTasks(request.TaskIDs)
+	switch {
+	case errors.Is(err, repository.ErrTaskNotFound):
+		pkg.PanicException(constant.DataNotFound, "Task not found")
+	case err != nil:
+		log.Errorln(err)
+		pkg.PanicException(constant.UnknownError, "Tasks were not reordered")
+	}
+}
+
+func (s *TaskServiceImpl) GetTaskStats(c *gin.Context) []dto.TaskStats {
+	tasks, err := s.taskRepository.GetAllTasks()
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	stats, err := s.taskRepository.GetTaskStats()
+	if err != nil {
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	byTask := make(map[uuid.UUID]dao.TaskStats, len(stats))
+	for _, taskStats := range stats {
+		byTask[taskStats.TaskID] = taskStats
+	}
+	taskStats := make([]dto.TaskStats, len(tasks))
+	for i, task := range tasks {
+		taskStats[i] = constructor.ConstructTaskStats(task, byTask[task.ID])
+	}
+	return taskStats
+}
+
 func TaskServiceInit(
+	transactionRepository repository.TransactionRepository,
 	taskRepository repository.TaskRepository,
-	inventoryRepository repository.InventoryRepository,
+	rewardRepository repository.RewardRepository,
+	upgradeRepository repository.UpgradeRepository,
 	userRepository repository.UserRepository,
-	nc *nats.Conn) *TaskServiceImpl {
-	return &TaskServiceImpl{
-		taskRepository:      taskRepository,
-		inventoryRepository: inventoryRepository,
-		userRepository:      userRepository,
-		nc:                  nc,
+	plantRepository repository.PlantRepository,
+	taskCheckerRegistry TaskCheckerRegistry,
+	referralService ReferralService) *TaskServiceImpl {
+	service := &TaskServiceImpl{
+		transactionRepository: transactionRepository,
+		taskRepository:        taskRepository,
+		rewardRepository:      rewardRepository,
+		upgradeRepository:     upgradeRepository,
+		userRepository:        userRepository,
+		plantRepository:       plantRepository,
+		taskCheckerRegistry:   taskCheckerRegistry,
+		referralService:       referralService,
+		// Language of the untranslated task texts and of the translation used when no other matches
+		defaultLanguage: pkg.PrimaryLanguage(os.Getenv("DEFAULT_LANGUAGE")),
+	}
+	if service.defaultLanguage == "" {
+		service.defaultLanguage = defaultTaskLanguage
+	}
+	service.validateTasks()
+	return service
+}
+
+// validateTasks reports task rows whose Data does not match their checker or whose rewards can not be
+// paid at startup, and prerequisites pointing at missing tasks, which would hide the task forever
+func (s *TaskServiceImpl) validateTasks() {
+	tasks, err := s.taskRepository.GetAllTasks()
+	if err != nil {
+		return
+	}
+	known := make(map[uuid.UUID]bool, len(tasks))
+	for _, task := range tasks {
+		known[task.ID] = true
+	}
+	for _, task := range tasks {
+		if err := s.taskCheckerRegistry.Validate(task); err != nil {
+			log.Errorln(err)
+		}
+		if err := validateRewards(task.RewardBundle(), s.plantRepository, s.upgradeRepository); err != nil {
+			log.Errorf("task %s: %v", task.ID, err)
+		}
+		for _, prerequisite := range task.Prerequisites {
+			if !known[prerequisite] {
+				log.Errorf("task %s: prerequisite %s does not exist", task.ID, prerequisite)
+			}
+		}
 	}
 }
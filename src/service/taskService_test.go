@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+	"crazyfarmbackend/src/repository"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const benchTaskCategory = "taskqueries-bench"
+
+// countingLogger counts every statement gorm executes
+type countingLogger struct {
+	queries atomic.Int64
+}
+
+func (l *countingLogger) LogMode(logger.LogLevel) logger.Interface      { return l }
+func (l *countingLogger) Info(context.Context, string, ...interface{})  {}
+func (l *countingLogger) Warn(context.Context, string, ...interface{})  {}
+func (l *countingLogger) Error(context.Context, string, ...interface{}) {}
+func (l *countingLogger) Trace(context.Context, time.Time, func() (string, int64), error) {
+	l.queries.Add(1)
+}
+
+var measurableTaskTypes = []constant.Task{
+	constant.FRIENDS, constant.INVENTORY, constant.PLANT, constant.HARVEST, constant.LOGIN_STREAK, constant.FARM_LEVEL,
+}
+
+// testDB connects to the scratch database at TEST_DB_DSN, tests needing a database are skipped without one
+func testDB(tb testing.TB, log logger.Interface) *gorm.DB {
+	tb.Helper()
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		tb.Skip("TEST_DB_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		SkipDefaultTransaction: true,
+		TranslateError:         true,
+		Logger:                 log,
+	})
+	if err != nil {
+		tb.Skip("database is not reachable: ", err)
+	}
+	return db
+}
+
+// testTaskService wires the task service to db without NATS, so subscription tasks are not checked
+func testTaskService(db *gorm.DB) *TaskServiceImpl {
+	userRepository := repository.UserRepositoryInit(db)
+	plantRepository := repository.PlantRepositoryInit(db)
+	inventoryRepository := repository.InventoryRepositoryInit(db)
+	counterRepository := repository.CounterRepositoryInit(db)
+	upgradeRepository := repository.UpgradeRepositoryInit(db)
+	registry := TaskCheckerRegistryInit(nil, userRepository, inventoryRepository, plantRepository, counterRepository)
+	referralService := ReferralServiceInit(repository.ReferralRepositoryInit(db), userRepository,
+		counterRepository, plantRepository, upgradeRepository)
+	return TaskServiceInit(repository.TransactionRepositoryInit(db), repository.TaskRepositoryInit(db, nil),
+		repository.RewardRepositoryInit(db), upgradeRepository, userRepository, plantRepository, registry, referralService)
+}
+
+// BenchmarkGetAllTasks lists a growing number of measurable tasks for a fresh user and fails when the
+// query count grows with the number of tasks. It seeds the scratch database at TEST_DB_DSN and removes
+// the tasks and the user again
+func BenchmarkGetAllTasks(b *testing.B) {
+	counter := &countingLogger{}
+	db := testDB(b, counter)
+	taskService := testTaskService(db)
+	userRepository := taskService.userRepository
+
+	plants := taskService.plantRepository.GetPlantNames()
+	if len(plants) == 0 {
+		b.Skip("plant catalog is empty")
+	}
+	user, err := userRepository.Create(uuid.NewString(), "bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		db.Exec("DELETE FROM task_completes WHERE user_id = ?", user.ID)
+		db.Where("category = ?", benchTaskCategory).Delete(&dao.Task{})
+		for _, table := range []string{"user_counter_buckets", "user_counters", "inventory_items", "user_upgrades", "user_auths"} {
+			db.Exec("DELETE FROM "+table+" WHERE user_id = ?", user.ID)
+		}
+		db.Exec("DELETE FROM users WHERE id = ?", user.ID)
+	})
+
+	gin.SetMode(gin.ReleaseMode)
+	var perOp []int64
+	seeded := 0
+	for _, size := range []int{10, 100, 1000} {
+		if err := seedBenchTasks(db, plants[0], seeded, size); err != nil {
+			b.Fatal(err)
+		}
+		seeded = size
+
+		var queries int64
+		ran := b.Run(fmt.Sprintf("tasks=%d", size), func(b *testing.B) {
+			counter.queries.Store(0)
+			for i := 0; i < b.N; i++ {
+				c, _ := gin.CreateTestContext(httptest.NewRecorder())
+				c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/tasks/all?category="+benchTaskCategory, nil)
+				c.Set("user", user)
+				tasks, err := taskService.GetAllTasks(c)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(tasks) != size {
+					b.Fatalf("listed %d tasks, want %d", len(tasks), size)
+				}
+			}
+			queries = counter.queries.Load() / int64(b.N)
+			b.ReportMetric(float64(queries), "queries/op")
+		})
+		if ran {
+			perOp = append(perOp, queries)
+		}
+	}
+
+	for _, queries := range perOp {
+		if queries != perOp[0] {
+			b.Fatalf("query count grows with the number of tasks: %v", perOp)
+		}
+	}
+}
+
+// seedBenchTasks adds tasks until there are total benchmark tasks, cycling through the measurable types
+// and making every other counter task daily so both lifetime and cycle progress are measured
+func seedBenchTasks(db *gorm.DB, reward constant.Plant, from, total int) error {
+	var tasks []dao.Task
+	for i := from; i < total; i++ {
+		task := dao.Task{
+			Name:          fmt.Sprintf("bench %d", i),
+			Reward:        reward,
+			RewardAmount:  1,
+			NeedDoneTimes: 1_000_000,
+			Type:          measurableTaskTypes[i%len(measurableTaskTypes)],
+			Data:          map[string]interface{}{},
+			Category:      benchTaskCategory,
+			SortOrder:     i,
+		}
+		if task.Type == constant.INVENTORY {
+			task.Data["item"] = string(reward)
+		}
+		if i%2 == 0 && (task.Type == constant.PLANT || task.Type == constant.HARVEST) {
+			task.Recurrence = constant.TASK_RECURRENCE_DAILY
+		}
+		tasks = append(tasks, task)
+	}
+	if len(tasks) == 0 {
+		return nil
+	}
+	return db.CreateInBatches(&tasks, 500).Error
+}
+
+// updateTestTask sends body as the admin update of taskId
+func updateTestTask(taskService *TaskServiceImpl, taskId uuid.UUID, body string) dto.TaskDefinition {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/v1/admin/tasks?taskId="+taskId.String(), strings.NewReader(body))
+	return taskService.UpdateTask(c)
+}
+
+func TestUpdateTask(t *testing.T) {
+	db := testDB(t, logger.Default.LogMode(logger.Silent))
+	taskService := testTaskService(db)
+	plants := taskService.plantRepository.GetPlantNames()
+	if len(plants) == 0 {
+		t.Skip("plant catalog is empty")
+	}
+	task, err := taskService.taskRepository.CreateTask(dao.Task{
+		Name:          "update test",
+		Reward:        plants[0],
+		RewardAmount:  1,
+		NeedDoneTimes: 1,
+		Type:          constant.FRIENDS,
+		Data:          map[string]interface{}{},
+		Enabled:       false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Delete(&dao.Task{}, "id = ?", task.ID) })
+
+	updateTestTask(taskService, task.ID, fmt.Sprintf(`{"Name": "update test", "Type": "%s", "NeedDoneTimes": 1,
+		"Description": "Invite a friend", "ButtonLabel": "Invite", "Translations": {"ru": {"Name": "Пригласи друга"}},
+		"Rewards": [{"Kind": "%s", "Plant": "%s", "Amount": 3}, {"Kind": "%s", "Amount": 50}]}`,
+		constant.FRIENDS, constant.REWARD_PLANT, plants[0], constant.REWARD_COINS))
+
+	stored, err := taskService.taskRepository.Get(task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []dao.RewardLine{
+		{Kind: constant.REWARD_PLANT, Plant: plants[0], Amount: 3},
+		{Kind: constant.REWARD_COINS, Amount: 50},
+	}
+	if !reflect.DeepEqual(stored.Rewards, want) {
+		t.Errorf("stored rewards %+v, want %+v", stored.Rewards, want)
+	}
+	if stored.Description == nil || *stored.Description != "Invite a friend" ||
+		stored.ButtonLabel == nil || *stored.ButtonLabel != "Invite" {
+		t.Errorf("stored texts %v, %v", stored.Description, stored.ButtonLabel)
+	}
+	if text := stored.Localize([]string{"ru"}); text.Name != "Пригласи друга" || text.ButtonLabel != "Invite" {
+		t.Errorf("stored translation resolves to %+v", text)
+	}
+}
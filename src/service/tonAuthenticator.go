@@ -0,0 +1,45 @@
+package service
+
+import (
+	"crazyfarmbackend/src/domain/dto"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/repository"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// How long a ton_proof payload and a signed proof stay valid
+const defaultTonProofTtl = 15 * time.Minute
+
+type tonAuthenticator struct {
+	authRepository repository.AuthRepository
+	domains        []string
+	network        string // "-239" for mainnet, empty accepts any network
+	ttl            time.Duration
+}
+
+// Authenticate verifies a TON Connect ton_proof and consumes its payload, so a proof signs in once.
+// The raw wallet address is the auth data
+func (t *tonAuthenticator) Authenticate(data string) (AuthIdentity, error) {
+	var proof dto.TonProof
+	if err := json.Unmarshal([]byte(data), &proof); err != nil {
+		return AuthIdentity{}, fmt.Errorf("%w: %v", ErrAuthFailed, err)
+	}
+	address, err := pkg.VerifyTonProof(proof, t.domains, t.network, t.ttl)
+	if errors.Is(err, pkg.ErrTonProofInvalid) {
+		return AuthIdentity{}, fmt.Errorf("%w: %v", ErrAuthFailed, err)
+	}
+	if err != nil {
+		return AuthIdentity{}, err
+	}
+	err = t.authRepository.ConsumeTonProofNonce(proof.Proof.Payload)
+	if errors.Is(err, repository.ErrTonProofNonceInvalid) {
+		return AuthIdentity{}, fmt.Errorf("%w: %v", ErrAuthFailed, err)
+	}
+	if err != nil {
+		return AuthIdentity{}, err
+	}
+	return AuthIdentity{AuthData: address}, nil
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/constructor"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/repository"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+)
+
+const defaultGiftDailyLimit = 20
+
+type TradeService interface {
+	SendGift(c *gin.Context) dto.Gift
+	CreateOffer(c *gin.Context) dto.TradeOffer
+	GetOffers(c *gin.Context) dto.TradeOffersResponse
+	AcceptOffer(c *gin.Context) dto.TradeOffer
+	RejectOffer(c *gin.Context) dto.TradeOffer
+	CancelOffer(c *gin.Context) dto.TradeOffer
+}
+
+type TradeServiceImpl struct {
+	tradeRepository repository.TradeRepository
+	userRepository  repository.UserRepository
+	plantRepository repository.PlantRepository
+	giftDailyLimit  int
+}
+
+func (t *TradeServiceImpl) validateItemStacks(stacks []dto.ItemStack) []dao.ItemStack {
+	items := make([]dao.ItemStack, len(stacks))
+	for i, stack := range stacks {
+		if !t.plantRepository.IsValidPlant(stack.Plant) {
+			pkg.PanicException(constant.WrongDataBody, "Plant not found")
+		}
+		if stack.Amount <= 0 {
+			pkg.PanicException(constant.WrongDataBody, "Invalid amount")
+		}
+		items[i] = dao.ItemStack{Plant: stack.Plant, Amount: stack.Amount}
+	}
+	return items
+}
+
+func (t *TradeServiceImpl) SendGift(c *gin.Context) dto.Gift {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	toUserId, err := uuid.Parse(c.Query("userID"))
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "Invalid user id")
+	}
+	plant := constant.Plant(c.Query("plant"))
+	if !t.plantRepository.IsValidPlant(plant) {
+		pkg.PanicException(constant.DataNotFound, "Plant not found")
+	}
+	quantity, err := strconv.Atoi(c.Query("quantity"))
+	if err != nil || quantity <= 0 {
+		pkg.PanicException(constant.WrongBody, "Invalid quantity")
+	}
+	if t.giftDailyLimit == 0 {
+		pkg.PanicException(constant.InvalidRequest, "Gifting is disabled")
+	}
+	mustBeFriend(t.userRepository, user.ID, toUserId)
+
+	gift, err := t.tradeRepository.SendGift(dao.Gift{
+		FromUserID: user.ID,
+		ToUserID:   toUserId,
+		Plant:      plant,
+		Quantity:   quantity,
+	}, t.giftDailyLimit)
+	switch {
+	case errors.Is(err, repository.ErrDailyGiftLimit):
+		pkg.PanicException(constant.InvalidRequest, "Daily gift limit reached")
+	case errors.Is(err, repository.ErrNotEnoughItems):
+		pkg.PanicException(constant.InvalidRequest, "Not enough items to gift")
+	case err != nil:
+		log.Errorln(err)
+		pkg.PanicException(constant.UnknownError, "Gift failed")
+	}
+	return constructor.ConstructGiftByModel(gift)
+}
+
+func (t *TradeServiceImpl) CreateOffer(c *gin.Context) dto.TradeOffer {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "")
+	}
+	var request dto.TradeOfferRequest
+	if err := pkg.UnmarshalAndValidate(body, &request); err != nil {
+		pkg.PanicException(constant.WrongDataBody, err.Error())
+	}
+	if len(request.Give) == 0 && len(request.Want) == 0 {
+		pkg.PanicException(constant.WrongDataBody, "Offer is empty")
+	}
+	give := t.validateItemStacks(request.Give)
+	want := t.validateItemStacks(request.Want)
+	mustBeFriend(t.userRepository, user.ID, request.ToUserID)
+
+	offer, err := t.tradeRepository.CreateOffer(dao.TradeOffer{
+		FromUserID: user.ID,
+		ToUserID:   request.ToUserID,
+		Give:       give,
+		Want:       want,
+	})
+	if err != nil {
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	return constructor.ConstructTradeOfferByModel(offer)
+}
+
+func (t *TradeServiceImpl) GetOffers(c *gin.Context) dto.TradeOffersResponse {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	offers, err := t.tradeRepository.GetPendingOffers(user.ID)
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+
+	response := dto.TradeOffersResponse{
+		Incoming: []dto.TradeOffer{},
+		Outgoing: []dto.TradeOffer{},
+	}
+	for _, offer := range offers {
+		if offer.ToUserID == user.ID {
+			response.Incoming = append(response.Incoming, constructor.ConstructTradeOfferByModel(offer))
+		} else {
+			response.Outgoing = append(response.Outgoing, constructor.ConstructTradeOfferByModel(offer))
+		}
+	}
+	return response
+}
+
+func (t *TradeServiceImpl) resolveOffer(c *gin.Context, resolve func(offerId, userId uuid.UUID) (dao.TradeOffer, error)) dto.TradeOffer {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+	offerId, err := uuid.Parse(c.Query("offerID"))
+	if err != nil {
+		pkg.PanicException(constant.WrongBody, "Invalid offer id")
+	}
+
+	offer, err := resolve(offerId, user.ID)
+	switch {
+	case errors.Is(err, repository.ErrTradeOfferNotFound):
+		pkg.PanicException(constant.DataNotFound, "Offer not found")
+	case errors.Is(err, repository.ErrNotEnoughItems):
+		pkg.PanicException(constant.InvalidRequest, "Not enough items to settle the offer")
+	case err != nil:
+		log.Errorln(err)
+		pkg.PanicException(constant.UnknownError, "")
+	}
+	return constructor.ConstructTradeOfferByModel(offer)
+}
+
+func (t *TradeServiceImpl) AcceptOffer(c *gin.Context) dto.TradeOffer {
+	return t.resolveOffer(c, t.tradeRepository.AcceptOffer)
+}
+
+func (t *TradeServiceImpl) RejectOffer(c *gin.Context) dto.TradeOffer {
+	return t.resolveOffer(c, t.tradeRepository.RejectOffer)
+}
+
+func (t *TradeServiceImpl) CancelOffer(c *gin.Context) dto.TradeOffer {
+	return t.resolveOffer(c, t.tradeRepository.CancelOffer)
+}
+
+func TradeServiceInit(
+	tradeRepository repository.TradeRepository,
+	userRepository repository.UserRepository,
+	plantRepository repository.PlantRepository) *TradeServiceImpl {
+	// Items a user may gift per UTC day
+	giftDailyLimit := int(envLimit("TRADE_GIFT_DAILY_LIMIT", defaultGiftDailyLimit))
+	return &TradeServiceImpl{
+		tradeRepository: tradeRepository,
+		userRepository:  userRepository,
+		plantRepository: plantRepository,
+		giftDailyLimit:  giftDailyLimit,
+	}
+}
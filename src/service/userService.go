@@ -7,12 +7,11 @@ import (
 	"crazyfarmbackend/src/domain/dto"
 	"crazyfarmbackend/src/pkg"
 	"crazyfarmbackend/src/repository"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
-	"os"
-	"strconv"
-	"time"
+	"net/http"
 )
 
 type UserService interface {
@@ -20,10 +19,17 @@ type UserService interface {
 	GetMe(*gin.Context) dto.User
 	GetUserUpgrade(c *gin.Context) dto.UserUpgrade
 	GetMyReferrals(c *gin.Context) []dto.UserReferral
+	GetFarmLevels(c *gin.Context) []dto.FarmLevel
+	BuyFarmUpgrade(c *gin.Context) dto.UserUpgrade
 }
 
 type UserServiceImpl struct {
-	userRepository repository.UserRepository
+	userRepository        repository.UserRepository
+	upgradeRepository     repository.UpgradeRepository
+	counterRepository     repository.CounterRepository
+	referralService       ReferralService
+	sessionService        SessionService
+	authenticatorRegistry AuthenticatorRegistry
 }
 
 func (u *UserServiceImpl) logAndReturnError(context string, err error) {
@@ -31,84 +37,65 @@ func (u *UserServiceImpl) logAndReturnError(context string, err error) {
 	pkg.PanicException(constant.UnknownError, "")
 }
 
+// AuthUser signs the user in with the authenticator of ?method=, creating the account on first use.
+// POST requests may send method and data as a JSON body instead
 func (u *UserServiceImpl) AuthUser(c *gin.Context) (dto.UserAuthResponse, error) {
-	method := c.Query("method")
-	data := c.Query("data")
-
-	if method == "" || data == "" {
+	request := dto.AuthRequest{Method: c.Query("method"), Data: c.Query("data")}
+	if request.Method == "" && request.Data == "" && c.Request.Method == http.MethodPost {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			pkg.PanicException(constant.WrongBody, "")
+		}
+	}
+	if request.Method == "" || request.Data == "" {
 		pkg.PanicException(constant.WrongBody, "")
 	}
 
-	var user dto.User
-	var userAuth dao.UserAuth
-
-	switch method {
-	case "telegram":
-		user, userAuth = u.AuthUserTelegram(data)
-	default:
+	authenticator, ok := u.authenticatorRegistry.Get(request.Method)
+	if !ok {
 		pkg.PanicException(constant.WrongMethod, "")
 	}
-
-	token, err := pkg.CreateJwtToken(userAuth.ID)
-	if err != nil {
-		u.logAndReturnError("Creating JWT token failed: ", err)
+	identity, err := authenticator.Authenticate(request.Data)
+	if errors.Is(err, ErrAuthFailed) {
+		log.Info("Sign in rejected: ", err)
+		pkg.PanicException(constant.Unauthorized, "Authentication failed")
 	}
-
-	return dto.UserAuthResponse{
-		User:  user,
-		Token: token,
-	}, nil
-}
-
-func (u *UserServiceImpl) AuthUserTelegram(data string) (dto.User, dao.UserAuth) {
-	telegramToken := os.Getenv("TELEGRAM_TOKEN")
-	telegramInitDataTtl, err := strconv.ParseFloat(os.Getenv("TELEGRAM_INIT_DATA_TTL"), 64)
 	if err != nil {
-		u.logAndReturnError("Parsing TELEGRAM_INIT_DATA_TTL failed: ", err)
+		u.logAndReturnError("Authenticating: ", err)
 	}
 
-	telegramInitData, err := pkg.ParseTelegramData(data)
-	if err != nil {
-		u.logAndReturnError("Initdata parse: ", err)
-	}
-
-	if err := pkg.ValidateTelegramData(data, telegramToken, time.Duration(telegramInitDataTtl*float64(time.Second))); err != nil {
-		u.logAndReturnError("Validating initdata: ", err)
-	}
-
-	telegramUserIDStr := strconv.FormatInt(telegramInitData.TelegramUser.ID, 10)
-	userAuth, isFirst, err := u.userRepository.GetOrCreateAuth(telegramUserIDStr, constant.Telegram)
+	userAuth, isFirst, err := u.userRepository.GetOrCreateAuth(identity.AuthData, request.Method)
 	if err != nil {
 		u.logAndReturnError("GetByAuth from database error: ", err)
 	}
-
 	user := userAuth.User
-	updates := map[string]interface{}{
-		"tg_id":         telegramInitData.TelegramUser.ID,
-		"first_name":    pkg.GetNullableString(telegramInitData.TelegramUser.FirstName),
-		"last_name":     pkg.GetNullableString(telegramInitData.TelegramUser.LastName),
-		"username":      pkg.GetNullableString(telegramInitData.TelegramUser.Username),
-		"icon":          pkg.GetNullableString(telegramInitData.TelegramUser.PhotoURL),
-		"language_code": pkg.GetNullableString(telegramInitData.TelegramUser.LanguageCode),
-	}
-
-	user, err = u.userRepository.UpdateUserFields(user.ID, updates)
-	if err != nil {
-		u.logAndReturnError("Updating data: ", err)
+	if len(identity.Profile) > 0 {
+		if user, err = u.userRepository.UpdateUserFields(user.ID, identity.Profile); err != nil {
+			u.logAndReturnError("Updating data: ", err)
+		}
 	}
-
 	if isFirst {
-		u.handleReferral(telegramInitData.StartParam, user.ID)
+		u.handleReferral(identity.StartParam, user.ID, user.IsPremium)
 	}
-	return constructor.ConstructUserFromModel(user), userAuth
+
+	// Login streak tasks count days with at least one sign in
+	_, _ = u.counterRepository.RecordLogin(userAuth.UserID)
+
+	return dto.UserAuthResponse{
+		User:          constructor.ConstructUserFromModel(user),
+		SessionTokens: u.sessionService.StartSession(c, userAuth),
+	}, nil
 }
 
-func (u *UserServiceImpl) handleReferral(startParam string, userID uuid.UUID) {
+// handleReferral links a new user to the referrer from the start param and pays the signup tier,
+// premium is kept on the referral so later tiers pay the premium bundle too
+func (u *UserServiceImpl) handleReferral(startParam string, userID uuid.UUID, premium bool) {
 	decodedParam := pkg.DecodeStartParam(startParam)
 	if decodedParam.Method == "ref" {
 		referrerId, err := uuid.Parse(decodedParam.Data)
 		if err == nil {
-			_, _ = u.userRepository.SetReferrals(userID, referrerId)
+			if _, err := u.userRepository.SetReferrals(userID, referrerId, premium); err == nil {
+				u.referralService.CheckMilestones(userID)
+			}
 		}
 	}
 }
@@ -131,7 +118,60 @@ func (u *UserServiceImpl) GetUserUpgrade(c *gin.Context) dto.UserUpgrade {
 	if err != nil {
 		pkg.PanicException(constant.DataNotFound, "")
 	}
-	return constructor.ConstructUserUpgradeFromModel(userUpgrade)
+	return u.constructUserUpgrade(userUpgrade)
+}
+
+func (u *UserServiceImpl) constructUserUpgrade(userUpgrade dao.UserUpgrade) dto.UserUpgrade {
+	level, err := u.upgradeRepository.GetFarmLevel(userUpgrade.FarmLvl)
+	if err != nil {
+		log.Error("Farm level lookup: ", err)
+		pkg.PanicException(constant.DataNotFound, "Farm level not found")
+	}
+	var next *dao.FarmLevel
+	if nextLevel, err := u.upgradeRepository.GetFarmLevel(userUpgrade.FarmLvl + 1); err == nil {
+		next = &nextLevel
+	}
+	return constructor.ConstructUserUpgradeFromModel(userUpgrade, level, next)
+}
+
+func (u *UserServiceImpl) GetFarmLevels(c *gin.Context) []dto.FarmLevel {
+	levels, err := u.upgradeRepository.GetFarmLevels()
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+
+	levelDTOs := make([]dto.FarmLevel, len(levels))
+	for i, level := range levels {
+		levelDTOs[i] = constructor.ConstructFarmLevelFromModel(level)
+	}
+	return levelDTOs
+}
+
+func (u *UserServiceImpl) BuyFarmUpgrade(c *gin.Context) dto.UserUpgrade {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+
+	userUpgrade, err := u.userRepository.GetUserUpgrade(user.ID)
+	if err != nil {
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+
+	nextLevel, err := u.upgradeRepository.GetFarmLevel(userUpgrade.FarmLvl + 1)
+	if err != nil {
+		pkg.PanicException(constant.InvalidRequest, "Max farm level reached")
+	}
+
+	if err := u.upgradeRepository.BuyFarmLevel(user.ID, nextLevel); err != nil {
+		log.Errorln(err)
+		pkg.PanicException(constant.InvalidRequest, "Not enough resources to upgrade")
+	}
+
+	u.referralService.CheckMilestones(user.ID)
+
+	userUpgrade.FarmLvl = nextLevel.Lvl
+	return u.constructUserUpgrade(userUpgrade)
 }
 
 func (u *UserServiceImpl) GetMyReferrals(c *gin.Context) []dto.UserReferral {
@@ -153,8 +193,19 @@ func (u *UserServiceImpl) GetMyReferrals(c *gin.Context) []dto.UserReferral {
 	return userReferralsDTOs
 }
 
-func UserServiceInit(userRepository repository.UserRepository) *UserServiceImpl {
+func UserServiceInit(
+	userRepository repository.UserRepository,
+	upgradeRepository repository.UpgradeRepository,
+	counterRepository repository.CounterRepository,
+	referralService ReferralService,
+	sessionService SessionService,
+	authenticatorRegistry AuthenticatorRegistry) *UserServiceImpl {
 	return &UserServiceImpl{
-		userRepository: userRepository,
+		userRepository:        userRepository,
+		upgradeRepository:     upgradeRepository,
+		counterRepository:     counterRepository,
+		referralService:       referralService,
+		sessionService:        sessionService,
+		authenticatorRegistry: authenticatorRegistry,
 	}
 }
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"crazyfarmbackend/src/constant"
+	"crazyfarmbackend/src/domain/dao"
+	"crazyfarmbackend/src/domain/dto"
+	"crazyfarmbackend/src/pkg"
+	"crazyfarmbackend/src/repository"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+type WalletService interface {
+	GetWallet(c *gin.Context) dto.Wallet
+	ReceiveCoins(data []byte) []byte
+}
+
+type WalletServiceImpl struct {
+	walletRepository repository.WalletRepository
+}
+
+func (w *WalletServiceImpl) GetWallet(c *gin.Context) dto.Wallet {
+	user, ok := c.MustGet("user").(dao.User)
+	if !ok {
+		pkg.PanicException(constant.DataNotFound, "User not found")
+	}
+
+	balance, err := w.walletRepository.GetBalance(user.ID)
+	if err != nil {
+		log.Errorln(err)
+		pkg.PanicException(constant.DataNotFound, "")
+	}
+	return dto.Wallet{Coins: balance}
+}
+
+// ReceiveCoins handles one dto.CoinCredit message and returns the encoded dto.CoinCreditReply,
+// redelivery of an already processed tx_id replies with the original result
+func (w *WalletServiceImpl) ReceiveCoins(data []byte) []byte {
+	var credit dto.CoinCredit
+	if err := pkg.UnmarshalAndValidate(data, &credit); err != nil {
+		return encodeCoinCreditReply(dto.CoinCreditReply{Error: err.Error()})
+	}
+
+	transaction, err := w.walletRepository.AdjustCoins(credit.UserID, credit.Amount, constant.WALLET_EXTERNAL_CREDIT, credit.TxID)
+	if errors.Is(err, repository.ErrDuplicateTransaction) {
+		existing, err := w.walletRepository.GetTransaction(credit.TxID)
+		if err != nil {
+			log.Error("Coin credit lookup: ", err)
+			return encodeCoinCreditReply(dto.CoinCreditReply{Error: "lookup failed"})
+		}
+		if existing.UserID != credit.UserID || existing.Amount != credit.Amount {
+			return encodeCoinCreditReply(dto.CoinCreditReply{Error: "tx_id reused with different payload"})
+		}
+		return encodeCoinCreditReply(dto.CoinCreditReply{OK: true, Duplicate: true, Balance: existing.BalanceAfter})
+	}
+	if errors.Is(err, repository.ErrNotEnoughCoins) {
+		// A positive credit only fails the balance guard when the user row is missing
+		return encodeCoinCreditReply(dto.CoinCreditReply{Error: "user not found"})
+	}
+	if err != nil {
+		log.Error("Coin credit: ", err)
+		return encodeCoinCreditReply(dto.CoinCreditReply{Error: "credit failed"})
+	}
+
+	return encodeCoinCreditReply(dto.CoinCreditReply{OK: true, Balance: transaction.BalanceAfter})
+}
+
+func encodeCoinCreditReply(reply dto.CoinCreditReply) []byte {
+	data, _ := json.Marshal(reply)
+	return data
+}
+
+func WalletServiceInit(walletRepository repository.WalletRepository) *WalletServiceImpl {
+	return &WalletServiceImpl{
+		walletRepository: walletRepository,
+	}
+}